@@ -0,0 +1,131 @@
+// Package accesstokens contains personal access tokens which let scripts use the api without password of user.
+// Tokens are named, limited by scopes and expire, only their hashes are stored.
+package accesstokens
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/tokens"
+)
+
+var (
+	// ErrNoToken indicates that token does not exist.
+	ErrNoToken = errs.Class("access token does not exist")
+
+	// ErrValidation indicates that name, scopes or lifetime of token are invalid.
+	ErrValidation = errs.Class("access token validation error")
+)
+
+// Scope is a permission granted to token.
+type Scope string
+
+const (
+	// ScopeItemsRead lets token list and read items.
+	ScopeItemsRead Scope = "items:read"
+	// ScopeItemsWrite lets token create, change and delete items.
+	ScopeItemsWrite Scope = "items:write"
+)
+
+// Scopes are all known scopes.
+var Scopes = []Scope{ScopeItemsRead, ScopeItemsWrite}
+
+const (
+	// Prefix starts every token, it tells access tokens from session tokens and makes leaked ones easy to find.
+	Prefix = "todo_pat_"
+
+	// MaxNameLength is the longest name of token.
+	MaxNameLength = 100
+
+	// secretLength is a length of random token secret.
+	secretLength = 32
+)
+
+// DB is exposing access to personal access tokens db.
+type DB interface {
+	// Create creates token in the database.
+	Create(ctx context.Context, token Token) error
+	// GetByHash returns token with hash.
+	GetByHash(ctx context.Context, hash []byte) (Token, error)
+	// List returns tokens of user, the most recent first.
+	List(ctx context.Context, userID uuid.UUID) ([]Token, error)
+	// UpdateLastUsed sets time token was last used at.
+	UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error
+	// Delete deletes token of user, ErrNoToken is returned when user has no such token.
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// Token is a personal access token of user.
+type Token struct {
+	ID     uuid.UUID `json:"id" bson:"id"`
+	UserID uuid.UUID `json:"-" bson:"user_id"`
+	Name   string    `json:"name" bson:"name"`
+	// Hash is sha256 hash of secret, secret itself is shown to user once.
+	Hash       []byte     `json:"-" bson:"hash"`
+	Scopes     []Scope    `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"created_at"`
+	ExpiresAt  time.Time  `json:"expiresAt" bson:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"last_used_at"`
+}
+
+// New returns secret, which is shown to user once, and token which keeps only its hash.
+func New(userID uuid.UUID, name string, scopes []Scope, ttl time.Duration) (secret string, token Token, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxNameLength {
+		return "", Token{}, ErrValidation.New("name is required and must be at most %d characters", MaxNameLength)
+	}
+	if len(scopes) == 0 {
+		return "", Token{}, ErrValidation.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return "", Token{}, ErrValidation.New("unknown scope %q", scope)
+		}
+	}
+	if ttl <= 0 {
+		return "", Token{}, ErrValidation.New("token must expire")
+	}
+
+	random := make([]byte, secretLength)
+	if _, err = rand.Read(random); err != nil {
+		return "", Token{}, errs.Wrap(err)
+	}
+	secret = Prefix + base64.RawURLEncoding.EncodeToString(random)
+
+	now := time.Now().UTC()
+	return secret, Token{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Hash:      tokens.Hash(secret),
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// IsAccessToken reports whether secret looks like personal access token rather than session token.
+func IsAccessToken(secret string) bool {
+	return strings.HasPrefix(secret, Prefix)
+}
+
+// Valid reports whether scope is known.
+func (scope Scope) Valid() bool {
+	for _, known := range Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether token is expired at now.
+func (token Token) Expired(now time.Time) bool {
+	return !now.Before(token.ExpiresAt)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zeebo/errs"
+	"gopkg.in/yaml.v3"
+
+	"todo"
+)
+
+const (
+	// envPrefix is a prefix of environment variables which override config values.
+	envPrefix = "TODO_"
+	// envConfigPath is an environment variable with path to config file.
+	envConfigPath = envPrefix + "CONFIG"
+)
+
+// options contains parsed global command line options.
+type options struct {
+	config      todo.Config
+	printConfig bool
+	args        []string
+}
+
+// configField is a single config value addressed by its yaml path, e.g. "console.auth.cookieName".
+type configField struct {
+	path  string
+	value reflect.Value
+}
+
+// env returns name of environment variable which overrides the field, e.g. TODO_CONSOLE_AUTH_COOKIE_NAME.
+func (field configField) env() string {
+	var name []rune
+	var prev rune
+	for _, r := range field.path {
+		switch {
+		case r == '.':
+			name = append(name, '_')
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			name = append(name, '_', r)
+		default:
+			name = append(name, unicode.ToUpper(r))
+		}
+		prev = r
+	}
+
+	return envPrefix + string(name)
+}
+
+// set parses raw value and assigns it to the field.
+func (field configField) set(raw string) error {
+	value := field.value
+	switch {
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(duration))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case value.Kind() == reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(i))
+	case value.Kind() == reflect.Uint32:
+		u, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		value.Set(reflect.ValueOf(list))
+	default:
+		return errs.New("unsupported type %s", value.Type())
+	}
+
+	return nil
+}
+
+// configFields returns all leaf values of config which can be overridden from environment and flags.
+func configFields(config *todo.Config) []configField {
+	var fields []configField
+
+	var walk func(prefix string, value reflect.Value)
+	walk = func(prefix string, value reflect.Value) {
+		for i := 0; i < value.NumField(); i++ {
+			structField := value.Type().Field(i)
+			name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+
+			path := prefix + name
+			fieldValue := value.Field(i)
+			switch {
+			case fieldValue.Kind() == reflect.Struct:
+				walk(path+".", fieldValue)
+			case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() != reflect.String:
+				// lists of structures can be set only from config file.
+			default:
+				fields = append(fields, configField{path: path, value: fieldValue})
+			}
+		}
+	}
+	walk("", reflect.ValueOf(config).Elem())
+
+	return fields
+}
+
+// flagValues records config values passed as flags, they are applied after config file and environment.
+type flagValues struct {
+	values map[string]string
+	order  []string
+}
+
+// flagValue is a flag.Value which records raw value of single config field.
+type flagValue struct {
+	path     string
+	values   *flagValues
+	boolFlag bool
+}
+
+// IsBoolFlag allows boolean fields to be passed without value.
+func (value flagValue) IsBoolFlag() bool {
+	return value.boolFlag
+}
+
+// String implements flag.Value.
+func (value flagValue) String() string {
+	if value.values == nil {
+		return ""
+	}
+	return value.values.values[value.path]
+}
+
+// Set implements flag.Value.
+func (value flagValue) Set(raw string) error {
+	if _, ok := value.values.values[value.path]; !ok {
+		value.values.order = append(value.values.order, value.path)
+	}
+	value.values.values[value.path] = raw
+	return nil
+}
+
+// parseOptions builds effective config from defaults, config file, environment variables and flags,
+// each next layer overrides the previous one.
+func parseOptions(args []string) (options, error) {
+	opts := options{config: todo.DefaultConfig()}
+
+	flags := flag.NewFlagSet("todo", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv(envConfigPath), "path to yaml config file, also read from "+envConfigPath)
+	flags.BoolVar(&opts.printConfig, "print-config", false, "print effective config with secrets redacted and exit")
+
+	overrides := &flagValues{values: make(map[string]string)}
+	for _, field := range configFields(&opts.config) {
+		value := flagValue{path: field.path, values: overrides, boolFlag: field.value.Kind() == reflect.Bool}
+		flags.Var(value, field.path, "overrides "+field.path+", also read from "+field.env())
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+	opts.args = flags.Args()
+
+	if *configPath != "" {
+		if err := loadConfigFile(*configPath, &opts.config); err != nil {
+			return opts, err
+		}
+	}
+
+	fields := make(map[string]configField)
+	for _, field := range configFields(&opts.config) {
+		fields[field.path] = field
+
+		raw, ok := os.LookupEnv(field.env())
+		if !ok {
+			continue
+		}
+		if err := field.set(raw); err != nil {
+			return opts, todo.ErrConfig.New("%s: %v", field.env(), err)
+		}
+	}
+
+	for _, path := range overrides.order {
+		if err := fields[path].set(overrides.values[path]); err != nil {
+			return opts, todo.ErrConfig.New("--%s: %v", path, err)
+		}
+	}
+
+	return opts, nil
+}
+
+// loadConfigFile decodes yaml config file on top of provided config, unknown keys are rejected.
+func loadConfigFile(path string, config *todo.Config) (err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return todo.ErrConfig.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, file.Close())
+	}()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return todo.ErrConfig.New("%s: %v", path, err)
+	}
+
+	return nil
+}
+
+// printConfig writes config with redacted secrets as yaml.
+func printConfig(w io.Writer, config todo.Config) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	return errs.Combine(encoder.Encode(config.Redacted()), encoder.Close())
+}
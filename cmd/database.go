@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"net/url"
+
+	"todo"
+	"todo/database"
+	"todo/database/memdb"
+	"todo/database/mongodb"
+)
+
+// openDatabase opens todo.DB backend selected by database url scheme.
+func openDatabase(ctx context.Context, databaseURL string) (todo.DB, error) {
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, todo.ErrConfig.New("database.url: %v", err)
+	}
+
+	switch parsed.Scheme {
+	case "postgres", "postgresql":
+		return database.New(databaseURL)
+	case "mongodb", "mongodb+srv":
+		return mongodb.New(ctx, databaseURL)
+	case "memory":
+		return memdb.New(), nil
+	default:
+		return nil, todo.ErrConfig.New("database.url: unsupported database scheme %q", parsed.Scheme)
+	}
+}
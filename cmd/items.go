@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"todo"
+	"todo/items"
+)
+
+const itemsUsage = `Usage: todo items <command> [--json]
+
+Commands:
+  list --user EMAIL [--sort name|created|updated|completed]
+                             list items of user, by name by default
+
+--json prints items as json instead of table.
+`
+
+// itemsCommand runs items subcommands which let operators look into items of users.
+func itemsCommand(ctx context.Context, config todo.Config, args []string) (err error) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, itemsUsage)
+		return errs.New("items command is required")
+	}
+
+	flags := flag.NewFlagSet("items "+args[0], flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, itemsUsage) }
+	asJSON := flags.Bool("json", false, "print json instead of table")
+	email := flags.String("user", "", "email of user whose items are listed")
+	sort := flags.String("sort", string(items.SortName), "order of items: name, created, updated or completed")
+	positional, err := parseArgs(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if args[0] != "list" {
+		fmt.Fprint(os.Stderr, itemsUsage)
+		return errs.New("unknown items command %q", args[0])
+	}
+	if len(positional) > 0 {
+		fmt.Fprint(os.Stderr, itemsUsage)
+		return errs.New("unexpected arguments %q", positional)
+	}
+	if *email == "" {
+		return errs.New("--user is required")
+	}
+
+	if config.Database.URL == "" {
+		return todo.ErrConfig.New("database.url is required")
+	}
+
+	db, err := openDatabase(ctx, config.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errs.Combine(err, db.Close())
+	}()
+
+	if err = db.CheckVersion(ctx); err != nil {
+		return err
+	}
+
+	return listItems(ctx, db, os.Stdout, *email, items.Sort(*sort), *asJSON)
+}
+
+// listItems prints items of user with email to out as table or as json array.
+func listItems(ctx context.Context, db todo.DB, out io.Writer, email string, sort items.Sort, asJSON bool) error {
+	user, err := userByEmail(ctx, db, email)
+	if err != nil {
+		return err
+	}
+
+	list, err := items.New(db.Items()).ListByUser(ctx, user.ID, sort)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if list == nil {
+			list = []items.Item{}
+		}
+		return printJSON(out, list)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tCREATED AT\tUPDATED AT\tCOMPLETED AT\tDESCRIPTION")
+	for _, item := range list {
+		completedAt := "-"
+		if item.CompletedAt != nil {
+			completedAt = item.CompletedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", item.ID, item.Name, item.Status, item.CreatedAt.Format(time.RFC3339),
+			item.UpdatedAt.Format(time.RFC3339), completedAt, item.Description)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/database/memdb"
+	"todo/items"
+	"todo/users"
+)
+
+func TestListItems(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+
+	owner := users.User{ID: uuid.New(), Email: "owner@gmail.com", CreatedAt: time.Now()}
+	other := users.User{ID: uuid.New(), Email: "other@gmail.com", CreatedAt: time.Now()}
+	require.NoError(t, db.Users().Create(ctx, owner))
+	require.NoError(t, db.Users().Create(ctx, other))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, item := range []items.Item{
+		{Name: "b", UserID: owner.ID, CreatedAt: now.Add(-time.Hour)},
+		{Name: "a", UserID: owner.ID, CreatedAt: now},
+		{Name: "foreign", UserID: other.ID, CreatedAt: now},
+	} {
+		item.ID, item.Status, item.UpdatedAt = uuid.New(), items.StatusTODO, item.CreatedAt
+		require.NoError(t, db.Items().Create(ctx, item), i)
+	}
+
+	names := func(email string, sort items.Sort) []string {
+		var out bytes.Buffer
+		require.NoError(t, listItems(ctx, db, &out, email, sort, true))
+
+		var list []items.Item
+		require.NoError(t, json.Unmarshal(out.Bytes(), &list))
+		var names []string
+		for _, item := range list {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"a", "b"}, names("Owner@gmail.com", items.SortName))
+	assert.Equal(t, []string{"a", "b"}, names("owner@gmail.com", items.SortCreated))
+	assert.Equal(t, []string{"foreign"}, names("other@gmail.com", items.SortName))
+
+	var out bytes.Buffer
+	err := listItems(ctx, db, &out, "owner@gmail.com", "size", false)
+	assert.True(t, items.ErrValidation.Has(err), err)
+	err = listItems(ctx, db, &out, "nobody@gmail.com", items.SortName, false)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/zeebo/errs"
+	"gopkg.in/yaml.v3"
+
+	"todo"
+	"todo/pkg/auth"
+	"todo/users/userauth"
+)
+
+const keysUsage = `Usage: todo keys <command> [--file PATH] [--algorithm HS256|EdDSA]
+
+Commands:
+  generate                   print new signing key
+  list                       list keys of key file, secrets are not printed
+  rotate                     add new key to key file and make it active
+  retire ID                  remove key from key file, its tokens stop being accepted
+
+Key file defaults to auth.keyFile, new keys are HS256 unless --algorithm is EdDSA.
+Public keys of EdDSA keys are served at /.well-known/jwks.json. Restart the server to load the changed keys.
+`
+
+// keysCommand runs keys subcommands which manage token signing keys.
+func keysCommand(config todo.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, keysUsage)
+		return errs.New("keys command is required")
+	}
+
+	flags := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	file := flags.String("file", config.Auth.KeyFile, "path to key file")
+	algorithm := flags.String("algorithm", auth.AlgorithmHS256, "algorithm of new key, HS256 or EdDSA")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "generate":
+		key, err := auth.GenerateKey(*algorithm)
+		if err != nil {
+			return err
+		}
+
+		return yaml.NewEncoder(os.Stdout).Encode([]auth.SigningKey{key})
+	case "list":
+		keyring, err := loadKeys(config, *file)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keyring.Keys {
+			active := ""
+			if key.ID == keyring.Active {
+				active = " (active)"
+			}
+			algorithm := key.Algorithm
+			if algorithm == "" {
+				algorithm = auth.AlgorithmHS256
+			}
+			fmt.Println(key.ID + " " + algorithm + active)
+		}
+		return nil
+	case "rotate":
+		if *file == "" {
+			return todo.ErrConfig.New("--file or auth.keyFile is required")
+		}
+
+		keyring, err := loadKeys(config, *file)
+		if err != nil {
+			return err
+		}
+
+		keyring, key, err := keyring.Rotate(*algorithm)
+		if err != nil {
+			return err
+		}
+
+		if err = auth.SaveKeyFile(*file, keyring); err != nil {
+			return err
+		}
+
+		fmt.Println(key.ID)
+		return nil
+	case "retire":
+		if *file == "" {
+			return todo.ErrConfig.New("--file or auth.keyFile is required")
+		}
+		if flags.NArg() != 1 {
+			fmt.Fprint(os.Stderr, keysUsage)
+			return errs.New("key id is required")
+		}
+
+		keyring, err := auth.LoadKeyFile(*file)
+		if err != nil {
+			return err
+		}
+
+		keyring, err = keyring.Retire(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		return auth.SaveKeyFile(*file, keyring)
+	default:
+		fmt.Fprint(os.Stderr, keysUsage)
+		return errs.New("unknown keys command %q", args[0])
+	}
+}
+
+// loadKeys loads keys from key file. Until the file exists keys are taken from config,
+// so that the first rotation keeps tokens signed with them valid.
+func loadKeys(config todo.Config, file string) (auth.KeyringConfig, error) {
+	keyring, err := auth.LoadKeyFile(file)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return keyring, err
+	}
+
+	config.Auth.KeyFile = ""
+	keyring, err = config.Auth.KeyringConfig()
+	if userauth.ErrNoKeys.Has(err) {
+		// no keys are configured, rotation starts an empty keyring.
+		return auth.KeyringConfig{}, nil
+	}
+
+	return keyring, err
+}
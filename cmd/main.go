@@ -2,38 +2,143 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/zeebo/errs"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"todo"
-	"todo/database"
 )
 
+const usage = `Usage: todo [flags] [command]
+
+Commands:
+  run                               run the web server, default command
+  migrate up|down|status|create     manage database schema migrations
+  sessions list|revoke|revoke-all   list and revoke login sessions of users
+  users create|list|disable|enable|delete|set-password|set-role
+                                    manage user accounts
+  items list                        list items of user
+  keys generate|list|rotate|retire  manage token signing keys
+
+Run "todo --help" to list flags.
+`
+
 func main() {
 	log := zap.NewExample()
-	ctx := context.Background()
-	db, err := database.New("postgres://postgres:123456@db:5432/todo?sslmode=disable")
+
+	opts, err := parseOptions(os.Args[1:])
 	if err != nil {
-		log.Error("could not create database" + err.Error())
-		os.Exit(1)
+		log.Error("could not parse config " + err.Error())
+		os.Exit(2)
 	}
 
-	app, err := todo.New(db)
+	if opts.printConfig {
+		if err = printConfig(os.Stdout, opts.config); err != nil {
+			log.Error("could not print config " + err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger, err := newLogger(opts.config.Log)
 	if err != nil {
-		log.Error("could not create representation of app" + err.Error())
-		os.Exit(1)
+		log.Error("could not create logger " + err.Error())
+		os.Exit(2)
 	}
+	log = logger
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	err = app.Run(ctx)
+	command, args := "run", opts.args
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "run":
+		err = run(ctx, log, opts.config)
+	case "migrate":
+		err = migrate(ctx, opts.config, args)
+	case "sessions":
+		err = sessionsCommand(ctx, opts.config, args)
+	case "users":
+		err = usersCommand(ctx, opts.config, args)
+	case "items":
+		err = itemsCommand(ctx, opts.config, args)
+	case "keys":
+		err = keysCommand(opts.config, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
 	if err != nil {
-		log.Error("could not run app" + err.Error())
+		log.Error(command + " failed: " + err.Error())
 		os.Exit(1)
 	}
+}
+
+// parseArgs parses flags which may follow positional arguments, e.g. "create EMAIL --verified",
+// and returns positional arguments. Arguments after "--" are positional even if they look like flags.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		positional, args = append(positional, rest[0]), rest[1:]
+	}
+}
+
+// run runs the app until ctx is canceled and then closes it.
+func run(ctx context.Context, log *zap.Logger, config todo.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	db, err := openDatabase(ctx, config.Database.URL)
+	if err != nil {
+		return errs.New("could not create database: %v", err)
+	}
+
+	app, err := todo.New(log, config, db)
+	if err != nil {
+		return errs.New("could not create representation of app: %v", errs.Combine(err, db.Close()))
+	}
+
+	runErr := app.Run(ctx)
+	log.Info("shutting down")
+
+	return errs.Combine(runErr, app.Close())
+}
+
+// newLogger creates app logger according to config.
+func newLogger(config todo.LogConfig) (*zap.Logger, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return nil, todo.ErrConfig.New("invalid log level: %v", err)
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	if config.Development {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
 
-	//err = app.Close()
-	//if err != nil {
-	//	log.Error("could not close app" + err.Error())
-	//	os.Exit(1)
-	//}
+	return zapConfig.Build()
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"todo"
+	"todo/database"
+)
+
+const migrateUsage = `Usage: todo migrate <command>
+
+Commands:
+  up [--steps N]             apply pending migrations, all of them by default
+  down [--steps N]           revert applied migrations, one by default
+  status                     list migrations and whether they are applied
+  create [--dir DIR] NAME    create empty up and down files for the next migration
+`
+
+// migrate runs migrate subcommands.
+func migrate(ctx context.Context, config todo.Config, args []string) (err error) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return errs.New("migrate command is required")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	steps := flags.Int("steps", 0, "number of migrations to apply or revert")
+	dir := flags.String("dir", "database/migrations", "directory with migration files")
+	if err = flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "create" {
+		if flags.NArg() != 1 {
+			return errs.New("migration name is required")
+		}
+
+		up, down, err := database.CreateMigration(*dir, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("created %s\ncreated %s\nmigrations are embedded, rebuild the binary to apply them\n", up, down)
+		return nil
+	}
+
+	if config.Database.URL == "" {
+		return todo.ErrConfig.New("database.url is required")
+	}
+
+	db, err := openDatabase(ctx, config.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errs.Combine(err, db.Close())
+	}()
+
+	migrator, ok := db.(database.Migrator)
+	if !ok {
+		// backends without versioned migrations can only be brought to the latest state.
+		switch args[0] {
+		case "up":
+			return db.MigrateToLatest(ctx)
+		case "status":
+			if err = db.CheckVersion(ctx); err != nil {
+				return err
+			}
+			fmt.Println("schema is up to date")
+			return nil
+		default:
+			return errs.New("database backend does not support migrate %s", args[0])
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.MigrateUp(ctx, *steps)
+	case "down":
+		if *steps == 0 {
+			*steps = 1
+		}
+		return migrator.MigrateDown(ctx, *steps)
+	case "status":
+		statuses, err := migrator.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Description, appliedAt)
+		}
+		return w.Flush()
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return errs.New("unknown migrate command %q", args[0])
+	}
+}
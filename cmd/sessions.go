@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo"
+	"todo/users"
+)
+
+const sessionsUsage = `Usage: todo sessions <command>
+
+Commands:
+  list EMAIL                 list sessions of user, the most recent first
+  revoke SESSION_ID          revoke session, its tokens stop being accepted
+  revoke-all EMAIL           revoke all sessions of user
+`
+
+// sessionsCommand runs sessions subcommands which let admins revoke sessions.
+func sessionsCommand(ctx context.Context, config todo.Config, args []string) (err error) {
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, sessionsUsage)
+		return errs.New("sessions command and its argument are required")
+	}
+
+	if config.Database.URL == "" {
+		return todo.ErrConfig.New("database.url is required")
+	}
+
+	db, err := openDatabase(ctx, config.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errs.Combine(err, db.Close())
+	}()
+
+	if err = db.CheckVersion(ctx); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		user, err := userByEmail(ctx, db, args[1])
+		if err != nil {
+			return err
+		}
+
+		list, err := db.Sessions().List(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED AT\tLAST SEEN AT\tIP\tUSER AGENT\tREVOKED")
+		for _, session := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", session.ID, session.CreatedAt.Format(time.RFC3339),
+				session.LastSeenAt.Format(time.RFC3339), session.IP, session.UserAgent, session.Revoked)
+		}
+		return w.Flush()
+	case "revoke":
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			return errs.New("invalid session id: %v", err)
+		}
+
+		return db.Sessions().Revoke(ctx, id)
+	case "revoke-all":
+		user, err := userByEmail(ctx, db, args[1])
+		if err != nil {
+			return err
+		}
+
+		return db.Sessions().RevokeAll(ctx, user.ID)
+	default:
+		fmt.Fprint(os.Stderr, sessionsUsage)
+		return errs.New("unknown sessions command %q", args[0])
+	}
+}
+
+// userByEmail returns user with email in any case.
+func userByEmail(ctx context.Context, db todo.DB, email string) (users.User, error) {
+	email, err := users.NormalizeEmail(email)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	return db.Users().GetByEmail(ctx, email)
+}
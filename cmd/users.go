@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zeebo/errs"
+	"golang.org/x/term"
+
+	"todo"
+	"todo/users"
+)
+
+const usersUsage = `Usage: todo users <command> [--json]
+
+Commands:
+  create [--verified] EMAIL  create user, password is read from stdin
+  list [SEARCH]              list users whose email contains SEARCH, all of them by default
+  disable EMAIL              disable user and revoke all its sessions
+  enable EMAIL               enable disabled user
+  delete EMAIL               delete user with all its items, sessions and tokens
+  set-password EMAIL         set password of user read from stdin and revoke all its sessions
+  set-role EMAIL ROLE        set role of user, "admin" lets manage users at /admin, "user" takes it away
+
+--json prints users as json instead of table.
+`
+
+// usersCommand runs users subcommands which let operators manage accounts.
+func usersCommand(ctx context.Context, config todo.Config, args []string) (err error) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usersUsage)
+		return errs.New("users command is required")
+	}
+
+	flags := flag.NewFlagSet("users "+args[0], flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usersUsage) }
+	asJSON := flags.Bool("json", false, "print json instead of table")
+	verified := flags.Bool("verified", false, "mark email of created user as verified")
+	positional, err := parseArgs(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	arity := map[string]int{"list": 1, "create": 1, "disable": 1, "enable": 1, "delete": 1, "set-password": 1, "set-role": 2}
+	n, ok := arity[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usersUsage)
+		return errs.New("unknown users command %q", args[0])
+	}
+	if len(positional) > n || (args[0] != "list" && len(positional) != n) {
+		fmt.Fprint(os.Stderr, usersUsage)
+		return errs.New("users %s requires %d arguments", args[0], n)
+	}
+
+	if config.Database.URL == "" {
+		return todo.ErrConfig.New("database.url is required")
+	}
+
+	db, err := openDatabase(ctx, config.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errs.Combine(err, db.Close())
+	}()
+
+	if err = db.CheckVersion(ctx); err != nil {
+		return err
+	}
+
+	passwords, err := users.NewPasswordHasher(config.Passwords)
+	if err != nil {
+		return err
+	}
+
+	manager := usersManager{db: db, users: users.New(db.Users(), passwords), in: os.Stdin, out: os.Stdout, asJSON: *asJSON}
+	return manager.run(ctx, args[0], positional, *verified)
+}
+
+// usersManager runs users subcommands against database, reading passwords from in and printing users to out.
+type usersManager struct {
+	db     todo.DB
+	users  *users.Service
+	in     *os.File
+	out    io.Writer
+	asJSON bool
+}
+
+// run runs users subcommand with its positional arguments, their number is checked by caller.
+func (manager usersManager) run(ctx context.Context, command string, args []string, verified bool) error {
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	switch command {
+	case "create":
+		password, err := readPassword(manager.in)
+		if err != nil {
+			return err
+		}
+		if err = manager.users.Create(ctx, arg(0), password); err != nil {
+			return err
+		}
+
+		user, err := manager.users.GetByEmail(ctx, arg(0))
+		if err != nil {
+			return err
+		}
+		if verified {
+			if err = manager.db.Users().Verify(ctx, user.ID, time.Now().UTC()); err != nil {
+				return err
+			}
+			if user, err = manager.users.GetByID(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		return printUsers(manager.out, manager.asJSON, []users.User{user})
+	case "list":
+		list, err := manager.users.List(ctx, arg(0))
+		if err != nil {
+			return err
+		}
+
+		return printUsers(manager.out, manager.asJSON, list)
+	}
+
+	user, err := manager.users.GetByEmail(ctx, arg(0))
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "disable":
+		if err = manager.users.Disable(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return manager.db.Sessions().RevokeAll(ctx, user.ID)
+	case "enable":
+		return manager.users.Enable(ctx, user.ID)
+	case "delete":
+		return manager.users.Delete(ctx, user.ID)
+	case "set-password":
+		password, err := readPassword(manager.in)
+		if err != nil {
+			return err
+		}
+		if err = manager.users.SetPassword(ctx, user.ID, password); err != nil {
+			return err
+		}
+
+		return manager.db.Sessions().RevokeAll(ctx, user.ID)
+	case "set-role":
+		return manager.users.SetRole(ctx, user.ID, users.Role(arg(1)))
+	default:
+		return errs.New("unknown users command %q", command)
+	}
+}
+
+// printUsers prints users to out as table or as json array.
+func printUsers(out io.Writer, asJSON bool, list []users.User) error {
+	if asJSON {
+		if list == nil {
+			list = []users.User{}
+		}
+		return printJSON(out, list)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tROLE\tVERIFIED\tDISABLED\tCREATED AT")
+	for _, user := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\t%s\n", user.ID, user.Email, user.Role, user.Verified(),
+			user.Disabled(), user.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// printJSON prints value to out as indented json.
+func printJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+// readPassword reads password from terminal without echoing it or from the first line of piped input,
+// so that it does not show up in shell history.
+func readPassword(in *os.File) (string, error) {
+	if fd := int(in.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", errs.New("could not read password from terminal: %v", err)
+		}
+
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", errs.New("could not read password from stdin: %v", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo"
+	"todo/database/memdb"
+	"todo/users"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := map[string]struct {
+		args       []string
+		positional []string
+		verified   bool
+	}{
+		"flags first":         {args: []string{"--verified", "user@gmail.com"}, positional: []string{"user@gmail.com"}, verified: true},
+		"flags last":          {args: []string{"user@gmail.com", "--verified"}, positional: []string{"user@gmail.com"}, verified: true},
+		"flags between":       {args: []string{"user@gmail.com", "-verified", "admin"}, positional: []string{"user@gmail.com", "admin"}, verified: true},
+		"no flags":            {args: []string{"user@gmail.com", "admin"}, positional: []string{"user@gmail.com", "admin"}},
+		"positional after --": {args: []string{"user@gmail.com", "--", "--verified"}, positional: []string{"user@gmail.com", "--verified"}},
+		"empty":               {},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			verified := flags.Bool("verified", false, "")
+
+			positional, err := parseArgs(flags, test.args)
+			require.NoError(t, err)
+			assert.Equal(t, test.positional, positional)
+			assert.Equal(t, test.verified, *verified)
+		})
+	}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(new(bytes.Buffer))
+	_, err := parseArgs(flags, []string{"user@gmail.com", "--unknown"})
+	assert.Error(t, err, "unknown flag after positional argument must be rejected")
+}
+
+func TestUsersCommandArguments(t *testing.T) {
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() { os.Stderr = stderr }()
+
+	for _, args := range [][]string{
+		{"create", "user@gmail.com", "--unknown"},
+		{"create"},
+		{"list", "gmail", "other"},
+		{"delete", "user@gmail.com", "other@gmail.com"},
+		{"set-role", "user@gmail.com"},
+		{"rename", "user@gmail.com"},
+	} {
+		err := usersCommand(context.Background(), todo.Config{}, args)
+		require.Error(t, err, args)
+		assert.False(t, todo.ErrConfig.Has(err), "%v must be rejected before database is opened: %v", args, err)
+	}
+}
+
+func TestUsersManager(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+
+	// run runs users subcommand with password piped to stdin and returns printed users.
+	run := func(password, command string, args ...string) []users.User {
+		in, err := os.Create(filepath.Join(t.TempDir(), "stdin"))
+		require.NoError(t, err)
+		_, err = in.WriteString(password + "\n")
+		require.NoError(t, err)
+		_, err = in.Seek(0, 0)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, in.Close()) }()
+
+		var out bytes.Buffer
+		manager := usersManager{db: db, users: users.New(db.Users(), passwords), in: in, out: &out, asJSON: true}
+		require.NoError(t, manager.run(ctx, command, args, command == "create"))
+
+		var list []users.User
+		if out.Len() > 0 {
+			require.NoError(t, json.Unmarshal(out.Bytes(), &list))
+		}
+		return list
+	}
+
+	created := run("password", "create", "User@gmail.com")
+	require.Len(t, created, 1)
+	assert.Equal(t, "user@gmail.com", created[0].Email)
+	assert.True(t, created[0].Verified())
+	assert.Equal(t, users.RoleUser, created[0].Role)
+
+	run("", "disable", "user@gmail.com")
+	run("", "set-role", "user@gmail.com", string(users.RoleAdmin))
+	list := run("", "list", "user@")
+	require.Len(t, list, 1)
+	assert.Equal(t, users.RoleAdmin, list[0].Role)
+	assert.True(t, list[0].Disabled())
+
+	run("", "enable", "user@gmail.com")
+	run("new password", "set-password", "user@gmail.com")
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	assert.False(t, user.Disabled())
+	require.NoError(t, passwords.Verify(user.Password, []byte("new password")))
+
+	// the last admin can not be deleted, so other admin is made first.
+	run("password", "create", "admin@gmail.com")
+	run("", "set-role", "admin@gmail.com", string(users.RoleAdmin))
+	run("", "delete", "user@gmail.com")
+	assert.Len(t, run("", "list"), 1)
+}
@@ -0,0 +1,221 @@
+package todo
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap/zapcore"
+
+	"todo/console"
+	"todo/pkg/auth"
+	"todo/pkg/mail"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// ErrConfig indicates that config is invalid.
+var ErrConfig = errs.Class("config error")
+
+// redacted replaces secret values when config is printed.
+const redacted = "[redacted]"
+
+// Config contains configuration for the whole app.
+type Config struct {
+	Log LogConfig `yaml:"log"`
+
+	Database struct {
+		URL string `yaml:"url"`
+		// MigrateOnStart applies pending migrations on start instead of refusing to run on outdated schema.
+		MigrateOnStart bool `yaml:"migrateOnStart"`
+	} `yaml:"database"`
+
+	Console console.Config  `yaml:"console"`
+	Auth    userauth.Config `yaml:"auth"`
+
+	// Passwords configures hashing of user passwords.
+	Passwords users.PasswordConfig `yaml:"passwords"`
+
+	// Mail configures delivery of emails to users.
+	Mail mail.Config `yaml:"mail"`
+}
+
+// LogConfig contains configuration for app logger.
+type LogConfig struct {
+	Level       string `yaml:"level"`
+	Development bool   `yaml:"development"`
+}
+
+// DefaultConfig returns config with default values, secrets and database url are left empty.
+func DefaultConfig() Config {
+	var config Config
+
+	config.Log.Level = "info"
+
+	config.Console.Address = ":8087"
+	config.Console.StaticDir = "web"
+	config.Console.ShutdownTimeout = 10 * time.Second
+	config.Console.Auth.CookieName = "todo"
+	config.Console.Auth.Path = "/"
+
+	config.Auth.TokenTTL = userauth.TokenExpirationTime
+	config.Auth.RefreshTTL = userauth.RefreshExpirationTime
+	config.Auth.RememberMeTTL = userauth.RememberMeExpirationTime
+	config.Auth.RefreshGrace = userauth.RefreshGracePeriod
+	config.Auth.Lockout = userauth.DefaultLockoutConfig()
+	config.Auth.ResetTTL = userauth.PasswordResetExpirationTime
+	config.Auth.PublicURL = "http://localhost:8087"
+	config.Auth.Verification = userauth.DefaultVerificationConfig()
+	config.Auth.TwoFactor = userauth.DefaultTwoFactorConfig()
+	config.Auth.OIDC = userauth.DefaultOIDCConfig()
+
+	config.Passwords = users.DefaultPasswordConfig()
+
+	config.Mail.Backend = mail.BackendStdout
+	config.Mail.From = "todo@localhost"
+
+	return config
+}
+
+// Validate checks that config contains all required values.
+func (config Config) Validate() error {
+	var errlist errs.Group
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(config.Log.Level)); err != nil {
+		errlist.Add(ErrConfig.New("log.level: %v", err))
+	}
+
+	if config.Database.URL == "" {
+		errlist.Add(ErrConfig.New("database.url is required"))
+	} else if _, err := url.Parse(config.Database.URL); err != nil {
+		errlist.Add(ErrConfig.New("database.url: %v", err))
+	}
+
+	if config.Console.Address == "" {
+		errlist.Add(ErrConfig.New("console.address is required"))
+	}
+	if config.Console.StaticDir == "" {
+		errlist.Add(ErrConfig.New("console.staticDir is required"))
+	}
+	if config.Console.ShutdownTimeout < 0 {
+		errlist.Add(ErrConfig.New("console.shutdownTimeout must not be negative"))
+	}
+	if config.Console.Auth.CookieName == "" {
+		errlist.Add(ErrConfig.New("console.auth.cookieName is required"))
+	}
+	if !strings.HasPrefix(config.Console.Auth.Path, "/") {
+		errlist.Add(ErrConfig.New("console.auth.path must start with /"))
+	}
+
+	if _, err := config.Auth.Keyring(); err != nil {
+		errlist.Add(ErrConfig.New("auth: %v", err))
+	}
+	if config.Auth.TokenTTL <= 0 {
+		errlist.Add(ErrConfig.New("auth.tokenTTL must be positive"))
+	}
+	if config.Auth.RefreshTTL < config.Auth.TokenTTL {
+		errlist.Add(ErrConfig.New("auth.refreshTTL must not be shorter than auth.tokenTTL"))
+	}
+	if config.Auth.RememberMeTTL < config.Auth.RefreshTTL {
+		errlist.Add(ErrConfig.New("auth.rememberMeTTL must not be shorter than auth.refreshTTL"))
+	}
+	if config.Auth.RefreshGrace < 0 || config.Auth.RefreshGrace > config.Auth.TokenTTL {
+		errlist.Add(ErrConfig.New("auth.refreshGrace must not be negative or longer than auth.tokenTTL"))
+	}
+	if lockout := config.Auth.Lockout; lockout.AccountThreshold > 0 || lockout.IPThreshold > 0 {
+		if lockout.BaseDelay <= 0 || lockout.MaxDelay < lockout.BaseDelay {
+			errlist.Add(ErrConfig.New("auth.lockout.baseDelay must be positive and not longer than auth.lockout.maxDelay"))
+		}
+		if lockout.Window < lockout.MaxDelay {
+			errlist.Add(ErrConfig.New("auth.lockout.window must not be shorter than auth.lockout.maxDelay"))
+		}
+	}
+
+	if config.Auth.ResetTTL <= 0 {
+		errlist.Add(ErrConfig.New("auth.resetTTL must be positive"))
+	}
+	if publicURL, err := url.Parse(config.Auth.PublicURL); err != nil || publicURL.Scheme == "" || publicURL.Host == "" {
+		errlist.Add(ErrConfig.New("auth.publicURL must be absolute url"))
+	}
+	if config.Auth.Verification.TTL <= 0 {
+		errlist.Add(ErrConfig.New("auth.verification.ttl must be positive"))
+	}
+	switch config.Auth.Verification.Unverified {
+	case userauth.UnverifiedLimited, userauth.UnverifiedBlocked:
+	default:
+		errlist.Add(ErrConfig.New("auth.verification.unverified must be %q or %q", userauth.UnverifiedLimited, userauth.UnverifiedBlocked))
+	}
+	if config.Auth.TwoFactor.Issuer == "" || strings.Contains(config.Auth.TwoFactor.Issuer, ":") {
+		errlist.Add(ErrConfig.New("auth.twoFactor.issuer is required and must not contain ':'"))
+	}
+	if config.Auth.TwoFactor.ChallengeTTL <= 0 {
+		errlist.Add(ErrConfig.New("auth.twoFactor.challengeTTL must be positive"))
+	}
+	if config.Auth.OIDC.Issuer != "" {
+		if issuer, err := url.Parse(config.Auth.OIDC.Issuer); err != nil || issuer.Scheme == "" || issuer.Host == "" {
+			errlist.Add(ErrConfig.New("auth.oidc.issuer must be absolute url"))
+		}
+		if config.Auth.OIDC.ClientID == "" {
+			errlist.Add(ErrConfig.New("auth.oidc.clientID is required"))
+		}
+		if config.Auth.OIDC.Name == "" {
+			errlist.Add(ErrConfig.New("auth.oidc.name is required"))
+		}
+		if !containsString(config.Auth.OIDC.Scopes, "openid") {
+			errlist.Add(ErrConfig.New("auth.oidc.scopes must contain openid"))
+		}
+	}
+
+	if err := config.Passwords.Validate(); err != nil {
+		errlist.Add(ErrConfig.New("passwords: %v", err))
+	}
+
+	if _, err := mail.New(config.Mail); err != nil {
+		errlist.Add(ErrConfig.New("mail: %v", err))
+	}
+
+	return errlist.Err()
+}
+
+// Redacted returns copy of config with all secrets hidden, it is safe to print or log it.
+func (config Config) Redacted() Config {
+	if config.Auth.TokenSecret != "" {
+		config.Auth.TokenSecret = redacted
+	}
+	if len(config.Auth.Keys) > 0 {
+		keys := make([]auth.SigningKey, len(config.Auth.Keys))
+		for i, key := range config.Auth.Keys {
+			keys[i] = key
+			keys[i].Secret = redacted
+		}
+		config.Auth.Keys = keys
+	}
+
+	if config.Auth.OIDC.ClientSecret != "" {
+		config.Auth.OIDC.ClientSecret = redacted
+	}
+
+	if config.Mail.SMTP.Password != "" {
+		config.Mail.SMTP.Password = redacted
+	}
+
+	if databaseURL, err := url.Parse(config.Database.URL); err == nil {
+		config.Database.URL = databaseURL.Redacted()
+	} else if config.Database.URL != "" {
+		config.Database.URL = redacted
+	}
+
+	return config
+}
+
+// containsString reports whether values contain value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"todo/accesstokens"
+	"todo/pkg/auth"
+)
+
+// AccessTokenRequest describes personal access token to create.
+type AccessTokenRequest struct {
+	Name   string               `json:"name"`
+	Scopes []accesstokens.Scope `json:"scopes"`
+	// ExpiresInDays is lifetime of token, it is at most a year.
+	ExpiresInDays int `json:"expiresInDays"`
+}
+
+// AccessTokenResponse carries secret of created token, it is returned only once.
+type AccessTokenResponse struct {
+	// Token is sent in "Authorization: Bearer" header.
+	Token       string             `json:"token"`
+	AccessToken accesstokens.Token `json:"accessToken"`
+}
+
+// AccessTokens is an endpoint which returns personal access tokens of user, the most recent first.
+func (controller *Auth) AccessTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	list, err := controller.auth.AccessTokens(ctx, claims.UserID)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if list == nil {
+		list = []accesstokens.Token{}
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, list)
+}
+
+// CreateAccessToken is an endpoint which creates personal access token and returns its secret.
+func (controller *Auth) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	var request AccessTokenRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ttl := time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	secret, token, err := controller.auth.CreateAccessToken(ctx, claims.UserID, request.Name, request.Scopes, ttl)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusCreated, AccessTokenResponse{Token: secret, AccessToken: token})
+}
+
+// RevokeAccessToken is an endpoint which revokes personal access token of user.
+func (controller *Auth) RevokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.auth.RevokeAccessToken(ctx, claims.UserID, id); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
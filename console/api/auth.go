@@ -0,0 +1,360 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"todo/pkg/auth"
+	"todo/sessions"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// Auth is an api controller which registers users and issues auth tokens.
+type Auth struct {
+	log   *zap.Logger
+	auth  *userauth.Service
+	users *users.Service
+}
+
+// NewAuth is a constructor for Auth.
+func NewAuth(log *zap.Logger, auth *userauth.Service, users *users.Service) *Auth {
+	return &Auth{
+		log:   log,
+		auth:  auth,
+		users: users,
+	}
+}
+
+// Credentials are email and password of user.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// RememberMe extends lifetime of refresh token.
+	RememberMe bool `json:"rememberMe,omitempty"`
+}
+
+// TokenResponse carries short-lived auth token, it is sent in "Authorization: Bearer" header,
+// and refresh token which exchanges for new tokens once.
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// SecondFactorResponse is returned instead of tokens when user has to enter second factor code.
+type SecondFactorResponse struct {
+	SecondFactorToken string    `json:"secondFactorToken"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+// SecondFactorRequest exchanges second factor token and TOTP or recovery code for tokens.
+type SecondFactorRequest struct {
+	SecondFactorToken string `json:"secondFactorToken"`
+	Code              string `json:"code"`
+	// RememberMe extends lifetime of refresh token.
+	RememberMe bool `json:"rememberMe,omitempty"`
+}
+
+// CodeRequest carries TOTP code or recovery code.
+type CodeRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse carries recovery codes, they are returned only once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// VerifyRequest carries token from email verification link.
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+// ResendRequest carries email which receives new verification link.
+type ResendRequest struct {
+	Email string `json:"email"`
+}
+
+// RefreshRequest carries refresh token.
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Token is an endpoint which exchanges credentials for auth token.
+func (controller *Auth) Token(w http.ResponseWriter, r *http.Request) {
+	var credentials Credentials
+	if err := decodeBody(r, &credentials); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if credentials.Email == "" || credentials.Password == "" {
+		ServeError(controller.log, w, ErrValidation.New("email and password are required"))
+		return
+	}
+
+	tokens, err := controller.auth.Token(r.Context(), credentials.Email, credentials.Password, credentials.RememberMe, sessions.RequestMetadata(r))
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if tokens.SecondFactorToken != "" {
+		ServeJSON(controller.log, w, http.StatusAccepted, SecondFactorResponse{
+			SecondFactorToken: tokens.SecondFactorToken,
+			ExpiresAt:         tokens.SecondFactorExpiresAt,
+		})
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, tokenResponse(tokens))
+}
+
+// SecondFactor is an endpoint which exchanges second factor token and code for auth token.
+func (controller *Auth) SecondFactor(w http.ResponseWriter, r *http.Request) {
+	var request SecondFactorRequest
+	if err := decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if request.SecondFactorToken == "" || request.Code == "" {
+		ServeError(controller.log, w, ErrValidation.New("second factor token and code are required"))
+		return
+	}
+
+	tokens, err := controller.auth.SecondFactor(r.Context(), request.SecondFactorToken, request.Code, request.RememberMe, sessions.RequestMetadata(r))
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, tokenResponse(tokens))
+}
+
+// Refresh is an endpoint which exchanges refresh token for new tokens.
+func (controller *Auth) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request RefreshRequest
+	if err := decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if request.RefreshToken == "" {
+		ServeError(controller.log, w, ErrValidation.New("refresh token is required"))
+		return
+	}
+
+	tokens, err := controller.auth.Refresh(r.Context(), request.RefreshToken)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, tokenResponse(tokens))
+}
+
+// tokenResponse converts tokens to response.
+func tokenResponse(tokens auth.Tokens) TokenResponse {
+	return TokenResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+}
+
+// Register is an endpoint which creates user.
+func (controller *Auth) Register(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var credentials Credentials
+	if err := decodeBody(r, &credentials); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err := controller.users.Create(ctx, credentials.Email, credentials.Password); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	controller.auth.RequestVerification(credentials.Email)
+
+	user, err := controller.users.GetByEmail(ctx, credentials.Email)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusCreated, user)
+}
+
+// Verify is an endpoint which verifies email with token from verification link.
+func (controller *Auth) Verify(w http.ResponseWriter, r *http.Request) {
+	var request VerifyRequest
+	if err := decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if request.Token == "" {
+		ServeError(controller.log, w, ErrValidation.New("token is required"))
+		return
+	}
+
+	user, err := controller.auth.VerifyEmail(r.Context(), request.Token)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, user)
+}
+
+// ResendVerification is an endpoint which mails new verification link,
+// it responds the same whether email is registered or not.
+func (controller *Auth) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var request ResendRequest
+	if err := decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if request.Email == "" {
+		ServeError(controller.log, w, ErrValidation.New("email is required"))
+		return
+	}
+
+	controller.auth.RequestVerification(request.Email)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Logout is an endpoint which revokes session of auth token.
+func (controller *Auth) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	if err = controller.auth.Logout(ctx, claims.SessionID); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutEverywhere is an endpoint which revokes all sessions of user.
+func (controller *Auth) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	if err = controller.auth.LogoutEverywhere(ctx, claims.UserID); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sessions is an endpoint which returns all sessions of user, the most recent first.
+func (controller *Auth) Sessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	list, err := controller.auth.Sessions(ctx, claims.UserID)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if list == nil {
+		list = []sessions.Session{}
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, list)
+}
+
+// JWKS is an endpoint which returns public keys of auth tokens, other services validate tokens with them.
+func (controller *Auth) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	ServeJSON(controller.log, w, http.StatusOK, controller.auth.JWKS())
+}
+
+// BeginEnrollment is an endpoint which starts enrollment of second factor and returns its secret.
+func (controller *Auth) BeginEnrollment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	enrollment, err := controller.auth.BeginEnrollment(ctx, claims.UserID)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, enrollment)
+}
+
+// ConfirmEnrollment is an endpoint which enables second factor with code and returns recovery codes.
+func (controller *Auth) ConfirmEnrollment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	var request CodeRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	codes, err := controller.auth.ConfirmEnrollment(ctx, claims.UserID, request.Code)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor is an endpoint which disables second factor with code or recovery code.
+func (controller *Auth) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	var request CodeRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.auth.DisableTwoFactor(ctx, claims.UserID, request.Code); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
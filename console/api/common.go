@@ -0,0 +1,123 @@
+// Package api contains json controllers of versioned rest api.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"todo/accesstokens"
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+var (
+	// ErrBadRequest indicates that request body could not be decoded.
+	ErrBadRequest = errs.Class("bad request")
+
+	// ErrValidation indicates that request is well-formed but its values are invalid.
+	ErrValidation = errs.Class("validation error")
+)
+
+// ErrorResponse is an envelope of every api error.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes api error.
+type ErrorBody struct {
+	// Code is a stable machine readable error code.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorCode returns http status and error code which match error class.
+func errorCode(err error) (int, string) {
+	switch {
+	case ErrBadRequest.Has(err):
+		return http.StatusBadRequest, "bad_request"
+	case ErrValidation.Has(err), items.ErrValidation.Has(err), users.ErrValidation.Has(err), accesstokens.ErrValidation.Has(err):
+		return http.StatusUnprocessableEntity, "validation_failed"
+	case userauth.ErrUnverified.Has(err):
+		return http.StatusForbidden, "email_not_verified"
+	case userauth.ErrTwoFactorRequired.Has(err):
+		return http.StatusForbidden, "two_factor_required"
+	case userauth.ErrDisabled.Has(err):
+		return http.StatusForbidden, "account_disabled"
+	case userauth.ErrInvalidCode.Has(err), users.ErrPassword.Has(err):
+		return http.StatusUnprocessableEntity, "validation_failed"
+	case auth.ErrNoCredentials.Has(err), auth.ErrUnauthenticated.Has(err), userauth.ErrUnauthenticated.Has(err):
+		return http.StatusUnauthorized, "unauthenticated"
+	case userauth.ErrInsufficientScope.Has(err):
+		return http.StatusForbidden, "insufficient_scope"
+	case items.ErrForbidden.Has(err):
+		return http.StatusForbidden, "forbidden"
+	case items.ErrNoItem.Has(err), users.ErrNoUser.Has(err), accesstokens.ErrNoToken.Has(err):
+		return http.StatusNotFound, "not_found"
+	case users.ErrEmailTaken.Has(err), users.ErrLastAdmin.Has(err), userauth.ErrTwoFactorState.Has(err), items.ErrConflict.Has(err):
+		return http.StatusConflict, "conflict"
+	case userauth.ErrLocked.Has(err):
+		return http.StatusTooManyRequests, "too_many_requests"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
+
+// ServeError writes error envelope with status which matches error class,
+// messages of internal errors are logged and not exposed to client.
+func ServeError(log *zap.Logger, w http.ResponseWriter, err error) {
+	status, code := errorCode(err)
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Error("api request failed", zap.Error(err))
+		message = http.StatusText(status)
+	}
+
+	ServeJSON(log, w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// ServeJSON writes value as json response with status.
+func ServeJSON(log *zap.Logger, w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Debug("could not write json response", zap.Error(err))
+	}
+}
+
+// decodeBody decodes json request body into value, unknown fields are rejected.
+func decodeBody(r *http.Request, value interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(value)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return ErrValidation.New("invalid value of field %q", typeErr.Field)
+		}
+
+		return ErrBadRequest.Wrap(err)
+	}
+
+	return nil
+}
+
+// pathID parses uuid from path variable.
+func pathID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)[name])
+	if err != nil {
+		return uuid.UUID{}, ErrValidation.New("invalid %s", name)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"todo/items"
+)
+
+// Items is an api controller for items of authenticated user.
+type Items struct {
+	log   *zap.Logger
+	items *items.Service
+}
+
+// NewItems is a constructor for Items.
+func NewItems(log *zap.Logger, items *items.Service) *Items {
+	return &Items{
+		log:   log,
+		items: items,
+	}
+}
+
+// ItemRequest contains editable fields of item.
+type ItemRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// List is an endpoint which returns all items of user in order of sort query parameter, by name by default.
+func (controller *Items) List(w http.ResponseWriter, r *http.Request) {
+	list, err := controller.items.List(r.Context(), items.Sort(r.URL.Query().Get("sort")))
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if list == nil {
+		list = []items.Item{}
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, list)
+}
+
+// Create is an endpoint which creates item.
+func (controller *Items) Create(w http.ResponseWriter, r *http.Request) {
+	var request ItemRequest
+	if err := decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	item, err := controller.items.Create(r.Context(), request.Name, request.Description)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusCreated, item)
+}
+
+// Get is an endpoint which returns item.
+func (controller *Items) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	item, err := controller.items.Get(r.Context(), id)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, item)
+}
+
+// Update is an endpoint which changes name and description of item.
+func (controller *Items) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	var request ItemRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.items.Update(ctx, id, request.Name, request.Description); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	controller.Get(w, r)
+}
+
+// UpdateStatus is an endpoint which moves item to the next status.
+func (controller *Items) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.items.UpdateStatus(r.Context(), id); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	controller.Get(w, r)
+}
+
+// Delete is an endpoint which deletes item.
+func (controller *Items) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.items.Delete(r.Context(), id); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
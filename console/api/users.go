@@ -0,0 +1,179 @@
+package api
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"todo/pkg/auth"
+	"todo/sessions"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// Users is an api controller for user profile and account self-service.
+type Users struct {
+	log   *zap.Logger
+	users *users.Service
+	auth  *userauth.Service
+}
+
+// NewUsers is a constructor for Users.
+func NewUsers(log *zap.Logger, users *users.Service, auth *userauth.Service) *Users {
+	return &Users{
+		log:   log,
+		users: users,
+		auth:  auth,
+	}
+}
+
+// ChangePasswordRequest carries current password and the new one.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password"`
+}
+
+// ChangeEmailRequest carries new email and password which confirms the change.
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// DeleteAccountRequest carries password which confirms account deletion.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// Me is an endpoint which returns profile of authenticated user.
+func (controller *Users) Me(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	user, err := controller.users.GetByID(ctx, claims.UserID)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, user)
+}
+
+// ChangePassword is an endpoint which changes password of user and revokes all other sessions.
+func (controller *Users) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	var request ChangePasswordRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = users.ValidatePassword(request.Password); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if err = controller.auth.Reauthenticate(ctx, claims.UserID, request.CurrentPassword, sessions.RequestMetadata(r)); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if err = controller.users.SetPassword(ctx, claims.UserID, request.Password); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if err = controller.auth.LogoutOthers(ctx, claims.UserID, claims.SessionID); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeEmail is an endpoint which changes email of user, mails link to verify it and returns updated profile.
+func (controller *Users) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	var request ChangeEmailRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.auth.Reauthenticate(ctx, claims.UserID, request.Password, sessions.RequestMetadata(r)); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	user, err := controller.users.ChangeEmail(ctx, claims.UserID, request.Email)
+	if err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if err = controller.auth.EmailChanged(ctx, claims.Email, user); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	ServeJSON(controller.log, w, http.StatusOK, user)
+}
+
+// SendConfirmationCode is an endpoint which mails code that confirms account changes instead of password.
+func (controller *Users) SendConfirmationCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	if err = controller.auth.SendConfirmationCode(ctx, claims.UserID, sessions.RequestMetadata(r)); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Delete is an endpoint which deletes user with all items.
+func (controller *Users) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		ServeError(controller.log, w, auth.ErrUnauthenticated.Wrap(err))
+		return
+	}
+
+	var request DeleteAccountRequest
+	if err = decodeBody(r, &request); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	if err = controller.auth.Reauthenticate(ctx, claims.UserID, request.Password, sessions.RequestMetadata(r)); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+	if err = controller.users.Delete(ctx, claims.UserID); err != nil {
+		ServeError(controller.log, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
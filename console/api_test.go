@@ -0,0 +1,261 @@
+package console_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/accesstokens"
+	"todo/console/api"
+	"todo/items"
+	"todo/users"
+)
+
+// apiClient sends json requests to api of test server.
+type apiClient struct {
+	t      *testing.T
+	server *testServer
+	token  string
+}
+
+// do sends request with json body and decodes json response into result, returns response status.
+func (client *apiClient) do(method, path string, body, result interface{}) int {
+	t := client.t
+
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	request, err := http.NewRequest(method, client.server.url+"/api/v1"+path, bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	if client.token != "" {
+		request.Header.Set("Authorization", "Bearer "+client.token)
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, resp.Body.Close()) }()
+
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+	}
+
+	return resp.StatusCode
+}
+
+// errorCode sends request which is expected to fail and returns its status and error code.
+func (client *apiClient) errorCode(method, path string, body interface{}) (int, string) {
+	var response api.ErrorResponse
+	status := client.do(method, path, body, &response)
+	return status, response.Error.Code
+}
+
+func TestAPI(t *testing.T) {
+	server := newTestServer(t)
+	client := &apiClient{t: t, server: server}
+	credentials := api.Credentials{Email: uuid.NewString() + "@gmail.com", Password: "password"}
+
+	var user users.User
+	require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/auth/register", credentials, &user))
+	assert.Equal(t, credentials.Email, user.Email)
+	assert.Nil(t, user.VerifiedAt)
+
+	status, code := client.errorCode(http.MethodPost, "/auth/register", credentials)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "conflict", code)
+
+	status, code = client.errorCode(http.MethodGet, "/items", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+
+	status, code = client.errorCode(http.MethodPost, "/auth/token", api.Credentials{Email: credentials.Email, Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+
+	status, code = client.errorCode(http.MethodPost, "/auth/token", api.Credentials{Email: "nobody@gmail.com", Password: "password"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+
+	var token api.TokenResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/token", credentials, &token))
+	require.NotEmpty(t, token.Token)
+	client.token = token.Token
+
+	// unverified user can read, but not change items.
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/items", nil, new([]items.Item)))
+	status, code = client.errorCode(http.MethodPost, "/items", api.ItemRequest{Name: "task"})
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "email_not_verified", code)
+
+	verification := api.VerifyRequest{Token: server.mailedToken(t, "/verify-email")}
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/verify", verification, &user))
+	require.NotNil(t, user.VerifiedAt)
+	status, code = client.errorCode(http.MethodPost, "/auth/verify", verification)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+
+	t.Run("profile", func(t *testing.T) {
+		var me map[string]interface{}
+		require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/users/me", nil, &me))
+		assert.Equal(t, user.ID.String(), me["id"])
+		assert.Equal(t, credentials.Email, me["email"])
+		assert.NotContains(t, me, "password")
+	})
+
+	t.Run("items", func(t *testing.T) {
+		var item items.Item
+		require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/items", api.ItemRequest{Name: "task", Description: "description"}, &item))
+		assert.Equal(t, user.ID, item.UserID)
+		assert.Equal(t, items.StatusTODO, item.Status)
+
+		var list []items.Item
+		require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/items", nil, &list))
+		assert.Equal(t, []items.Item{item}, list)
+
+		path := "/items/" + item.ID.String()
+		require.Equal(t, http.StatusOK, client.do(http.MethodPut, path, api.ItemRequest{Name: "updated", Description: "updated"}, &item))
+		assert.Equal(t, "updated", item.Name)
+
+		require.Equal(t, http.StatusOK, client.do(http.MethodPost, path+"/status", nil, &item))
+		assert.Equal(t, items.StatusInProgress, item.Status)
+		require.Equal(t, http.StatusOK, client.do(http.MethodPost, path+"/status", nil, &item))
+		assert.Equal(t, items.StatusCompleted, item.Status)
+		require.NotNil(t, item.CompletedAt)
+		assert.Equal(t, item.UpdatedAt, *item.CompletedAt)
+		assert.False(t, item.UpdatedAt.Before(item.CreatedAt))
+
+		list = nil
+		require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/items?sort=completed", nil, &list))
+		assert.Equal(t, []items.Item{item}, list)
+
+		status, code := client.errorCode(http.MethodPost, path+"/status", nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+
+		var stored items.Item
+		require.Equal(t, http.StatusOK, client.do(http.MethodGet, path, nil, &stored))
+		assert.Equal(t, item, stored)
+
+		require.Equal(t, http.StatusNoContent, client.do(http.MethodDelete, path, nil, nil))
+
+		status, code = client.errorCode(http.MethodGet, path, nil)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "not_found", code)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		status, code := client.errorCode(http.MethodPost, "/items", api.ItemRequest{Description: "no name"})
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+
+		status, code = client.errorCode(http.MethodGet, "/items/not-uuid", nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+
+		status, code = client.errorCode(http.MethodGet, "/items?sort=priority", nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+
+		status, code = client.errorCode(http.MethodPost, "/items", map[string]interface{}{"name": 1})
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+
+		status, code = client.errorCode(http.MethodPost, "/auth/register", api.Credentials{Email: "invalid", Password: "password"})
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+	})
+
+	t.Run("other user items are not found", func(t *testing.T) {
+		var item items.Item
+		require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/items", api.ItemRequest{Name: "task"}, &item))
+
+		_, intruderCookie := server.login(t)
+		intruder := &apiClient{t: t, server: server, token: intruderCookie.Value}
+
+		status, code := intruder.errorCode(http.MethodDelete, "/items/"+item.ID.String(), nil)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "not_found", code)
+
+		var list []items.Item
+		require.Equal(t, http.StatusOK, intruder.do(http.MethodGet, "/items", nil, &list))
+		assert.Empty(t, list)
+	})
+}
+
+func TestAccessTokens(t *testing.T) {
+	server := newTestServer(t)
+	_, cookie := server.login(t)
+	session := &apiClient{t: t, server: server, token: cookie.Value}
+
+	status, code := session.errorCode(http.MethodPost, "/auth/tokens", api.AccessTokenRequest{Name: "ci", ExpiresInDays: 30})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "validation_failed", code)
+
+	create := func(scopes ...accesstokens.Scope) api.AccessTokenResponse {
+		var created api.AccessTokenResponse
+		require.Equal(t, http.StatusCreated, session.do(http.MethodPost, "/auth/tokens", api.AccessTokenRequest{Name: "ci", Scopes: scopes, ExpiresInDays: 30}, &created))
+		require.NotEmpty(t, created.Token)
+		return created
+	}
+	reader := create(accesstokens.ScopeItemsRead)
+	writer := create(accesstokens.ScopeItemsRead, accesstokens.ScopeItemsWrite)
+
+	readClient := &apiClient{t: t, server: server, token: reader.Token}
+	writeClient := &apiClient{t: t, server: server, token: writer.Token}
+
+	var item items.Item
+	require.Equal(t, http.StatusCreated, writeClient.do(http.MethodPost, "/items", api.ItemRequest{Name: "task"}, &item))
+	var list []items.Item
+	require.Equal(t, http.StatusOK, readClient.do(http.MethodGet, "/items", nil, &list))
+	assert.Equal(t, []items.Item{item}, list)
+	require.Equal(t, http.StatusOK, readClient.do(http.MethodGet, "/users/me", nil, new(map[string]interface{})))
+
+	status, code = readClient.errorCode(http.MethodDelete, "/items/"+item.ID.String(), nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "insufficient_scope", code)
+
+	// tokens can not manage sessions, second factor or other tokens.
+	for _, path := range []string{"/auth/tokens", "/auth/sessions"} {
+		status, code = writeClient.errorCode(http.MethodGet, path, nil)
+		assert.Equal(t, http.StatusForbidden, status, path)
+		assert.Equal(t, "insufficient_scope", code, path)
+	}
+	assert.Equal(t, http.StatusForbidden, server.status(t, http.MethodGet, "/settings/tokens", &http.Cookie{Name: "todo", Value: writer.Token}))
+
+	var tokens []accesstokens.Token
+	require.Equal(t, http.StatusOK, session.do(http.MethodGet, "/auth/tokens", nil, &tokens))
+	require.Len(t, tokens, 2)
+	assert.Equal(t, writer.AccessToken.ID, tokens[0].ID)
+	assert.NotNil(t, tokens[0].LastUsedAt)
+	assert.NotNil(t, tokens[1].LastUsedAt)
+
+	require.Equal(t, http.StatusNoContent, session.do(http.MethodDelete, "/auth/tokens/"+reader.AccessToken.ID.String(), nil, nil))
+	status, code = readClient.errorCode(http.MethodGet, "/items", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+	status, code = session.errorCode(http.MethodDelete, "/auth/tokens/"+reader.AccessToken.ID.String(), nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "not_found", code)
+
+	t.Run("settings page", func(t *testing.T) {
+		form := url.Values{"name": {"deploy"}, "scope": {string(accesstokens.ScopeItemsRead)}, "expires": {"7"}}
+		assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/settings/tokens", form, cookie))
+		assert.Equal(t, http.StatusUnprocessableEntity, server.submit(t, http.MethodPost, "/settings/tokens", url.Values{"name": {"deploy"}}, cookie))
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/settings/tokens", cookie))
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodPost, "/settings/tokens/"+writer.AccessToken.ID.String()+"/revoke", cookie))
+
+		status, _ := writeClient.errorCode(http.MethodGet, "/items", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+}
@@ -0,0 +1,621 @@
+package console_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/accesstokens"
+	"todo/console/api"
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/pkg/oidc/oidctest"
+	"todo/pkg/totp"
+	"todo/sessions"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+func TestAuthRequired(t *testing.T) {
+	server := newTestServer(t)
+	owner, cookie := server.login(t)
+	ownerItems := server.url + "/" + owner.UserID.String() + "/items"
+
+	client := http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	send := func(method, url string, body string, header http.Header) *http.Response {
+		request, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		for key, values := range header {
+			request.Header[key] = values
+		}
+
+		resp, err := client.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("browser is redirected to login", func(t *testing.T) {
+		resp := send(http.MethodGet, ownerItems, "", nil)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "/login", resp.Header.Get("Location"))
+
+		resp = send(http.MethodGet, ownerItems, "", http.Header{"Cookie": {"todo=invalid"}})
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+	})
+
+	t.Run("api client gets 401", func(t *testing.T) {
+		for _, header := range []http.Header{
+			{"Accept": {"application/json"}},
+			{"Authorization": {"Bearer invalid"}},
+			{"Authorization": {"Basic dXNlcjpwYXNz"}},
+			{auth.APIKeyHeader: {"invalid"}},
+			{auth.APIKeyHeader: {cookie.Value}},
+		} {
+			resp := send(http.MethodGet, ownerItems, "", header)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var body api.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "unauthenticated", body.Error.Code)
+		}
+	})
+
+	t.Run("anonymous request does not reach handler", func(t *testing.T) {
+		form := url.Values{"name": {"task"}, "description": {"description"}}
+		resp := send(http.MethodPost, ownerItems+"/create", form.Encode(), http.Header{
+			"Content-Type": {"application/x-www-form-urlencoded"},
+		})
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+		list, err := server.items.List(auth.SetClaims(context.Background(), owner), items.SortName)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("any supported credentials are accepted", func(t *testing.T) {
+		for _, header := range []http.Header{
+			{"Cookie": {cookie.String()}},
+			{"Authorization": {"Bearer " + cookie.Value}},
+		} {
+			resp := send(http.MethodGet, ownerItems, "", header)
+			assert.Equal(t, http.StatusOK, resp.StatusCode, header)
+		}
+
+		// api keys are personal access tokens only.
+		secret, _, err := server.auth.CreateAccessToken(context.Background(), owner.UserID, "ci",
+			[]accesstokens.Scope{accesstokens.ScopeItemsRead}, time.Hour)
+		require.NoError(t, err)
+		resp := send(http.MethodGet, server.url+"/api/v1/items", "", http.Header{auth.APIKeyHeader: {secret}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestLogoutRevokesSessions(t *testing.T) {
+	server := newTestServer(t)
+	claims, cookie := server.login(t)
+	itemsPath := "/" + claims.UserID.String() + "/items"
+
+	newToken := func() string {
+		tokens, err := server.auth.Token(context.Background(), claims.Email, "password", false, sessions.Metadata{UserAgent: "script"})
+		require.NoError(t, err)
+		return tokens.AccessToken
+	}
+	other, third := newToken(), newToken()
+
+	// logout is a form post, so that links and prefetching can not log users out.
+	assert.Equal(t, http.StatusMethodNotAllowed, server.status(t, http.MethodGet, "/logout", cookie))
+	assert.Equal(t, http.StatusMethodNotAllowed, server.status(t, http.MethodGet, "/logout-all", cookie))
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, itemsPath, cookie))
+
+	// copied token of logged out session is rejected.
+	assert.Equal(t, http.StatusFound, server.status(t, http.MethodPost, "/logout", cookie))
+	assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, cookie))
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, itemsPath, &http.Cookie{Name: cookie.Name, Value: other}))
+
+	client := &apiClient{t: t, server: server, token: third}
+	var list []sessions.Session
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/auth/sessions", nil, &list))
+	require.Len(t, list, 3)
+	revoked := 0
+	for _, session := range list {
+		assert.Equal(t, claims.UserID, session.UserID)
+		if session.Revoked {
+			revoked++
+		}
+	}
+	assert.Equal(t, 1, revoked)
+
+	require.Equal(t, http.StatusNoContent, client.do(http.MethodPost, "/auth/logout-all", nil, nil))
+
+	for _, token := range []string{other, third} {
+		client.token = token
+		status, code := client.errorCode(http.MethodGet, "/items", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "unauthenticated", code)
+	}
+
+	cookie.Value, other = newToken(), newToken()
+	assert.Equal(t, http.StatusFound, server.status(t, http.MethodPost, "/logout-all", cookie))
+	assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, &http.Cookie{Name: cookie.Name, Value: other}))
+}
+
+func TestRefreshTokens(t *testing.T) {
+	server := newTestServer(t)
+	client := &apiClient{t: t, server: server}
+	credentials := api.Credentials{Email: uuid.NewString() + "@gmail.com", Password: "password", RememberMe: true}
+	require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/auth/register", credentials, nil))
+
+	var issued api.TokenResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/token", credentials, &issued))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), issued.ExpiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), issued.RefreshExpiresAt, time.Minute)
+
+	var rotated api.TokenResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/refresh", api.RefreshRequest{RefreshToken: issued.RefreshToken}, &rotated))
+	assert.NotEqual(t, issued.RefreshToken, rotated.RefreshToken)
+
+	client.token = rotated.Token
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/items", nil, &[]items.Item{}))
+
+	// reused refresh token revokes the whole family.
+	client.token = ""
+	status, code := client.errorCode(http.MethodPost, "/auth/refresh", api.RefreshRequest{RefreshToken: issued.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+
+	status, _ = client.errorCode(http.MethodPost, "/auth/refresh", api.RefreshRequest{RefreshToken: rotated.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	client.token = rotated.Token
+	status, _ = client.errorCode(http.MethodGet, "/items", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, code = client.errorCode(http.MethodPost, "/auth/refresh", api.RefreshRequest{RefreshToken: "invalid"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthenticated", code)
+}
+
+func TestCookieRenewal(t *testing.T) {
+	server := newTestServer(t)
+	email := uuid.NewString() + "@gmail.com"
+	require.NoError(t, server.users.Create(context.Background(), email, "password"))
+
+	client := http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	send := func(method, path string, form url.Values, cookies ...*http.Cookie) (*http.Response, map[string]*http.Cookie) {
+		request, err := http.NewRequest(method, server.url+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		resp, err := client.Do(request)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		set := map[string]*http.Cookie{}
+		for _, cookie := range resp.Cookies() {
+			set[cookie.Name] = cookie
+		}
+		return resp, set
+	}
+
+	login := func(form url.Values) (string, map[string]*http.Cookie) {
+		resp, cookies := send(http.MethodPost, "/login", form)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		require.Contains(t, cookies, "todo")
+		require.Contains(t, cookies, "todo_refresh")
+		return resp.Header.Get("Location"), cookies
+	}
+
+	_, cookies := login(url.Values{"email": {email}, "password": {"password"}})
+	assert.True(t, cookies["todo_refresh"].Expires.IsZero(), "refresh cookie must not outlive browser without remember me")
+
+	itemsPath, cookies := login(url.Values{"email": {email}, "password": {"password"}, "remember": {"on"}})
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), cookies["todo_refresh"].Expires, time.Minute)
+
+	// expired access cookie is renewed with refresh cookie without redirect to login.
+	oldRefresh := &http.Cookie{Name: "todo_refresh", Value: cookies["todo_refresh"].Value}
+	resp, renewed := send(http.MethodGet, itemsPath, nil, oldRefresh)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, renewed, "todo")
+	require.Contains(t, renewed, "todo_refresh")
+	assert.NotEqual(t, oldRefresh.Value, renewed["todo_refresh"].Value)
+
+	resp, _ = send(http.MethodGet, itemsPath, nil, &http.Cookie{Name: "todo", Value: renewed["todo"].Value})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// stolen refresh cookie which was already rotated logs everybody out of the session.
+	resp, _ = send(http.MethodGet, itemsPath, nil, oldRefresh)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	resp, _ = send(http.MethodGet, itemsPath, nil, &http.Cookie{Name: "todo_refresh", Value: renewed["todo_refresh"].Value})
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestPasswordReset(t *testing.T) {
+	server := newConfiguredServer(t, func(config *userauth.Config) {
+		config.Lockout = userauth.LockoutConfig{AccountThreshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	})
+	claims, cookie := server.login(t)
+	itemsPath := "/" + claims.UserID.String() + "/items"
+
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/forgot-password", nil))
+	assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/forgot-password", url.Values{"email": {"nobody@gmail.com"}}, nil))
+	server.auth.Wait()
+	assert.Zero(t, server.mailbox.Len(), "unknown email must not receive mail")
+
+	assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/forgot-password", url.Values{"email": {claims.Email}}, nil))
+	token := server.mailedToken(t, "/reset-password")
+
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/reset-password?token="+url.QueryEscape(token), nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, server.submit(t, http.MethodPost, "/reset-password", url.Values{"token": {token}, "password": {""}}, nil))
+	assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/reset-password", url.Values{"token": {token}, "password": {"new password"}}, nil))
+	assert.Equal(t, http.StatusUnauthorized, server.submit(t, http.MethodPost, "/reset-password", url.Values{"token": {token}, "password": {"other"}}, nil))
+
+	// session opened with old password is revoked.
+	assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, cookie))
+	assert.Equal(t, http.StatusUnauthorized, server.submit(t, http.MethodPost, "/login", url.Values{"email": {claims.Email}, "password": {"password"}}, nil))
+	assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/login", url.Values{"email": {claims.Email}, "password": {"new password"}}, nil))
+
+	assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/forgot-password", url.Values{"email": {claims.Email}}, nil))
+	assert.Equal(t, http.StatusTooManyRequests, server.submit(t, http.MethodPost, "/forgot-password", url.Values{"email": {claims.Email}}, nil))
+	server.auth.Wait()
+}
+
+func TestEmailVerification(t *testing.T) {
+	server := newTestServer(t)
+	email := uuid.NewString() + "@gmail.com"
+
+	form := url.Values{"email": {email}, "password": {"password"}}
+	require.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/register", form, nil))
+	assert.Equal(t, http.StatusConflict, server.submit(t, http.MethodPost, "/register", form, nil))
+	token := server.mailedToken(t, "/verify-email")
+
+	tokens, err := server.auth.Token(context.Background(), email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	claims, err := server.auth.Authorize(context.Background(), tokens.AccessToken)
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: "todo", Value: tokens.AccessToken}
+	itemsPath := "/" + claims.UserID.String() + "/items"
+
+	// unverified user can read, but not change items.
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, itemsPath, cookie))
+	assert.Equal(t, http.StatusForbidden, server.status(t, http.MethodGet, itemsPath+"/create", cookie))
+
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/verify-email?token="+url.QueryEscape(token), nil))
+	assert.Equal(t, http.StatusUnauthorized, server.status(t, http.MethodGet, "/verify-email?token="+url.QueryEscape(token), nil))
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, itemsPath+"/create", cookie))
+
+	// new link is not sent to verified email.
+	server.mailbox.Reset()
+	assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/verify-email", url.Values{"email": {email}}, nil))
+	server.auth.Wait()
+	assert.Zero(t, server.mailbox.Len())
+}
+
+func TestTwoFactor(t *testing.T) {
+	server := newTestServer(t)
+	claims, cookie := server.login(t)
+	client := &apiClient{t: t, server: server, token: cookie.Value}
+	itemsPath := "/" + claims.UserID.String() + "/items"
+
+	// code returns code of secret which is valid now, next codes are used after previous ones.
+	code := func(secret string, next int64) string {
+		code, err := totp.Code(secret, totp.Counter(time.Now())+next)
+		require.NoError(t, err)
+		return code
+	}
+
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/settings/2fa", cookie))
+
+	var enrollment userauth.Enrollment
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/2fa/enroll", nil, &enrollment))
+	status, errCode := client.errorCode(http.MethodPost, "/auth/2fa/confirm", api.CodeRequest{Code: "abcdef"})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "validation_failed", errCode)
+
+	var recovery api.RecoveryCodesResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/2fa/confirm", api.CodeRequest{Code: code(enrollment.Secret, 0)}, &recovery))
+	require.Len(t, recovery.RecoveryCodes, 10)
+	status, errCode = client.errorCode(http.MethodPost, "/auth/2fa/enroll", nil)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "conflict", errCode)
+
+	t.Run("api", func(t *testing.T) {
+		client := &apiClient{t: t, server: server}
+		credentials := api.Credentials{Email: claims.Email, Password: "password"}
+
+		var challenge api.SecondFactorResponse
+		require.Equal(t, http.StatusAccepted, client.do(http.MethodPost, "/auth/token", credentials, &challenge))
+		require.NotEmpty(t, challenge.SecondFactorToken)
+
+		request := api.SecondFactorRequest{SecondFactorToken: challenge.SecondFactorToken, Code: "abcdef"}
+		status, errCode := client.errorCode(http.MethodPost, "/auth/2fa/verify", request)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "unauthenticated", errCode)
+
+		var tokens api.TokenResponse
+		request.Code = recovery.RecoveryCodes[0]
+		require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/2fa/verify", request, &tokens))
+		client.token = tokens.Token
+		assert.Equal(t, http.StatusOK, client.do(http.MethodGet, "/items", nil, &[]items.Item{}))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		form := url.Values{"email": {claims.Email}, "password": {"password"}}
+		assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/login", form, nil), "code page must be shown")
+
+		tokens, err := server.auth.Token(context.Background(), claims.Email, "password", false, sessions.Metadata{})
+		require.NoError(t, err)
+		form = url.Values{"token": {tokens.SecondFactorToken}, "code": {"abcdef"}}
+		assert.Equal(t, http.StatusUnauthorized, server.submit(t, http.MethodPost, "/login/2fa", form, nil))
+		form.Set("code", code(enrollment.Secret, 1))
+		assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/login/2fa", form, nil))
+
+		form = url.Values{"code": {recovery.RecoveryCodes[1]}}
+		assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/settings/2fa/disable", form, cookie))
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, itemsPath, cookie))
+	})
+
+	t.Run("required", func(t *testing.T) {
+		server := newConfiguredServer(t, func(config *userauth.Config) {
+			config.TwoFactor.Required = true
+		})
+		claims, cookie := server.login(t)
+		client := &apiClient{t: t, server: server, token: cookie.Value}
+
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, "/"+claims.UserID.String()+"/items", cookie))
+		status, errCode := client.errorCode(http.MethodGet, "/items", nil)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "two_factor_required", errCode)
+
+		var enrollment userauth.Enrollment
+		require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/2fa/enroll", nil, &enrollment))
+		require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/2fa/confirm", api.CodeRequest{Code: code(enrollment.Secret, 0)}, &api.RecoveryCodesResponse{}))
+		assert.Equal(t, http.StatusOK, client.do(http.MethodGet, "/items", nil, &[]items.Item{}))
+	})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+	provider.SetUser(oidctest.User{Subject: "1", Email: "sso@example.com", EmailVerified: true})
+	server := newConfiguredServer(t, func(config *userauth.Config) {
+		config.OIDC = userauth.DefaultOIDCConfig()
+		config.OIDC.Name = "Example"
+		config.OIDC.Issuer, config.OIDC.ClientID, config.OIDC.ClientSecret = provider.Issuer, provider.ClientID, provider.ClientSecret
+	})
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	browser := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// get sends request with browser cookies and returns response status, body and location.
+	get := func(rawURL string) (int, string, string) {
+		response, err := browser.Get(rawURL)
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		return response.StatusCode, string(body), response.Header.Get("Location")
+	}
+
+	// callback returns url of console where provider sends browser back after login.
+	callback := func() string {
+		status, _, location := get(server.url + "/login/oidc")
+		require.Equal(t, http.StatusFound, status)
+		status, _, location = get(location)
+		require.Equal(t, http.StatusFound, status)
+
+		// provider redirects to public url of console, test server listens elsewhere.
+		callback, err := url.Parse(location)
+		require.NoError(t, err)
+		require.Equal(t, userauth.OIDCCallbackPath, callback.Path)
+		return server.url + callback.RequestURI()
+	}
+
+	_, body, _ := get(server.url + "/login")
+	assert.Contains(t, body, "Sign in with Example")
+
+	status, body, _ := get(callback())
+	require.Equal(t, http.StatusOK, status, body)
+	user, err := server.db.Users().GetByEmail(context.Background(), "sso@example.com")
+	require.NoError(t, err)
+	itemsPath := "/" + user.ID.String() + "/items"
+	assert.Contains(t, body, itemsPath)
+
+	status, _, _ = get(server.url + itemsPath)
+	assert.Equal(t, http.StatusOK, status, "auth cookie must be set")
+
+	// state cookie is used once, so callback can not be replayed.
+	replayed := callback()
+	status, _, _ = get(replayed)
+	require.Equal(t, http.StatusOK, status)
+	status, body, _ = get(replayed)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Contains(t, body, "start again")
+
+	status, _, _ = get(server.url + userauth.OIDCCallbackPath + "?error=access_denied")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	t.Run("disabled", func(t *testing.T) {
+		server := newTestServer(t)
+
+		assert.Equal(t, http.StatusNotFound, server.status(t, http.MethodGet, "/login/oidc", nil))
+		_, body, _ := get(server.url + "/login")
+		assert.NotContains(t, body, "Sign in with")
+	})
+}
+
+func TestAccountSettings(t *testing.T) {
+	server := newTestServer(t)
+	claims, cookie := server.login(t)
+	itemsPath := "/" + claims.UserID.String() + "/items"
+	email := claims.Email
+
+	newToken := func(password string) string {
+		tokens, err := server.auth.Token(context.Background(), email, password, false, sessions.Metadata{UserAgent: "script"})
+		require.NoError(t, err)
+		return tokens.AccessToken
+	}
+	other := &http.Cookie{Name: cookie.Name, Value: newToken("password")}
+
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/settings", cookie))
+
+	t.Run("change password", func(t *testing.T) {
+		form := url.Values{"current": {"wrong"}, "password": {"new password"}, "confirm": {"new password"}}
+		assert.Equal(t, http.StatusUnprocessableEntity, server.submit(t, http.MethodPost, "/settings/password", form, cookie))
+		form = url.Values{"current": {"password"}, "password": {"new password"}, "confirm": {"other"}}
+		assert.Equal(t, http.StatusUnprocessableEntity, server.submit(t, http.MethodPost, "/settings/password", form, cookie))
+
+		form = url.Values{"current": {"password"}, "password": {"new password"}, "confirm": {"new password"}}
+		assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/settings/password", form, cookie))
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, itemsPath, cookie), "current session must stay")
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, other), "other sessions must be revoked")
+	})
+
+	t.Run("change email", func(t *testing.T) {
+		client := &apiClient{t: t, server: server, token: newToken("new password")}
+
+		status, code := client.errorCode(http.MethodPut, "/users/me/email", api.ChangeEmailRequest{Email: "changed@gmail.com", Password: "password"})
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "validation_failed", code)
+
+		email = uuid.NewString() + "@gmail.com"
+		form := url.Values{"email": {email}, "password": {"new password"}}
+		assert.Equal(t, http.StatusOK, server.submit(t, http.MethodPost, "/settings/email", form, cookie))
+
+		var me map[string]interface{}
+		require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/users/me", nil, &me))
+		assert.Equal(t, email, me["email"])
+		assert.NotContains(t, me, "verifiedAt")
+
+		status, code = client.errorCode(http.MethodPost, "/items", api.ItemRequest{Name: "task"})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "email_not_verified", code)
+
+		require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/verify", api.VerifyRequest{Token: server.mailedToken(t, "/verify-email")}, &me))
+		require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/items", api.ItemRequest{Name: "task"}, new(items.Item)))
+
+		require.Equal(t, http.StatusNoContent, client.do(http.MethodPut, "/users/me/password", api.ChangePasswordRequest{CurrentPassword: "new password", Password: "password"}, nil))
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, cookie), "other sessions must be revoked")
+	})
+
+	t.Run("delete account", func(t *testing.T) {
+		cookie := &http.Cookie{Name: cookie.Name, Value: newToken("password")}
+		form := url.Values{"password": {"password"}}
+		assert.Equal(t, http.StatusUnprocessableEntity, server.submit(t, http.MethodPost, "/settings/delete", form, cookie), "deletion must be confirmed")
+		form.Set("confirm", "on")
+		assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/settings/delete", form, cookie))
+
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, cookie))
+		list, err := server.db.Items().List(context.Background(), claims.UserID)
+		require.NoError(t, err)
+		assert.Empty(t, list, "items must be deleted with user")
+	})
+
+	t.Run("confirmation code", func(t *testing.T) {
+		_, cookie := server.login(t)
+		client := &apiClient{t: t, server: server, token: cookie.Value}
+
+		server.mailbox.Reset()
+		require.Equal(t, http.StatusAccepted, client.do(http.MethodPost, "/users/me/confirmation", nil, nil))
+		matches := regexp.MustCompile(`works once:\r?\n(\S+)`).FindStringSubmatch(server.mailbox.String())
+		require.Len(t, matches, 2, server.mailbox.String())
+
+		form := url.Values{"password": {matches[1]}, "confirm": {"on"}}
+		assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/settings/delete", form, cookie))
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, itemsPath, cookie))
+	})
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	admin, adminCookie := server.login(t)
+	user, userCookie := server.login(t)
+	userItems := "/" + user.UserID.String() + "/items"
+
+	_, err := server.items.Create(auth.SetClaims(ctx, user), "task", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusForbidden, server.status(t, http.MethodGet, "/admin", adminCookie), "users can not manage users")
+	require.NoError(t, server.users.SetRole(ctx, admin.UserID, users.RoleAdmin))
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/admin", adminCookie))
+
+	// the only admin can not delete own account.
+	form := url.Values{"password": {"password"}, "confirm": {"on"}}
+	assert.Equal(t, http.StatusConflict, server.submit(t, http.MethodPost, "/settings/delete", form, adminCookie))
+	client := &apiClient{t: t, server: server, token: adminCookie.Value}
+	status, code := client.errorCode(http.MethodDelete, "/users/me", api.DeleteAccountRequest{Password: "password"})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "conflict", code)
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/admin", adminCookie))
+
+	secret, _, err := server.auth.CreateAccessToken(ctx, admin.UserID, "ci", []accesstokens.Scope{accesstokens.ScopeItemsRead}, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, server.status(t, http.MethodGet, "/admin", &http.Cookie{Name: "todo", Value: secret}),
+		"personal access tokens can not manage users")
+
+	t.Run("search", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, server.url+"/admin?q="+url.QueryEscape(user.Email), nil)
+		require.NoError(t, err)
+		request.AddCookie(adminCookie)
+		resp, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), user.Email)
+		assert.Contains(t, string(body), "<td>1</td>", "item count of user must be shown")
+		assert.NotContains(t, string(body), admin.Email)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnprocessableEntity, server.status(t, http.MethodPost, "/admin/users/"+admin.UserID.String()+"/disable", adminCookie),
+			"admin can not disable own account")
+		assert.Equal(t, http.StatusNotFound, server.status(t, http.MethodPost, "/admin/users/"+uuid.NewString()+"/disable", adminCookie))
+
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodPost, "/admin/users/"+user.UserID.String()+"/disable", adminCookie))
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, userItems, userCookie), "sessions of disabled user must be revoked")
+		assert.Equal(t, http.StatusForbidden, server.submit(t, http.MethodPost, "/login", url.Values{"email": {user.Email}, "password": {"password"}}, nil))
+
+		status, code := (&apiClient{t: t, server: server}).errorCode(http.MethodPost, "/auth/token", api.Credentials{Email: user.Email, Password: "password"})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "account_disabled", code)
+
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodPost, "/admin/users/"+user.UserID.String()+"/enable", adminCookie))
+		assert.Equal(t, http.StatusFound, server.submit(t, http.MethodPost, "/login", url.Values{"email": {user.Email}, "password": {"password"}}, nil))
+	})
+
+	t.Run("force logout", func(t *testing.T) {
+		tokens, err := server.auth.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+		require.NoError(t, err)
+		cookie := &http.Cookie{Name: "todo", Value: tokens.AccessToken}
+		require.Equal(t, http.StatusOK, server.status(t, http.MethodGet, userItems, cookie))
+
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodPost, "/admin/users/"+user.UserID.String()+"/logout", adminCookie))
+		assert.Equal(t, http.StatusFound, server.status(t, http.MethodGet, userItems, cookie))
+		assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, "/admin", adminCookie), "admin must stay logged in")
+	})
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"todo/accesstokens"
+)
+
+// AccessTokenExpirations are lifetimes in days offered on access tokens page.
+var AccessTokenExpirations = []int{7, 30, 90, 365}
+
+// AccessTokensPage is data of personal access tokens settings page.
+type AccessTokensPage struct {
+	Tokens      []accesstokens.Token
+	Scopes      []accesstokens.Scope
+	Expirations []int
+	// Secret of just created token, it is shown once.
+	Secret string
+	Error  string
+	Now    time.Time
+}
+
+// AccessTokens is an endpoint which shows personal access tokens of user and a form to create one.
+func (auth *Auth) AccessTokens(w http.ResponseWriter, r *http.Request) {
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	auth.accessTokensPage(w, r, claims.UserID, AccessTokensPage{})
+}
+
+// CreateAccessToken is an endpoint which creates personal access token and shows its secret once.
+func (auth *Auth) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(w, "could not parse access token form", http.StatusBadRequest)
+		return
+	}
+
+	var scopes []accesstokens.Scope
+	for _, scope := range r.Form["scope"] {
+		scopes = append(scopes, accesstokens.Scope(scope))
+	}
+	// missing or malformed lifetime is zero and is rejected by validation.
+	days, _ := strconv.Atoi(r.FormValue("expires"))
+
+	var page AccessTokensPage
+	page.Secret, _, err = auth.service.CreateAccessToken(ctx, claims.UserID, r.FormValue("name"), scopes, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		if !accesstokens.ErrValidation.Has(err) {
+			auth.log.Error("could not create access token " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not create access token", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		page.Error = err.Error()
+	}
+
+	auth.accessTokensPage(w, r, claims.UserID, page)
+}
+
+// RevokeAccessToken is an endpoint which revokes personal access token.
+func (auth *Auth) RevokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = auth.service.RevokeAccessToken(r.Context(), claims.UserID, id)
+	switch {
+	case err == nil, accesstokens.ErrNoToken.Has(err):
+		Redirect(w, r, "/settings/tokens", http.MethodGet)
+	default:
+		auth.log.Error("could not revoke access token " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not revoke access token", http.StatusInternalServerError)
+	}
+}
+
+// accessTokensPage lists tokens of user and renders access tokens page.
+func (auth *Auth) accessTokensPage(w http.ResponseWriter, r *http.Request, userID uuid.UUID, page AccessTokensPage) {
+	var err error
+	page.Tokens, err = auth.service.AccessTokens(r.Context(), userID)
+	if err != nil {
+		auth.log.Error("could not list access tokens " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not list access tokens", http.StatusInternalServerError)
+		return
+	}
+	page.Scopes = accesstokens.Scopes
+	page.Expirations = AccessTokenExpirations
+	page.Now = time.Now()
+
+	if err = auth.templates.AccessTokens.Execute(w, page); err != nil {
+		auth.log.Error("could not execute access tokens template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute access tokens template", http.StatusInternalServerError)
+	}
+}
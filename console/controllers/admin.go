@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+var (
+	// ErrAdmin is an internal error type for admin controller.
+	ErrAdmin = errs.Class("admin controller error")
+)
+
+// AdminTemplates holds all admin related templates.
+type AdminTemplates struct {
+	Users *template.Template
+}
+
+// AdminUser is a user as it is shown to admins.
+type AdminUser struct {
+	users.User
+	Items int
+}
+
+// AdminUsersPage is data of admin users page.
+type AdminUsersPage struct {
+	// AdminID is id of acting admin, who can not disable own account.
+	AdminID uuid.UUID
+	Search  string
+	Users   []AdminUser
+	Message string
+	Error   string
+}
+
+// Admin is a mvc controller which lets admins manage users.
+type Admin struct {
+	log *zap.Logger
+
+	users *users.Service
+	items *items.Service
+	auth  *userauth.Service
+
+	templates AdminTemplates
+}
+
+// NewAdmin is constructor for Admin.
+func NewAdmin(log *zap.Logger, users *users.Service, items *items.Service, auth *userauth.Service, templates AdminTemplates) *Admin {
+	return &Admin{
+		log:       log,
+		users:     users,
+		items:     items,
+		auth:      auth,
+		templates: templates,
+	}
+}
+
+// Users is an endpoint which lists users whose email contains q query parameter with their item counts.
+func (controller *Admin) Users(w http.ResponseWriter, r *http.Request) {
+	controller.usersPage(w, r, AdminUsersPage{})
+}
+
+// Disable is an endpoint which disables user and revokes all sessions of user.
+func (controller *Admin) Disable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if id == claims.UserID {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		controller.usersPage(w, r, AdminUsersPage{Error: "you can not disable your own account"})
+		return
+	}
+
+	err = controller.users.Disable(ctx, id)
+	if err == nil {
+		err = controller.auth.LogoutEverywhere(ctx, id)
+	}
+	controller.userChanged(w, r, err, "user is disabled and logged out")
+}
+
+// Enable is an endpoint which enables disabled user.
+func (controller *Admin) Enable(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	controller.userChanged(w, r, controller.users.Enable(r.Context(), id), "user is enabled")
+}
+
+// Logout is an endpoint which revokes all sessions of user.
+func (controller *Admin) Logout(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	controller.userChanged(w, r, controller.auth.LogoutEverywhere(r.Context(), id), "user is logged out everywhere")
+}
+
+// userChanged renders users page with outcome of change made to user.
+func (controller *Admin) userChanged(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case err == nil:
+		controller.usersPage(w, r, AdminUsersPage{Message: message})
+	case users.ErrNoUser.Has(err):
+		w.WriteHeader(http.StatusNotFound)
+		controller.usersPage(w, r, AdminUsersPage{Error: "user does not exist"})
+	case users.ErrLastAdmin.Has(err):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		controller.usersPage(w, r, AdminUsersPage{Error: "the last admin can not be disabled"})
+	default:
+		controller.log.Error("could not change user " + ErrAdmin.Wrap(err).Error())
+		http.Error(w, "could not change user", http.StatusInternalServerError)
+	}
+}
+
+// usersPage fills page with users matching search and their item counts and renders admin users page.
+func (controller *Admin) usersPage(w http.ResponseWriter, r *http.Request, page AdminUsersPage) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	page.AdminID = claims.UserID
+	page.Search = r.URL.Query().Get("q")
+
+	list, err := controller.users.List(ctx, page.Search)
+	if err != nil {
+		controller.log.Error("could not list users " + ErrAdmin.Wrap(err).Error())
+		http.Error(w, "could not list users", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := controller.items.CountByUser(ctx)
+	if err != nil {
+		controller.log.Error("could not count items " + ErrAdmin.Wrap(err).Error())
+		http.Error(w, "could not count items", http.StatusInternalServerError)
+		return
+	}
+
+	for _, user := range list {
+		page.Users = append(page.Users, AdminUser{User: user, Items: counts[user.ID]})
+	}
+
+	if err = controller.templates.Users.Execute(w, page); err != nil {
+		controller.log.Error("could not execute admin users template " + ErrAdmin.Wrap(err).Error())
+		http.Error(w, "could not execute admin users template", http.StatusInternalServerError)
+	}
+}
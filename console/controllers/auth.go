@@ -1,13 +1,16 @@
 package controllers
 
 import (
+	"context"
 	"html/template"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
 
 	"todo/pkg/auth"
+	"todo/sessions"
 	"todo/users"
 	"todo/users/userauth"
 )
@@ -17,8 +20,39 @@ var AuthError = errs.Class("auth controller error")
 
 // AuthTemplates holds all auth related templates.
 type AuthTemplates struct {
-	Login    *template.Template
-	Register *template.Template
+	Login          *template.Template
+	Register       *template.Template
+	ForgotPassword *template.Template
+	ResetPassword  *template.Template
+	VerifyEmail    *template.Template
+	// LoginSecondFactor asks for second factor code after password.
+	LoginSecondFactor *template.Template
+	TwoFactor         *template.Template
+	// LoginComplete navigates to items after login at external provider.
+	LoginComplete *template.Template
+	AccessTokens  *template.Template
+	Settings      *template.Template
+}
+
+// ForgotPasswordPage is data of forgot password page.
+type ForgotPasswordPage struct {
+	// Sent is true once the form is submitted, whether email is registered or not.
+	Sent  bool
+	Error string
+}
+
+// VerifyEmailPage is data of email verification page.
+type VerifyEmailPage struct {
+	// Sent is true once verification link is requested, whether email is registered or not.
+	Sent     bool
+	Verified bool
+	Error    string
+}
+
+// ResetPasswordPage is data of reset password page.
+type ResetPasswordPage struct {
+	Token string
+	Error string
 }
 
 // Auth login authentication entity.
@@ -49,11 +83,7 @@ func (auth *Auth) Login(w http.ResponseWriter, r *http.Request) {
 	var err error
 	switch r.Method {
 	case http.MethodGet:
-		if err = auth.templates.Login.Execute(w, nil); err != nil {
-			auth.log.Error("could not execute login template" + AuthError.Wrap(err).Error())
-			http.Error(w, "could not execute login template", http.StatusInternalServerError)
-			return
-		}
+		auth.loginPage(w, LoginPage{OIDC: auth.oidcName()})
 	case http.MethodPost:
 		if err = r.ParseForm(); err != nil {
 			http.Error(w, "could not parse login form", http.StatusBadRequest)
@@ -68,22 +98,32 @@ func (auth *Auth) Login(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		response, err := auth.service.Token(ctx, email, password)
+		rememberMe := r.FormValue("remember") != ""
+		tokens, err := auth.service.Token(ctx, email, password, rememberMe, sessions.RequestMetadata(r))
 		if err != nil {
-			auth.log.Error("could not get auth token " + AuthError.Wrap(err).Error())
 			switch {
-			case users.ErrNoUser.Has(err):
-				http.Error(w, "could not get auth token", http.StatusNotFound)
 			case userauth.ErrUnauthenticated.Has(err):
-				http.Error(w, "could not get auth token", http.StatusUnauthorized)
+				http.Error(w, "invalid email or password", http.StatusUnauthorized)
+			case userauth.ErrLocked.Has(err):
+				http.Error(w, "too many failed attempts, try again later", http.StatusTooManyRequests)
+			case userauth.ErrUnverified.Has(err):
+				http.Error(w, "verify your email to log in, a new link can be requested at /verify-email", http.StatusForbidden)
+			case userauth.ErrDisabled.Has(err):
+				http.Error(w, "account is disabled", http.StatusForbidden)
 			default:
+				auth.log.Error("could not get auth token " + AuthError.Wrap(err).Error())
 				http.Error(w, "could not get auth token", http.StatusInternalServerError)
 			}
 
 			return
 		}
 
-		auth.cookie.SetTokenCookie(w, response)
+		if tokens.SecondFactorToken != "" {
+			auth.loginSecondFactorPage(w, LoginSecondFactorPage{Token: tokens.SecondFactorToken, RememberMe: rememberMe})
+			return
+		}
+
+		auth.cookie.SetTokens(w, tokens)
 
 		user, err := auth.users.GetByEmail(ctx, email)
 		if err != nil {
@@ -127,17 +167,172 @@ func (auth *Auth) Register(w http.ResponseWriter, r *http.Request) {
 
 		err := auth.users.Create(ctx, email[0], password[0])
 		if err != nil {
-			auth.log.Error("could not create user " + AuthError.Wrap(err).Error())
-			http.Error(w, "could not create user ", http.StatusInternalServerError)
+			switch {
+			case users.ErrValidation.Has(err):
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			case users.ErrEmailTaken.Has(err):
+				http.Error(w, "email is already registered", http.StatusConflict)
+			default:
+				auth.log.Error("could not create user " + AuthError.Wrap(err).Error())
+				http.Error(w, "could not create user ", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		auth.service.RequestVerification(email[0])
+		auth.verifyEmailPage(w, VerifyEmailPage{Sent: true})
+	}
+}
+
+// VerifyEmail is an endpoint to verify email with token from verification link and to request new link.
+func (auth *Auth) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var page VerifyEmailPage
+
+	switch r.Method {
+	case http.MethodGet:
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			break
+		}
+
+		_, err := auth.service.VerifyEmail(r.Context(), token)
+		switch {
+		case err == nil:
+			page.Verified = true
+		case userauth.ErrUnauthenticated.Has(err):
+			w.WriteHeader(http.StatusUnauthorized)
+			page.Error = "verification link is invalid or expired, request a new one"
+		default:
+			auth.log.Error("could not verify email " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not verify email", http.StatusInternalServerError)
+			return
+		}
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "could not parse verification form", http.StatusBadRequest)
+			return
+		}
+
+		email := r.FormValue("email")
+		if email == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		auth.service.RequestVerification(email)
+		page.Sent = true
+	}
+
+	auth.verifyEmailPage(w, page)
+}
+
+// verifyEmailPage renders email verification page.
+func (auth *Auth) verifyEmailPage(w http.ResponseWriter, page VerifyEmailPage) {
+	if err := auth.templates.VerifyEmail.Execute(w, page); err != nil {
+		auth.log.Error("could not execute verify email template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute verify email template", http.StatusInternalServerError)
+	}
+}
+
+// ForgotPassword is an endpoint to request link to reset password by email.
+func (auth *Auth) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var page ForgotPasswordPage
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "could not parse forgot password form", http.StatusBadRequest)
 			return
 		}
 
-		Redirect(w, r, "/login", http.MethodGet)
+		email := r.FormValue("email")
+		if email == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		err := auth.service.RequestPasswordReset(r.Context(), email, sessions.RequestMetadata(r))
+		switch {
+		case err == nil:
+			page.Sent = true
+		case userauth.ErrLocked.Has(err):
+			w.WriteHeader(http.StatusTooManyRequests)
+			page.Error = "too many requests, try again later"
+		default:
+			auth.log.Error("could not request password reset " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not request password reset", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := auth.templates.ForgotPassword.Execute(w, page); err != nil {
+		auth.log.Error("could not execute forgot password template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute forgot password template", http.StatusInternalServerError)
 	}
 }
 
-// Logout is an endpoint to log out and remove auth cookie from browser.
+// ResetPassword is an endpoint to set new password with token from reset link.
+func (auth *Auth) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	page := ResetPasswordPage{Token: r.URL.Query().Get("token")}
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "could not parse reset password form", http.StatusBadRequest)
+			return
+		}
+
+		page.Token = r.FormValue("token")
+		err := auth.service.ResetPassword(r.Context(), page.Token, r.FormValue("password"))
+		switch {
+		case err == nil:
+			Redirect(w, r, "/login", http.MethodGet)
+			return
+		case userauth.ErrUnauthenticated.Has(err):
+			w.WriteHeader(http.StatusUnauthorized)
+			page.Error = "reset link is invalid or expired, request a new one"
+		case users.ErrValidation.Has(err):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			page.Error = err.Error()
+		default:
+			auth.log.Error("could not reset password " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not reset password", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := auth.templates.ResetPassword.Execute(w, page); err != nil {
+		auth.log.Error("could not execute reset password template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute reset password template", http.StatusInternalServerError)
+	}
+}
+
+// Logout is an endpoint to revoke current session and remove auth cookie from browser.
 func (auth *Auth) Logout(w http.ResponseWriter, r *http.Request) {
-	auth.cookie.RemoveTokenCookie(w)
-	Redirect(w, r, "/login", "GET")
+	logout(auth, w, r, func(ctx context.Context, userID, sessionID uuid.UUID) error {
+		return auth.service.Logout(ctx, sessionID)
+	})
+}
+
+// LogoutEverywhere is an endpoint to revoke all sessions of user and remove auth cookie from browser.
+func (auth *Auth) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	logout(auth, w, r, func(ctx context.Context, userID, sessionID uuid.UUID) error {
+		return auth.service.LogoutEverywhere(ctx, userID)
+	})
+}
+
+// logout revokes sessions of authenticated user, removes auth cookie and redirects to login page.
+func logout(controller *Auth, w http.ResponseWriter, r *http.Request, revoke func(ctx context.Context, userID, sessionID uuid.UUID) error) {
+	ctx := r.Context()
+
+	claims, err := auth.GetClaims(ctx)
+	if err == nil {
+		err = revoke(ctx, claims.UserID, claims.SessionID)
+	}
+	if err != nil {
+		controller.log.Error("could not revoke session " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not log out", http.StatusInternalServerError)
+		return
+	}
+
+	controller.cookie.RemoveTokenCookie(w)
+	Redirect(w, r, "/login", http.MethodGet)
 }
@@ -79,9 +79,9 @@ func (controller *Items) Create(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err = controller.items.Create(ctx, id, name, description); err != nil {
-			controller.log.Error("could not update item:" + ErrItems.Wrap(err).Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if _, err = controller.items.Create(ctx, name, description); err != nil {
+			controller.log.Error("could not create item:" + ErrItems.Wrap(err).Error())
+			http.Error(w, err.Error(), errorStatus(err))
 			return
 		}
 
@@ -89,7 +89,7 @@ func (controller *Items) Create(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// List is an endpoint that returns all users items.
+// List is an endpoint that returns all users items in order of sort query parameter, by name by default.
 func (controller *Items) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
@@ -100,19 +100,29 @@ func (controller *Items) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allItems, err := controller.items.List(ctx, id)
+	sort, err := items.ParseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	allItems, err := controller.items.List(ctx, sort)
 	if err != nil {
 		controller.log.Error("could not get items:" + ErrItems.Wrap(err).Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
 	fields := struct {
 		Items  []items.Item
 		UserID uuid.UUID
+		Sort   items.Sort
+		Sorts  []items.Sort
 	}{
 		Items:  allItems,
 		UserID: id,
+		Sort:   sort,
+		Sorts:  items.Sorts,
 	}
 
 	if err = controller.templates.List.Execute(w, fields); err != nil {
@@ -149,6 +159,12 @@ func (controller *Items) Update(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
+		if _, err = controller.items.Get(ctx, id); err != nil {
+			controller.log.Error("could not get item:" + ErrItems.Wrap(err).Error())
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
 		if err = controller.templates.Update.Execute(w, request); err != nil {
 			controller.log.Error("could not parse template:" + ErrItems.Wrap(err).Error())
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -173,8 +189,8 @@ func (controller *Items) Update(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err = controller.items.Update(ctx, id, name, description); err != nil {
-			controller.log.Error("could not create item:" + ErrItems.Wrap(err).Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			controller.log.Error("could not update item:" + ErrItems.Wrap(err).Error())
+			http.Error(w, err.Error(), errorStatus(err))
 			return
 		}
 
@@ -201,7 +217,7 @@ func (controller *Items) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 
 	if err = controller.items.UpdateStatus(ctx, id); err != nil {
 		controller.log.Error("could not update status of item:" + ErrItems.Wrap(err).Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -227,9 +243,25 @@ func (controller *Items) Delete(w http.ResponseWriter, r *http.Request) {
 
 	if err = controller.items.Delete(ctx, id); err != nil {
 		controller.log.Error("could not delete item:" + ErrItems.Wrap(err).Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
 	Redirect(w, r, "/"+userID.String()+"/items", http.MethodGet)
 }
+
+// errorStatus returns http status which matches items service error.
+func errorStatus(err error) int {
+	switch {
+	case items.ErrNoItem.Has(err):
+		return http.StatusNotFound
+	case items.ErrForbidden.Has(err):
+		return http.StatusForbidden
+	case items.ErrConflict.Has(err):
+		return http.StatusConflict
+	case items.ErrValidation.Has(err):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
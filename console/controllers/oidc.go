@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"todo/sessions"
+	"todo/users/userauth"
+)
+
+// LoginPage is data of login page.
+type LoginPage struct {
+	// OIDC is name of provider users can sign in with, empty when it is not configured.
+	OIDC  string
+	Error string
+}
+
+// LoginOIDC is an endpoint which sends user to log in at OpenID Connect provider.
+func (auth *Auth) LoginOIDC(w http.ResponseWriter, r *http.Request) {
+	authURL, request, err := auth.service.BeginOIDC(r.Context())
+	if err != nil {
+		if userauth.ErrOIDCDisabled.Has(err) {
+			http.NotFound(w, r)
+			return
+		}
+		auth.log.Error("could not begin single sign-on " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not reach sign-in provider", http.StatusBadGateway)
+		return
+	}
+
+	// state, nonce and verifier are url safe base64, so they never contain dots.
+	auth.cookie.SetLoginState(w, strings.Join([]string{request.State, request.Nonce, request.Verifier}, "."),
+		time.Now().Add(userauth.OIDCRequestExpirationTime))
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// LoginOIDCCallback is an endpoint where provider sends user back with authorization code.
+func (auth *Auth) LoginOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var request userauth.OIDCRequest
+	if state, err := auth.cookie.GetLoginState(r); err == nil {
+		if parts := strings.Split(state, "."); len(parts) == 3 {
+			request = userauth.OIDCRequest{State: parts[0], Nonce: parts[1], Verifier: parts[2]}
+		}
+	}
+	auth.cookie.RemoveLoginState(w)
+
+	if query.Get("error") != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		auth.loginPage(w, LoginPage{OIDC: auth.oidcName(), Error: "sign-in was canceled or denied by provider"})
+		return
+	}
+
+	tokens, err := auth.service.CompleteOIDC(ctx, request, query.Get("state"), query.Get("code"), sessions.RequestMetadata(r))
+	if err != nil {
+		switch {
+		case userauth.ErrUnauthenticated.Has(err):
+			w.WriteHeader(http.StatusUnauthorized)
+			auth.loginPage(w, LoginPage{OIDC: auth.oidcName(), Error: err.Error()})
+		case userauth.ErrDisabled.Has(err):
+			w.WriteHeader(http.StatusForbidden)
+			auth.loginPage(w, LoginPage{OIDC: auth.oidcName(), Error: "account is disabled"})
+		case userauth.ErrOIDCDisabled.Has(err):
+			http.NotFound(w, r)
+		default:
+			auth.log.Error("could not complete single sign-on " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not complete sign-in", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if tokens.SecondFactorToken != "" {
+		auth.loginSecondFactorPage(w, LoginSecondFactorPage{Token: tokens.SecondFactorToken})
+		return
+	}
+
+	auth.cookie.SetTokens(w, tokens)
+
+	claims, err := auth.service.Authorize(ctx, tokens.AccessToken)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	// browsers do not send strict cookies after redirect which started at provider,
+	// so the page navigates to items itself instead of redirecting.
+	if err = auth.templates.LoginComplete.Execute(w, "/"+claims.UserID.String()+"/items"); err != nil {
+		auth.log.Error("could not execute login complete template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute login complete template", http.StatusInternalServerError)
+	}
+}
+
+// loginPage renders login page.
+func (auth *Auth) loginPage(w http.ResponseWriter, page LoginPage) {
+	if err := auth.templates.Login.Execute(w, page); err != nil {
+		auth.log.Error("could not execute login template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute login template", http.StatusInternalServerError)
+	}
+}
+
+// oidcName returns name of OpenID Connect provider shown on login page, empty if it is not configured.
+func (auth *Auth) oidcName() string {
+	if !auth.service.OIDCEnabled() {
+		return ""
+	}
+	return auth.service.OIDCName()
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"net/http"
+
+	"todo/sessions"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// SettingsPage is data of account settings page.
+type SettingsPage struct {
+	UserID   string
+	Email    string
+	Verified bool
+	// Admin reports whether user can manage other users.
+	Admin bool
+	// Message confirms change which was just made.
+	Message string
+	Error   string
+}
+
+// Settings is an endpoint which shows account settings: password, email and account deletion.
+func (auth *Auth) Settings(w http.ResponseWriter, r *http.Request) {
+	auth.settingsPage(w, r, SettingsPage{})
+}
+
+// ChangePassword is an endpoint which changes password of user and revokes all other sessions.
+func (auth *Auth) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(w, "could not parse password form", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("password") != r.FormValue("confirm") {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		auth.settingsPage(w, r, SettingsPage{Error: "new passwords do not match"})
+		return
+	}
+
+	err = users.ValidatePassword(r.FormValue("password"))
+	if err == nil {
+		err = auth.service.Reauthenticate(ctx, claims.UserID, r.FormValue("current"), sessions.RequestMetadata(r))
+	}
+	if err == nil {
+		err = auth.users.SetPassword(ctx, claims.UserID, r.FormValue("password"))
+	}
+	if err == nil {
+		err = auth.service.LogoutOthers(ctx, claims.UserID, claims.SessionID)
+	}
+	switch {
+	case err == nil:
+		auth.settingsPage(w, r, SettingsPage{Message: "password is changed, other sessions are logged out"})
+	case users.ErrPassword.Has(err), users.ErrValidation.Has(err):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		auth.settingsPage(w, r, SettingsPage{Error: err.Error()})
+	case userauth.ErrLocked.Has(err):
+		w.WriteHeader(http.StatusTooManyRequests)
+		auth.settingsPage(w, r, SettingsPage{Error: "too many wrong passwords, try again later"})
+	default:
+		auth.log.Error("could not change password " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not change password", http.StatusInternalServerError)
+	}
+}
+
+// ChangeEmail is an endpoint which changes email of user and mails link to verify the new one.
+func (auth *Auth) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(w, "could not parse email form", http.StatusBadRequest)
+		return
+	}
+
+	var user users.User
+	err = auth.service.Reauthenticate(ctx, claims.UserID, r.FormValue("password"), sessions.RequestMetadata(r))
+	if err == nil {
+		user, err = auth.users.ChangeEmail(ctx, claims.UserID, r.FormValue("email"))
+	}
+	if err == nil {
+		err = auth.service.EmailChanged(ctx, claims.Email, user)
+	}
+	switch {
+	case err == nil:
+		auth.settingsPage(w, r, SettingsPage{Message: "email is changed, open the link mailed to " + user.Email + " to verify it"})
+	case users.ErrPassword.Has(err), users.ErrValidation.Has(err):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		auth.settingsPage(w, r, SettingsPage{Error: err.Error()})
+	case userauth.ErrLocked.Has(err):
+		w.WriteHeader(http.StatusTooManyRequests)
+		auth.settingsPage(w, r, SettingsPage{Error: "too many wrong passwords, try again later"})
+	case users.ErrEmailTaken.Has(err):
+		w.WriteHeader(http.StatusConflict)
+		auth.settingsPage(w, r, SettingsPage{Error: "email is already registered"})
+	default:
+		auth.log.Error("could not change email " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not change email", http.StatusInternalServerError)
+	}
+}
+
+// SendConfirmationCode is an endpoint which mails code that confirms account changes instead of password,
+// users who signed in only with OpenID Connect have no password to confirm them with.
+func (auth *Auth) SendConfirmationCode(w http.ResponseWriter, r *http.Request) {
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	err = auth.service.SendConfirmationCode(r.Context(), claims.UserID, sessions.RequestMetadata(r))
+	switch {
+	case err == nil:
+		auth.settingsPage(w, r, SettingsPage{Message: "confirmation code is mailed to " + claims.Email + ", enter it instead of password"})
+	case userauth.ErrLocked.Has(err):
+		w.WriteHeader(http.StatusTooManyRequests)
+		auth.settingsPage(w, r, SettingsPage{Error: "too many requests, try again later"})
+	default:
+		auth.log.Error("could not send confirmation code " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not send confirmation code", http.StatusInternalServerError)
+	}
+}
+
+// DeleteAccount is an endpoint which deletes user with all items and logs out.
+func (auth *Auth) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(w, "could not parse delete form", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("confirm") == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		auth.settingsPage(w, r, SettingsPage{Error: "confirm that account and all items are deleted"})
+		return
+	}
+
+	err = auth.service.Reauthenticate(r.Context(), claims.UserID, r.FormValue("password"), sessions.RequestMetadata(r))
+	if err == nil {
+		err = auth.users.Delete(r.Context(), claims.UserID)
+	}
+	switch {
+	case err == nil:
+		auth.cookie.RemoveTokenCookie(w)
+		Redirect(w, r, "/login", http.MethodGet)
+	case users.ErrPassword.Has(err):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		auth.settingsPage(w, r, SettingsPage{Error: err.Error()})
+	case userauth.ErrLocked.Has(err):
+		w.WriteHeader(http.StatusTooManyRequests)
+		auth.settingsPage(w, r, SettingsPage{Error: "too many wrong passwords, try again later"})
+	case users.ErrLastAdmin.Has(err):
+		w.WriteHeader(http.StatusConflict)
+		auth.settingsPage(w, r, SettingsPage{Error: "you are the last admin, make other user admin before deleting your account"})
+	default:
+		auth.log.Error("could not delete account " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not delete account", http.StatusInternalServerError)
+	}
+}
+
+// settingsPage fills page with current email of user and renders account settings page.
+func (auth *Auth) settingsPage(w http.ResponseWriter, r *http.Request, page SettingsPage) {
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := auth.users.GetByID(r.Context(), claims.UserID)
+	if err != nil {
+		auth.log.Error("could not get user " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not get user", http.StatusInternalServerError)
+		return
+	}
+	page.UserID, page.Email, page.Verified = user.ID.String(), user.Email, user.Verified()
+	page.Admin = user.Role.Can(users.PermissionManageUsers)
+
+	if err = auth.templates.Settings.Execute(w, page); err != nil {
+		auth.log.Error("could not execute settings template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute settings template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"html/template"
+	"net/http"
+
+	"rsc.io/qr"
+
+	"todo/pkg/auth"
+	"todo/sessions"
+	"todo/users/userauth"
+)
+
+// LoginSecondFactorPage is data of the second login step.
+type LoginSecondFactorPage struct {
+	// Token is second factor token issued after password check.
+	Token      string
+	RememberMe bool
+	Error      string
+}
+
+// TwoFactorPage is data of second factor settings page.
+type TwoFactorPage struct {
+	Enabled bool
+	// Required is true when admin requires second factor from everyone, so it can not be disabled.
+	Required bool
+	// QRCode is png image of enrollment uri as data url.
+	QRCode template.URL
+	Secret string
+	// RecoveryCodes are shown once right after enrollment is confirmed.
+	RecoveryCodes []string
+	Error         string
+}
+
+// LoginSecondFactor is an endpoint to exchange second factor token and code for auth cookie.
+func (auth *Auth) LoginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "could not parse second factor form", http.StatusBadRequest)
+		return
+	}
+
+	page := LoginSecondFactorPage{Token: r.FormValue("token"), RememberMe: r.FormValue("remember") != ""}
+	tokens, err := auth.service.SecondFactor(ctx, page.Token, r.FormValue("code"), page.RememberMe, sessions.RequestMetadata(r))
+	if err != nil {
+		switch {
+		case userauth.ErrUnauthenticated.Has(err):
+			w.WriteHeader(http.StatusUnauthorized)
+			page.Error = err.Error()
+		case userauth.ErrLocked.Has(err):
+			w.WriteHeader(http.StatusTooManyRequests)
+			page.Error = "too many failed attempts, try again later"
+		case userauth.ErrDisabled.Has(err):
+			w.WriteHeader(http.StatusForbidden)
+			page.Error = "account is disabled"
+		default:
+			auth.log.Error("could not check second factor " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not check second factor", http.StatusInternalServerError)
+			return
+		}
+
+		auth.loginSecondFactorPage(w, page)
+		return
+	}
+
+	auth.cookie.SetTokens(w, tokens)
+
+	claims, err := auth.service.Authorize(ctx, tokens.AccessToken)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	Redirect(w, r, "/"+claims.UserID.String()+"/items", http.MethodGet)
+}
+
+// loginSecondFactorPage renders the second login step.
+func (auth *Auth) loginSecondFactorPage(w http.ResponseWriter, page LoginSecondFactorPage) {
+	if err := auth.templates.LoginSecondFactor.Execute(w, page); err != nil {
+		auth.log.Error("could not execute login second factor template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute login second factor template", http.StatusInternalServerError)
+	}
+}
+
+// TwoFactor is an endpoint which shows whether second factor is enabled and starts enrollment if it is not.
+func (auth *Auth) TwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	page := TwoFactorPage{Required: auth.service.TwoFactorRequired()}
+	page.Enabled, err = auth.service.TwoFactorEnabled(ctx, claims.UserID)
+	if err != nil {
+		auth.log.Error("could not get second factor " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not get second factor", http.StatusInternalServerError)
+		return
+	}
+
+	if !page.Enabled {
+		if err = auth.beginEnrollment(r, &page); err != nil {
+			auth.log.Error("could not begin enrollment " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not begin enrollment", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	auth.twoFactorPage(w, page)
+}
+
+// ConfirmTwoFactor is an endpoint to enable second factor with code from authenticator app.
+func (auth *Auth) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(w, "could not parse second factor form", http.StatusBadRequest)
+		return
+	}
+
+	var page TwoFactorPage
+	page.RecoveryCodes, err = auth.service.ConfirmEnrollment(ctx, claims.UserID, r.FormValue("code"))
+	switch {
+	case err == nil:
+		page.Enabled = true
+	case userauth.ErrInvalidCode.Has(err):
+		if err = auth.beginEnrollment(r, &page); err != nil {
+			auth.log.Error("could not begin enrollment " + AuthError.Wrap(err).Error())
+			http.Error(w, "could not begin enrollment", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		page.Error = "code is wrong, check time on your phone and try again"
+	case userauth.ErrTwoFactorState.Has(err):
+		w.WriteHeader(http.StatusConflict)
+		page.Error = err.Error()
+	default:
+		auth.log.Error("could not confirm enrollment " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	auth.twoFactorPage(w, page)
+}
+
+// DisableTwoFactor is an endpoint to disable second factor with current code or recovery code.
+func (auth *Auth) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := getClaims(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(w, "could not parse second factor form", http.StatusBadRequest)
+		return
+	}
+
+	err = auth.service.DisableTwoFactor(ctx, claims.UserID, r.FormValue("code"))
+	switch {
+	case err == nil:
+		Redirect(w, r, "/settings/2fa", http.MethodGet)
+		return
+	case userauth.ErrInvalidCode.Has(err):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		auth.twoFactorPage(w, TwoFactorPage{Enabled: true, Error: "code is wrong or was already used"})
+	case userauth.ErrTwoFactorState.Has(err):
+		w.WriteHeader(http.StatusConflict)
+		auth.twoFactorPage(w, TwoFactorPage{Enabled: true, Required: true, Error: err.Error()})
+	default:
+		auth.log.Error("could not disable second factor " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not disable second factor", http.StatusInternalServerError)
+	}
+}
+
+// beginEnrollment starts enrollment and puts its secret and QR code into page.
+func (auth *Auth) beginEnrollment(r *http.Request, page *TwoFactorPage) error {
+	claims, err := getClaims(r)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := auth.service.BeginEnrollment(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	page.Secret = enrollment.Secret
+	page.QRCode, err = qrCode(enrollment.URI)
+	return err
+}
+
+// twoFactorPage renders second factor settings page.
+func (auth *Auth) twoFactorPage(w http.ResponseWriter, page TwoFactorPage) {
+	if err := auth.templates.TwoFactor.Execute(w, page); err != nil {
+		auth.log.Error("could not execute two factor template " + AuthError.Wrap(err).Error())
+		http.Error(w, "could not execute two factor template", http.StatusInternalServerError)
+	}
+}
+
+// qrCode returns png QR code of text as data url.
+func qrCode(text string) (template.URL, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return "", AuthError.Wrap(err)
+	}
+
+	// data url is built from encoded png only, so it is safe to render unescaped.
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(code.PNG())), nil
+}
+
+// getClaims returns claims of authenticated request.
+func getClaims(r *http.Request) (auth.Claims, error) {
+	return auth.GetClaims(r.Context())
+}
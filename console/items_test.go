@@ -0,0 +1,191 @@
+package console_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"todo"
+	"todo/console"
+	"todo/database/memdb"
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/pkg/mail"
+	"todo/sessions"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// testServer is running console server backed by in-memory database.
+type testServer struct {
+	url string
+	db  todo.DB
+	// mailbox receives mails sent by server.
+	mailbox *bytes.Buffer
+
+	users *users.Service
+	items *items.Service
+	auth  *userauth.Service
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return newConfiguredServer(t, nil)
+}
+
+// newConfiguredServer returns test server whose default auth config is changed by configure.
+func newConfiguredServer(t *testing.T, configure func(config *userauth.Config)) *testServer {
+	db := memdb.New()
+
+	config := console.Config{StaticDir: filepath.Join("..", "web")}
+	config.Auth.CookieName = "todo"
+	config.Auth.Path = "/"
+
+	keyring, err := auth.NewKeyring(auth.KeyringConfig{
+		Active: "test",
+		Keys:   []auth.SigningKey{{ID: "test", Secret: "0123456789abcdef"}},
+	})
+	require.NoError(t, err)
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	authConfig := userauth.Config{
+		TokenTTL:      time.Hour,
+		RefreshTTL:    24 * time.Hour,
+		RememberMeTTL: 30 * 24 * time.Hour,
+		ResetTTL:      time.Hour,
+		PublicURL:     "http://localhost:8087",
+		Verification:  userauth.DefaultVerificationConfig(),
+		TwoFactor:     userauth.DefaultTwoFactorConfig(),
+	}
+	if configure != nil {
+		configure(&authConfig)
+	}
+
+	mailbox := new(bytes.Buffer)
+	server := &testServer{
+		url:     "http://" + listener.Addr().String(),
+		db:      db,
+		mailbox: mailbox,
+		users:   users.New(db.Users(), passwords),
+		items:   items.New(db.Items()),
+		auth: userauth.NewService(zap.NewNop(), db.Users(), db.Sessions(), db.Tokens(), db.TwoFactor(), db.Identities(), db.AccessTokens(), auth.TokenSigner{Keyring: keyring}, passwords,
+			mail.NewWriter("todo@localhost", mailbox), authConfig),
+	}
+
+	endpoint, err := console.NewServer(config, listener, server.auth, zap.NewNop(), server.items, server.users)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- endpoint.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	return server
+}
+
+// login creates user with verified email and returns its claims and auth cookie.
+func (server *testServer) login(t *testing.T) (auth.Claims, *http.Cookie) {
+	ctx := context.Background()
+	email := uuid.NewString() + "@gmail.com"
+
+	require.NoError(t, server.users.Create(ctx, email, "password"))
+	user, err := server.users.GetByEmail(ctx, email)
+	require.NoError(t, err)
+	require.NoError(t, server.db.Users().Verify(ctx, user.ID, time.Now().UTC()))
+
+	tokens, err := server.auth.Token(ctx, email, "password", false, sessions.Metadata{UserAgent: "test", IP: "127.0.0.1"})
+	require.NoError(t, err)
+
+	claims, err := server.auth.Authorize(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+
+	return claims, &http.Cookie{Name: "todo", Value: tokens.AccessToken}
+}
+
+// mailedToken returns token of the last link to page mailed by server.
+func (server *testServer) mailedToken(t *testing.T, page string) string {
+	server.auth.Wait()
+	matches := regexp.MustCompile(page+`\?token=(\S+)`).FindAllStringSubmatch(server.mailbox.String(), -1)
+	require.NotEmpty(t, matches, server.mailbox.String())
+
+	token, err := url.QueryUnescape(matches[len(matches)-1][1])
+	require.NoError(t, err)
+	return token
+}
+
+// status sends request without following redirects and returns response status.
+func (server *testServer) status(t *testing.T, method, path string, cookie *http.Cookie) int {
+	return server.submit(t, method, path, nil, cookie)
+}
+
+// submit sends form without following redirects and returns response status.
+func (server *testServer) submit(t *testing.T, method, path string, form url.Values, cookie *http.Cookie) int {
+	request, err := http.NewRequest(method, server.url+path, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	client := http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(request)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	return resp.StatusCode
+}
+
+func TestItemsOwnership(t *testing.T) {
+	server := newTestServer(t)
+	owner, ownerCookie := server.login(t)
+	intruder, intruderCookie := server.login(t)
+
+	ownerCtx := auth.SetClaims(context.Background(), owner)
+	item, err := server.items.Create(ownerCtx, "task", "description")
+	require.NoError(t, err)
+
+	ownerItems := "/" + owner.UserID.String() + "/items"
+	intruderItems := "/" + intruder.UserID.String() + "/items"
+
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, ownerItems, ownerCookie))
+	assert.Equal(t, http.StatusOK, server.status(t, http.MethodGet, ownerItems+"?sort=created", ownerCookie))
+	assert.Equal(t, http.StatusUnprocessableEntity, server.status(t, http.MethodGet, ownerItems+"?sort=priority", ownerCookie))
+
+	// other user's pages are forbidden.
+	assert.Equal(t, http.StatusForbidden, server.status(t, http.MethodGet, ownerItems, intruderCookie))
+	assert.Equal(t, http.StatusForbidden, server.status(t, http.MethodGet, ownerItems+"/delete/"+item.ID.String(), intruderCookie))
+
+	// other user's items are not found through own pages, so that their existence is not disclosed.
+	for _, path := range []string{
+		intruderItems + "/update/" + item.ID.String(),
+		intruderItems + "/update-status/" + item.ID.String(),
+		intruderItems + "/delete/" + item.ID.String(),
+	} {
+		assert.Equal(t, http.StatusNotFound, server.status(t, http.MethodGet, path, intruderCookie), path)
+	}
+
+	stored, err := server.items.Get(ownerCtx, item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, item, stored)
+}
@@ -0,0 +1,478 @@
+package console
+
+import (
+	"net/http"
+
+	"todo/accesstokens"
+	"todo/console/api"
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/pkg/openapi"
+	"todo/sessions"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// openAPIDocument describes every route of the server, html pages included.
+// Every route registered in NewServer must have an operation here.
+func openAPIDocument(cookieName string) openapi.Document {
+	item := openapi.SchemaOf(items.Item{})
+	item.Properties["status"].Enum = []interface{}{items.StatusTODO, items.StatusInProgress, items.StatusCompleted}
+
+	claims := openapi.SchemaOf(auth.JWTClaims{})
+	claims.Description = "Payload of auth token, a JWT signed with HS256 or EdDSA key named by kid header."
+
+	scopes := make([]interface{}, 0, len(accesstokens.Scopes))
+	for _, scope := range accesstokens.Scopes {
+		scopes = append(scopes, scope)
+	}
+	accessToken := openapi.SchemaOf(accesstokens.Token{})
+	accessToken.Properties["scopes"].Items.Enum = scopes
+	accessTokenRequest := openapi.SchemaOf(api.AccessTokenRequest{})
+	accessTokenRequest.Properties["scopes"].Items.Enum = scopes
+
+	document := openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "TODO",
+			Description: "JSON api under /api/v1 and html console pages.",
+			Version:     "1",
+		},
+		Paths: map[string]openapi.PathItem{},
+		Components: openapi.Components{
+			Schemas: map[string]*openapi.Schema{
+				"Item":                  item,
+				"ItemRequest":           openapi.SchemaOf(api.ItemRequest{}),
+				"User":                  openapi.SchemaOf(users.User{}),
+				"Claims":                claims,
+				"Credentials":           openapi.SchemaOf(api.Credentials{}),
+				"TokenResponse":         openapi.SchemaOf(api.TokenResponse{}),
+				"SecondFactorResponse":  openapi.SchemaOf(api.SecondFactorResponse{}),
+				"SecondFactorRequest":   openapi.SchemaOf(api.SecondFactorRequest{}),
+				"Enrollment":            openapi.SchemaOf(userauth.Enrollment{}),
+				"CodeRequest":           openapi.SchemaOf(api.CodeRequest{}),
+				"RecoveryCodesResponse": openapi.SchemaOf(api.RecoveryCodesResponse{}),
+				"RefreshRequest":        openapi.SchemaOf(api.RefreshRequest{}),
+				"VerifyRequest":         openapi.SchemaOf(api.VerifyRequest{}),
+				"ResendRequest":         openapi.SchemaOf(api.ResendRequest{}),
+				"Session":               openapi.SchemaOf(sessions.Session{}),
+				"ErrorResponse":         openapi.SchemaOf(api.ErrorResponse{}),
+				"JSONWebKeySet":         openapi.SchemaOf(auth.JSONWebKeySet{}),
+				"AccessToken":           accessToken,
+				"AccessTokenRequest":    accessTokenRequest,
+				"AccessTokenResponse":   openapi.SchemaOf(api.AccessTokenResponse{}),
+				"ChangePasswordRequest": openapi.SchemaOf(api.ChangePasswordRequest{}),
+				"ChangeEmailRequest":    openapi.SchemaOf(api.ChangeEmailRequest{}),
+				"DeleteAccountRequest":  openapi.SchemaOf(api.DeleteAccountRequest{}),
+			},
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+				"apiKey": {Type: "apiKey", In: "header", Name: auth.APIKeyHeader, Description: "Personal access token, session tokens are not accepted."},
+				"cookie": {Type: "apiKey", In: "cookie", Name: cookieName},
+			},
+		},
+	}
+
+	add := func(method, path string, operation openapi.Operation) {
+		if document.Paths[path] == nil {
+			document.Paths[path] = openapi.PathItem{}
+		}
+		document.Paths[path][method] = operation
+	}
+
+	authenticated := []map[string][]string{{"bearer": {}}, {"apiKey": {}}, {"cookie": {}}}
+	jsonResponse := func(description string, schema *openapi.Schema) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(schema)}
+	}
+	jsonBody := func(schema *openapi.Schema) *openapi.RequestBody {
+		return &openapi.RequestBody{Required: true, Content: openapi.JSON(schema)}
+	}
+	apiError := func(description string) openapi.Response {
+		return jsonResponse(description, openapi.Ref("ErrorResponse"))
+	}
+	pathID := func(name string) openapi.Parameter {
+		return openapi.Parameter{Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}}
+	}
+
+	// json api.
+	add("get", "/api/v1/openapi.json", openapi.Operation{
+		Summary:   "This document.",
+		Tags:      []string{"api"},
+		Responses: map[string]openapi.Response{"200": {Description: "OpenAPI document.", Content: openapi.JSON(&openapi.Schema{Type: "object"})}},
+	})
+	add("get", "/.well-known/jwks.json", openapi.Operation{
+		Summary:   "Public keys which verify EdDSA auth tokens.",
+		Tags:      []string{"auth"},
+		Responses: map[string]openapi.Response{"200": jsonResponse("JSON web key set.", openapi.Ref("JSONWebKeySet"))},
+	})
+	add("post", "/api/v1/auth/register", openapi.Operation{
+		Summary:     "Register user.",
+		Tags:        []string{"auth"},
+		RequestBody: jsonBody(openapi.Ref("Credentials")),
+		Responses: map[string]openapi.Response{
+			"201": jsonResponse("Registered user, verification link is mailed to email.", openapi.Ref("User")),
+			"400": apiError("Malformed request."),
+			"409": apiError("Email is already registered."),
+			"422": apiError("Invalid email or password."),
+		},
+	})
+	add("post", "/api/v1/auth/verify", openapi.Operation{
+		Summary:     "Verify email with token from verification link.",
+		Tags:        []string{"auth"},
+		RequestBody: jsonBody(openapi.Ref("VerifyRequest")),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Verified user.", openapi.Ref("User")),
+			"401": apiError("Invalid, expired or used token."),
+			"422": apiError("Missing token."),
+		},
+	})
+	add("post", "/api/v1/auth/verify/resend", openapi.Operation{
+		Summary:     "Mail new verification link, the response is the same whether email is registered or not.",
+		Tags:        []string{"auth"},
+		RequestBody: jsonBody(openapi.Ref("ResendRequest")),
+		Responses: map[string]openapi.Response{
+			"202": {Description: "Link is sent if email is registered and not verified."},
+			"422": apiError("Missing email."),
+		},
+	})
+	add("post", "/api/v1/auth/token", openapi.Operation{
+		Summary:     "Exchange credentials for auth token.",
+		Tags:        []string{"auth"},
+		RequestBody: jsonBody(openapi.Ref("Credentials")),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Auth token.", openapi.Ref("TokenResponse")),
+			"202": jsonResponse("Password is correct, second factor token is exchanged for auth token at /api/v1/auth/2fa/verify.", openapi.Ref("SecondFactorResponse")),
+			"401": apiError("Invalid credentials, unknown email is reported the same way."),
+			"403": apiError("Account is disabled, or email is not verified and auth.verification.unverified is blocked."),
+			"422": apiError("Missing email or password."),
+			"429": apiError("Login is locked after too many failed attempts."),
+		},
+	})
+	add("post", "/api/v1/auth/2fa/verify", openapi.Operation{
+		Summary:     "Exchange second factor token and TOTP or recovery code for auth token.",
+		Tags:        []string{"auth"},
+		RequestBody: jsonBody(openapi.Ref("SecondFactorRequest")),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Auth token.", openapi.Ref("TokenResponse")),
+			"401": apiError("Invalid or expired second factor token, wrong or used code."),
+			"403": apiError("Account is disabled."),
+			"422": apiError("Missing second factor token or code."),
+			"429": apiError("Second factor is locked after too many wrong codes."),
+		},
+	})
+	add("post", "/api/v1/auth/refresh", openapi.Operation{
+		Summary:     "Exchange refresh token for new tokens, reused refresh token revokes its session.",
+		Tags:        []string{"auth"},
+		RequestBody: jsonBody(openapi.Ref("RefreshRequest")),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("New auth and refresh tokens.", openapi.Ref("TokenResponse")),
+			"401": apiError("Invalid, expired or reused refresh token."),
+			"403": apiError("Account is disabled."),
+			"422": apiError("Missing refresh token."),
+		},
+	})
+	add("post", "/api/v1/auth/logout", openapi.Operation{
+		Summary:  "Revoke session of auth token.",
+		Tags:     []string{"auth"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Session is revoked."},
+			"401": apiError("Not authenticated."),
+		},
+	})
+	add("post", "/api/v1/auth/logout-all", openapi.Operation{
+		Summary:  "Revoke all sessions of authenticated user.",
+		Tags:     []string{"auth"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Sessions are revoked."},
+			"401": apiError("Not authenticated."),
+		},
+	})
+	add("get", "/api/v1/auth/sessions", openapi.Operation{
+		Summary:  "List sessions of authenticated user, the most recent first.",
+		Tags:     []string{"auth"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Sessions.", &openapi.Schema{Type: "array", Items: openapi.Ref("Session")}),
+			"401": apiError("Not authenticated."),
+		},
+	})
+	add("post", "/api/v1/auth/2fa/enroll", openapi.Operation{
+		Summary:  "Start enrollment of TOTP second factor, unconfirmed enrollment is returned again.",
+		Tags:     []string{"auth"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Secret and otpauth uri for authenticator app.", openapi.Ref("Enrollment")),
+			"401": apiError("Not authenticated."),
+			"409": apiError("Second factor is already enabled."),
+		},
+	})
+	add("post", "/api/v1/auth/2fa/confirm", openapi.Operation{
+		Summary:     "Enable second factor with code from authenticator app.",
+		Tags:        []string{"auth"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("CodeRequest")),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Recovery codes, they are returned only once.", openapi.Ref("RecoveryCodesResponse")),
+			"401": apiError("Not authenticated."),
+			"409": apiError("Enrollment is not started or second factor is already enabled."),
+			"422": apiError("Wrong code."),
+		},
+	})
+	add("post", "/api/v1/auth/2fa/disable", openapi.Operation{
+		Summary:     "Disable second factor with TOTP or recovery code.",
+		Tags:        []string{"auth"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("CodeRequest")),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Second factor is disabled."},
+			"401": apiError("Not authenticated."),
+			"409": apiError("Second factor is not enabled or is required for all users."),
+			"422": apiError("Wrong or used code."),
+		},
+	})
+	add("get", "/api/v1/auth/tokens", openapi.Operation{
+		Summary:  "List personal access tokens of user, the most recent first.",
+		Tags:     []string{"auth"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Personal access tokens.", &openapi.Schema{Type: "array", Items: openapi.Ref("AccessToken")}),
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage tokens."),
+		},
+	})
+	add("post", "/api/v1/auth/tokens", openapi.Operation{
+		Summary:     "Create personal access token, its secret is returned only once.",
+		Tags:        []string{"auth"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("AccessTokenRequest")),
+		Responses: map[string]openapi.Response{
+			"201": jsonResponse("Created token and its secret.", openapi.Ref("AccessTokenResponse")),
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage tokens."),
+			"422": apiError("Invalid name, scopes or lifetime."),
+		},
+	})
+	add("delete", "/api/v1/auth/tokens/{id}", openapi.Operation{
+		Summary:    "Revoke personal access token.",
+		Tags:       []string{"auth"},
+		Security:   authenticated,
+		Parameters: []openapi.Parameter{pathID("id")},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Token is revoked."},
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage tokens."),
+			"404": apiError("Token does not exist."),
+		},
+	})
+	add("get", "/api/v1/users/me", openapi.Operation{
+		Summary:  "Profile of authenticated user.",
+		Tags:     []string{"users"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("User profile.", openapi.Ref("User")),
+			"401": apiError("Not authenticated."),
+		},
+	})
+	add("delete", "/api/v1/users/me", openapi.Operation{
+		Summary:     "Delete authenticated user with all items, password confirms it.",
+		Tags:        []string{"users"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("DeleteAccountRequest")),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "User is deleted."},
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage account."),
+			"409": apiError("User is the last enabled admin."),
+			"422": apiError("Wrong password."),
+			"429": apiError("Too many wrong passwords."),
+		},
+	})
+	add("put", "/api/v1/users/me/password", openapi.Operation{
+		Summary:     "Change password and revoke all other sessions.",
+		Tags:        []string{"users"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("ChangePasswordRequest")),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Password is changed."},
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage account."),
+			"422": apiError("Wrong current password or invalid new one."),
+			"429": apiError("Too many wrong passwords."),
+		},
+	})
+	add("put", "/api/v1/users/me/email", openapi.Operation{
+		Summary:     "Change email, it is unverified until link mailed to it is opened.",
+		Tags:        []string{"users"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("ChangeEmailRequest")),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Updated user profile.", openapi.Ref("User")),
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage account."),
+			"409": apiError("Email is registered by other user."),
+			"422": apiError("Wrong password, invalid or unchanged email."),
+			"429": apiError("Too many wrong passwords."),
+		},
+	})
+	add("post", "/api/v1/users/me/confirmation", openapi.Operation{
+		Summary:  "Mail code which confirms account changes instead of password, for users without password.",
+		Tags:     []string{"users"},
+		Security: authenticated,
+		Responses: map[string]openapi.Response{
+			"202": {Description: "Code is mailed."},
+			"401": apiError("Not authenticated."),
+			"403": apiError("Personal access tokens can not manage account."),
+			"429": apiError("Too many requests."),
+		},
+	})
+	sorts := &openapi.Schema{Type: "string"}
+	for _, sort := range items.Sorts {
+		sorts.Enum = append(sorts.Enum, sort)
+	}
+	sortParameter := openapi.Parameter{Name: "sort", In: "query", Schema: sorts}
+	add("get", "/api/v1/items", openapi.Operation{
+		Summary: "List items of authenticated user ordered by name, " +
+			"or the most recently created, updated or completed first.",
+		Tags:       []string{"items"},
+		Security:   authenticated,
+		Parameters: []openapi.Parameter{sortParameter},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Items.", &openapi.Schema{Type: "array", Items: openapi.Ref("Item")}),
+			"401": apiError("Not authenticated."),
+			"403": apiError("Token has no items:read scope, or second factor is required and not enrolled."),
+			"422": apiError("Unknown sort."),
+		},
+	})
+	add("post", "/api/v1/items", openapi.Operation{
+		Summary:     "Create item.",
+		Tags:        []string{"items"},
+		Security:    authenticated,
+		RequestBody: jsonBody(openapi.Ref("ItemRequest")),
+		Responses: map[string]openapi.Response{
+			"201": jsonResponse("Created item.", openapi.Ref("Item")),
+			"401": apiError("Not authenticated."),
+			"403": apiError("Token has no items:write scope, email of user is not verified, or second factor is required and not enrolled."),
+			"422": apiError("Invalid item."),
+		},
+	})
+	itemResponses := func(description string) map[string]openapi.Response {
+		return map[string]openapi.Response{
+			"200": jsonResponse(description, openapi.Ref("Item")),
+			"401": apiError("Not authenticated."),
+			"403": apiError("Token has no items scope, email of user is not verified, or second factor is required and not enrolled."),
+			"404": apiError("Item does not exist or is owned by other user."),
+			"422": apiError("Invalid request."),
+		}
+	}
+	add("get", "/api/v1/items/{id}", openapi.Operation{
+		Summary:    "Get item.",
+		Tags:       []string{"items"},
+		Security:   authenticated,
+		Parameters: []openapi.Parameter{pathID("id")},
+		Responses:  itemResponses("Item."),
+	})
+	add("put", "/api/v1/items/{id}", openapi.Operation{
+		Summary:     "Update name and description of item.",
+		Tags:        []string{"items"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{pathID("id")},
+		RequestBody: jsonBody(openapi.Ref("ItemRequest")),
+		Responses:   itemResponses("Updated item."),
+	})
+	add("delete", "/api/v1/items/{id}", openapi.Operation{
+		Summary:    "Delete item.",
+		Tags:       []string{"items"},
+		Security:   authenticated,
+		Parameters: []openapi.Parameter{pathID("id")},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Item is deleted."},
+			"401": apiError("Not authenticated."),
+			"403": apiError("Token has no items scope, email of user is not verified, or second factor is required and not enrolled."),
+			"404": apiError("Item does not exist or is owned by other user."),
+		},
+	})
+	statusResponses := itemResponses("Updated item.")
+	statusResponses["409"] = apiError("Item status was changed by other request.")
+	add("post", "/api/v1/items/{id}/status", openapi.Operation{
+		Summary:    "Move item to the next status, completed items can not be moved.",
+		Tags:       []string{"items"},
+		Security:   authenticated,
+		Parameters: []openapi.Parameter{pathID("id")},
+		Responses:  statusResponses,
+	})
+
+	// html console.
+	page := func(summary string, parameters ...openapi.Parameter) openapi.Operation {
+		return openapi.Operation{
+			Summary:    summary,
+			Tags:       []string{"console"},
+			Parameters: parameters,
+			Responses: map[string]openapi.Response{
+				"200": {Description: "Html page.", Content: map[string]openapi.MediaType{"text/html": {}}},
+				"302": {Description: "Redirect to the next page."},
+			},
+		}
+	}
+	form := func(summary string, parameters ...openapi.Parameter) openapi.Operation {
+		operation := page(summary, parameters...)
+		operation.RequestBody = &openapi.RequestBody{Content: map[string]openapi.MediaType{"application/x-www-form-urlencoded": {}}}
+		return operation
+	}
+	userPage := func(operation openapi.Operation) openapi.Operation {
+		operation.Security = authenticated
+		return operation
+	}
+
+	add("get", "/login", page("Login page."))
+	add("post", "/login", form("Log in and set auth cookie, users with second factor get code page instead."))
+	add("post", "/login/2fa", form("Check second factor code and set auth cookie."))
+	add("get", "/login/oidc", page("Redirect to OpenID Connect provider to sign in, 404 when it is not configured."))
+	add("get", "/login/oidc/callback", page("Complete sign-in at OpenID Connect provider, set auth cookie and open items page, "+
+		"users with second factor get code page instead.",
+		openapi.Parameter{Name: "code", In: "query", Schema: &openapi.Schema{Type: "string"}},
+		openapi.Parameter{Name: "state", In: "query", Schema: &openapi.Schema{Type: "string"}}))
+	add("get", "/register", page("Registration page."))
+	add("post", "/register", form("Register user."))
+	add("get", "/forgot-password", page("Page to request password reset link by email."))
+	add("post", "/forgot-password", form("Mail password reset link if email is registered, too many requests by email or ip are throttled."))
+	token := openapi.Parameter{Name: "token", In: "query", Schema: &openapi.Schema{Type: "string"}}
+	add("get", "/reset-password", page("Page to set new password with token from reset link.", token))
+	add("post", "/reset-password", form("Set new password and revoke all sessions of user."))
+	add("get", "/verify-email", page("Verify email with token from verification link, without token shows form to request new link.", token))
+	add("post", "/verify-email", form("Mail new verification link if email is registered and not verified."))
+	add("post", "/logout", userPage(form("Revoke session and remove auth cookie.")))
+	add("post", "/logout-all", userPage(form("Revoke all sessions of user and remove auth cookie.")))
+	add("get", "/settings", userPage(page("Account settings page.")))
+	add("post", "/settings/password", userPage(form("Change password and log out other sessions.")))
+	add("post", "/settings/email", userPage(form("Change email and mail link to verify it.")))
+	add("post", "/settings/delete", userPage(form("Delete account with all items and log out.")))
+	add("post", "/settings/confirmation", userPage(form("Mail code which confirms account changes instead of password.")))
+	add("get", "/settings/2fa", userPage(page("Second factor status, QR code to enroll if it is not enabled.")))
+	add("post", "/settings/2fa/confirm", userPage(form("Enable second factor with code and show recovery codes.")))
+	add("post", "/settings/2fa/disable", userPage(form("Disable second factor with code or recovery code.")))
+	add("get", "/settings/tokens", userPage(page("Personal access tokens and form to create one.")))
+	add("post", "/settings/tokens", userPage(form("Create personal access token and show it once.")))
+	add("post", "/settings/tokens/{id}/revoke", userPage(form("Revoke personal access token.", pathID("id"))))
+	search := openapi.Parameter{Name: "q", In: "query", Schema: &openapi.Schema{Type: "string"}}
+	add("get", "/admin", userPage(page("Users whose email contains q with their item counts, only for admins.", search)))
+	add("post", "/admin/users/{id}/disable", userPage(form("Disable user and revoke all its sessions, only for admins.", pathID("id"))))
+	add("post", "/admin/users/{id}/enable", userPage(form("Enable disabled user, only for admins.", pathID("id"))))
+	add("post", "/admin/users/{id}/logout", userPage(form("Revoke all sessions of user, only for admins.", pathID("id"))))
+	add("get", "/{userId}/items", userPage(page("Items page, times are shown in timezone of browser.", pathID("userId"), sortParameter)))
+	add("get", "/{userId}/items/create", userPage(page("Item creation page.", pathID("userId"))))
+	add("post", "/{userId}/items/create", userPage(form("Create item.", pathID("userId"))))
+	add("get", "/{userId}/items/update/{id}", userPage(page("Item update page.", pathID("userId"), pathID("id"))))
+	add("post", "/{userId}/items/update/{id}", userPage(form("Update item.", pathID("userId"), pathID("id"))))
+	add("get", "/{userId}/items/update-status/{id}", userPage(page("Move item to the next status.", pathID("userId"), pathID("id"))))
+	add("post", "/{userId}/items/update-status/{id}", userPage(page("Move item to the next status.", pathID("userId"), pathID("id"))))
+	add("get", "/{userId}/items/delete/{id}", userPage(page("Delete item.", pathID("userId"), pathID("id"))))
+
+	return document
+}
+
+// serveOpenAPI is an endpoint which returns OpenAPI document.
+func (server *Server) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	api.ServeJSON(server.log, w, http.StatusOK, server.openAPI)
+}
@@ -0,0 +1,84 @@
+package console
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/pkg/openapi"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	config := Config{StaticDir: filepath.Join("..", "web")}
+	server, err := NewServer(config, listener, userauth.NewService(zap.NewNop(), nil, nil, nil, nil, nil, nil, auth.TokenSigner{}, nil, nil, userauth.Config{}), zap.NewNop(), items.New(nil), users.New(nil, nil))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Close()) }()
+
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var document openapi.Document
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&document))
+	assert.Equal(t, openapi.Version, document.OpenAPI)
+
+	// every registered route and method is described.
+	routes := map[string]bool{}
+	router := server.server.Handler.(*mux.Router)
+	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		methods, err := route.GetMethods()
+		require.NoError(t, err, path)
+
+		for _, method := range methods {
+			routes[method+" "+path] = true
+			_, ok := document.Operation(method, path)
+			assert.True(t, ok, "route %s %s is missing in openapi document", method, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// and nothing else.
+	for path, operations := range document.Paths {
+		for method := range operations {
+			route := strings.ToUpper(method) + " " + path
+			assert.True(t, routes[route], "openapi document describes unknown route %s", route)
+		}
+	}
+
+	// schema references are resolvable.
+	body, err := json.Marshal(document)
+	require.NoError(t, err)
+	for _, part := range strings.Split(string(body), `"$ref":"#/components/schemas/`)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		assert.Contains(t, document.Components.Schemas, name)
+	}
+
+	user := document.Components.Schemas["User"]
+	assert.NotContains(t, user.Properties, "password")
+	assert.Equal(t, "uuid", user.Properties["id"].Format)
+	assert.Equal(t, "date-time", user.Properties["createdAt"].Format)
+	assert.Len(t, document.Components.Schemas["Item"].Properties["status"].Enum, 3)
+}
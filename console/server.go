@@ -10,15 +10,20 @@ import (
 	"net"
 	"net/http"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
+	"todo/accesstokens"
+	"todo/console/api"
 	"todo/console/controllers"
 	"todo/items"
 	"todo/pkg/auth"
+	"todo/pkg/openapi"
 	"todo/users"
 	"todo/users/userauth"
 )
@@ -30,13 +35,16 @@ var (
 
 // Config contains configuration for admin web server.
 type Config struct {
-	Address   string `json:"address"`
-	StaticDir string `json:"staticDir"`
+	Address   string `json:"address" yaml:"address"`
+	StaticDir string `json:"staticDir" yaml:"staticDir"`
+
+	// ShutdownTimeout is how long in-flight requests are drained on shutdown before connections are closed.
+	ShutdownTimeout time.Duration `json:"shutdownTimeout" yaml:"shutdownTimeout"`
 
 	Auth struct {
-		CookieName string `json:"cookieName"`
-		Path       string `json:"path"`
-	} `json:"auth"`
+		CookieName string `json:"cookieName" yaml:"cookieName"`
+		Path       string `json:"path" yaml:"path"`
+	} `json:"auth" yaml:"auth"`
 }
 
 // AbsolutePath defines path to html templates.
@@ -46,31 +54,45 @@ const AbsolutePath = "/Users/macos/go/src/awesomeProject/todo/web"
 //
 // architecture: Endpoint
 type Server struct {
-	log *zap.Logger
+	log    *zap.Logger
+	config Config
 
 	listener net.Listener
 	server   http.Server
 
-	authService *userauth.Service
-	cookieAuth  *auth.CookieAuth
+	authService   *userauth.Service
+	cookieAuth    *auth.CookieAuth
+	authenticator auth.Authenticator
+	renewer       auth.Renewer
+
+	openAPI openapi.Document
 
 	templates struct {
 		items controllers.ItemsTemplates
 		auth  controllers.AuthTemplates
+		admin controllers.AdminTemplates
 	}
 }
 
 // NewServer is a constructor for admin web server.
-func NewServer(listener net.Listener, authService *userauth.Service, logger *zap.Logger, items *items.Service, users *users.Service) (*Server, error) {
+func NewServer(config Config, listener net.Listener, authService *userauth.Service, logger *zap.Logger, items *items.Service, usersService *users.Service) (*Server, error) {
 	server := &Server{
+		config: config,
 		cookieAuth: auth.NewCookieAuth(auth.CookieSettings{
-			Name: "todo",
-			Path: "/",
+			Name: config.Auth.CookieName,
+			Path: config.Auth.Path,
 		}),
 		authService: authService,
 		listener:    listener,
 		log:         logger,
+		openAPI:     openAPIDocument(config.Auth.CookieName),
+	}
+	server.authenticator = auth.Chain{
+		auth.CookieAuthenticator{Cookie: server.cookieAuth, Tokens: authService},
+		auth.BearerAuthenticator{Tokens: authService},
+		auth.APIKeyAuthenticator{Keys: authService, Prefix: accesstokens.Prefix},
 	}
+	server.renewer = auth.CookieRenewer{Cookie: server.cookieAuth, Tokens: authService}
 
 	err := server.initializeTemplates()
 	if err != nil {
@@ -79,19 +101,91 @@ func NewServer(listener net.Listener, authService *userauth.Service, logger *zap
 	}
 
 	router := mux.NewRouter()
-	authController := controllers.NewAuth(server.log, server.authService, server.cookieAuth, server.templates.auth, users)
+	authController := controllers.NewAuth(server.log, server.authService, server.cookieAuth, server.templates.auth, usersService)
 	router.HandleFunc("/login", authController.Login).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/login/2fa", authController.LoginSecondFactor).Methods(http.MethodPost)
+	router.HandleFunc("/login/oidc", authController.LoginOIDC).Methods(http.MethodGet)
+	router.HandleFunc(userauth.OIDCCallbackPath, authController.LoginOIDCCallback).Methods(http.MethodGet)
 	router.HandleFunc("/register", authController.Register).Methods(http.MethodGet, http.MethodPost)
-	router.HandleFunc("/logout", authController.Logout).Methods(http.MethodGet)
+	router.HandleFunc("/forgot-password", authController.ForgotPassword).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/reset-password", authController.ResetPassword).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/verify-email", authController.VerifyEmail).Methods(http.MethodGet, http.MethodPost)
+
+	sessionRouter := router.NewRoute().Subrouter()
+	sessionRouter.Use(server.withAuth, server.withSession)
+	sessionRouter.HandleFunc("/logout", authController.Logout).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/logout-all", authController.LogoutEverywhere).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings", authController.Settings).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/settings/password", authController.ChangePassword).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/email", authController.ChangeEmail).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/delete", authController.DeleteAccount).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/confirmation", authController.SendConfirmationCode).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/2fa", authController.TwoFactor).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/settings/2fa/confirm", authController.ConfirmTwoFactor).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/2fa/disable", authController.DisableTwoFactor).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/tokens", authController.AccessTokens).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/settings/tokens", authController.CreateAccessToken).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/settings/tokens/{id}/revoke", authController.RevokeAccessToken).Methods(http.MethodPost)
+
+	apiAuthController := api.NewAuth(server.log, server.authService, usersService)
+	router.HandleFunc("/.well-known/jwks.json", apiAuthController.JWKS).Methods(http.MethodGet)
+
+	apiRouter := router.PathPrefix("/api/v1").Subrouter()
+	apiRouter.HandleFunc("/openapi.json", server.serveOpenAPI).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/auth/token", apiAuthController.Token).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auth/register", apiAuthController.Register).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auth/refresh", apiAuthController.Refresh).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auth/verify", apiAuthController.Verify).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auth/verify/resend", apiAuthController.ResendVerification).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auth/2fa/verify", apiAuthController.SecondFactor).Methods(http.MethodPost)
+
+	apiAuthRouter := apiRouter.NewRoute().Subrouter()
+	apiAuthRouter.Use(server.withAuth)
+	apiUsersController := api.NewUsers(server.log, usersService, server.authService)
+	apiAuthRouter.HandleFunc("/users/me", apiUsersController.Me).Methods(http.MethodGet)
+
+	apiSessionRouter := apiAuthRouter.NewRoute().Subrouter()
+	apiSessionRouter.Use(server.withSession)
+	apiSessionRouter.HandleFunc("/auth/logout", apiAuthController.Logout).Methods(http.MethodPost)
+	apiSessionRouter.HandleFunc("/auth/logout-all", apiAuthController.LogoutEverywhere).Methods(http.MethodPost)
+	apiSessionRouter.HandleFunc("/auth/sessions", apiAuthController.Sessions).Methods(http.MethodGet)
+	apiSessionRouter.HandleFunc("/auth/2fa/enroll", apiAuthController.BeginEnrollment).Methods(http.MethodPost)
+	apiSessionRouter.HandleFunc("/auth/2fa/confirm", apiAuthController.ConfirmEnrollment).Methods(http.MethodPost)
+	apiSessionRouter.HandleFunc("/auth/2fa/disable", apiAuthController.DisableTwoFactor).Methods(http.MethodPost)
+	apiSessionRouter.HandleFunc("/auth/tokens", apiAuthController.AccessTokens).Methods(http.MethodGet)
+	apiSessionRouter.HandleFunc("/auth/tokens", apiAuthController.CreateAccessToken).Methods(http.MethodPost)
+	apiSessionRouter.HandleFunc("/auth/tokens/{id}", apiAuthController.RevokeAccessToken).Methods(http.MethodDelete)
+	apiSessionRouter.HandleFunc("/users/me", apiUsersController.Delete).Methods(http.MethodDelete)
+	apiSessionRouter.HandleFunc("/users/me/password", apiUsersController.ChangePassword).Methods(http.MethodPut)
+	apiSessionRouter.HandleFunc("/users/me/email", apiUsersController.ChangeEmail).Methods(http.MethodPut)
+	apiSessionRouter.HandleFunc("/users/me/confirmation", apiUsersController.SendConfirmationCode).Methods(http.MethodPost)
+
+	apiItemsRouter := apiAuthRouter.NewRoute().Subrouter()
+	apiItemsRouter.Use(server.withItemsScope, server.withTwoFactor)
+	apiItemsController := api.NewItems(server.log, items)
+	apiItemsRouter.HandleFunc("/items", apiItemsController.List).Methods(http.MethodGet)
+	apiItemsRouter.Handle("/items", server.withVerifiedEmail(apiItemsController.Create)).Methods(http.MethodPost)
+	apiItemsRouter.HandleFunc("/items/{id}", apiItemsController.Get).Methods(http.MethodGet)
+	apiItemsRouter.Handle("/items/{id}", server.withVerifiedEmail(apiItemsController.Update)).Methods(http.MethodPut)
+	apiItemsRouter.Handle("/items/{id}", server.withVerifiedEmail(apiItemsController.Delete)).Methods(http.MethodDelete)
+	apiItemsRouter.Handle("/items/{id}/status", server.withVerifiedEmail(apiItemsController.UpdateStatus)).Methods(http.MethodPost)
 
 	itemsRouter := router.PathPrefix("/{userId}/items").Subrouter()
-	itemsRouter.Use(server.withAuth)
+	itemsRouter.Use(server.withAuth, server.withSession, server.withUserAccess, server.withTwoFactor)
 	itemsController := controllers.NewItems(server.log, items, server.templates.items)
 	itemsRouter.HandleFunc("", itemsController.List).Methods(http.MethodGet)
-	itemsRouter.HandleFunc("/create", itemsController.Create).Methods(http.MethodGet, http.MethodPost)
-	itemsRouter.HandleFunc("/update/{id}", itemsController.Update).Methods(http.MethodGet, http.MethodPost)
-	itemsRouter.HandleFunc("/update-status/{id}", itemsController.UpdateStatus).Methods(http.MethodGet, http.MethodPost)
-	itemsRouter.HandleFunc("/delete/{id}", itemsController.Delete).Methods(http.MethodGet)
+	itemsRouter.Handle("/create", server.withVerifiedEmail(itemsController.Create)).Methods(http.MethodGet, http.MethodPost)
+	itemsRouter.Handle("/update/{id}", server.withVerifiedEmail(itemsController.Update)).Methods(http.MethodGet, http.MethodPost)
+	itemsRouter.Handle("/update-status/{id}", server.withVerifiedEmail(itemsController.UpdateStatus)).Methods(http.MethodGet, http.MethodPost)
+	itemsRouter.Handle("/delete/{id}", server.withVerifiedEmail(itemsController.Delete)).Methods(http.MethodGet)
+
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(server.withAuth, server.withSession, server.withPermission(users.PermissionManageUsers))
+	adminController := controllers.NewAdmin(server.log, usersService, items, server.authService, server.templates.admin)
+	adminRouter.HandleFunc("", adminController.Users).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/users/{id}/disable", adminController.Disable).Methods(http.MethodPost)
+	adminRouter.HandleFunc("/users/{id}/enable", adminController.Enable).Methods(http.MethodPost)
+	adminRouter.HandleFunc("/users/{id}/logout", adminController.Logout).Methods(http.MethodPost)
 
 	server.server = http.Server{
 		Handler: router,
@@ -106,7 +200,7 @@ func (server *Server) Run(ctx context.Context) (err error) {
 	var group errgroup.Group
 	group.Go(func() error {
 		<-ctx.Done()
-		return server.server.Shutdown(context.Background())
+		return server.shutdown()
 	})
 	group.Go(func() error {
 		defer cancel()
@@ -121,56 +215,225 @@ func (server *Server) Run(ctx context.Context) (err error) {
 	return group.Wait()
 }
 
+// shutdown stops accepting new connections and waits for in-flight requests
+// until shutdown timeout expires, then remaining connections are closed.
+func (server *Server) shutdown() error {
+	ctx := context.Background()
+	if server.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, server.config.ShutdownTimeout)
+		defer cancel()
+	}
+
+	err := server.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		server.log.Warn("shutdown timeout expired, closing remaining connections")
+		return Error.Wrap(server.server.Close())
+	}
+
+	return Error.Wrap(err)
+}
+
 // Close closes server and underlying listener.
 func (server *Server) Close() error {
-	return server.server.Close()
+	err := server.server.Close()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+
+	return Error.Wrap(err)
 }
 
-// withAuth performs initial authorization before every request.
+// withAuth authenticates every request, anonymous requests never reach handler.
+// Expired access token cookie is transparently renewed with refresh token cookie.
 func (server *Server) withAuth(handler http.Handler) http.Handler {
+	return auth.Middleware(server.authenticator, server.renewer, server.unauthenticated)(handler)
+}
+
+// withUserAccess lets users reach only their own pages, it must run after withAuth.
+func (server *Server) withUserAccess(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var ctx context.Context
+		claims, err := auth.GetClaims(r.Context())
+		if err != nil || mux.Vars(r)["userId"] != claims.UserID.String() {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
 
-		ctxWithAuth := func(ctx context.Context) context.Context {
-			token, err := server.cookieAuth.GetToken(r)
-			if err != nil {
-				controllers.Redirect(w, r, "/login", http.MethodGet)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// withSession lets only session tokens through, personal access tokens can not reach console pages,
+// manage sessions, second factor and other tokens. It must run after withAuth.
+func (server *Server) withSession(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.GetClaims(r.Context())
+		if err != nil || !claims.Session() {
+			err = userauth.ErrInsufficientScope.New("personal access tokens can not be used here")
+			if isAPIRequest(r) {
+				api.ServeError(server.log, w, err)
+				return
 			}
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 
-			claims, err := server.authService.Authorize(ctx, token)
-			if err != nil {
-				controllers.Redirect(w, r, "/login", http.MethodGet)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// withPermission lets only users whose role is granted permission through, it must run after withAuth.
+func (server *Server) withPermission(permission users.Permission) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := auth.GetClaims(r.Context())
+			if err != nil || !users.Role(claims.Role).Can(permission) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
 			}
 
-			return auth.SetClaims(ctx, claims)
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withItemsScope lets personal access tokens read items with items:read scope
+// and change them with items:write scope, it must run after withAuth.
+func (server *Server) withItemsScope(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := accesstokens.ScopeItemsWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			scope = accesstokens.ScopeItemsRead
 		}
 
-		ctx = ctxWithAuth(r.Context())
+		claims, err := auth.GetClaims(r.Context())
+		if err != nil || !claims.HasScope(string(scope)) {
+			api.ServeError(server.log, w, userauth.ErrInsufficientScope.New("token has no %s scope", scope))
+			return
+		}
 
-		handler.ServeHTTP(w, r.Clone(ctx))
+		handler.ServeHTTP(w, r)
 	})
 }
 
+// withVerifiedEmail lets only users with verified email change items, it must run after withAuth.
+// Users with unverified email reach it only when auth.verification.unverified is "limited".
+func (server *Server) withVerifiedEmail(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.GetClaims(r.Context())
+		if err != nil || !claims.EmailVerified {
+			err = userauth.ErrUnverified.New("verify your email to change items")
+			if isAPIRequest(r) {
+				api.ServeError(server.log, w, err)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// withTwoFactor sends users who have to enroll second factor to enrollment, it must run after withAuth.
+func (server *Server) withTwoFactor(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.GetClaims(r.Context())
+		if err != nil || claims.TwoFactorRequired {
+			if isAPIRequest(r) {
+				api.ServeError(server.log, w, userauth.ErrTwoFactorRequired.New("enroll second factor at /api/v1/auth/2fa/enroll"))
+				return
+			}
+			controllers.Redirect(w, r, "/settings/2fa", http.MethodGet)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// unauthenticated redirects browsers to login page and responds with 401 to api clients.
+func (server *Server) unauthenticated(w http.ResponseWriter, r *http.Request, err error) {
+	server.log.Debug("request is not authenticated", zap.String("path", r.URL.Path), zap.Error(err))
+
+	if !isAPIRequest(r) {
+		controllers.Redirect(w, r, "/login", http.MethodGet)
+		return
+	}
+
+	api.ServeError(server.log, w, err)
+}
+
+// isAPIRequest reports whether request is made by api client rather than browser.
+func isAPIRequest(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+	if r.Header.Get("Authorization") != "" || r.Header.Get(auth.APIKeyHeader) != "" {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
 // initializeTemplates initializes and caches templates for managers controller.
 func (server *Server) initializeTemplates() (err error) {
-	server.templates.auth.Login, err = template.ParseFiles(filepath.Join("web", "auth", "login.html"))
+	server.templates.auth.Login, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "login.html"))
 	if err != nil {
 		return err
 	}
-	server.templates.auth.Register, err = template.ParseFiles(filepath.Join("web", "auth", "register.html"))
+	server.templates.auth.Register, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "register.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.ForgotPassword, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "forgot-password.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.ResetPassword, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "reset-password.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.VerifyEmail, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "verify-email.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.LoginSecondFactor, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "login-2fa.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.TwoFactor, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "two-factor.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.LoginComplete, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "login-complete.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.AccessTokens, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "access-tokens.html"))
+	if err != nil {
+		return err
+	}
+	server.templates.auth.Settings, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "auth", "settings.html"))
+	if err != nil {
+		return err
+	}
+
+	server.templates.admin.Users, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "admin", "users.html"))
 	if err != nil {
 		return err
 	}
 
-	server.templates.items.List, err = template.ParseFiles(filepath.Join("web", "items", "list.html"))
+	server.templates.items.List, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "items", "list.html"))
 	if err != nil {
 		return err
 	}
-	server.templates.items.Create, err = template.ParseFiles(filepath.Join("web", "items", "create.html"))
+	server.templates.items.Create, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "items", "create.html"))
 	if err != nil {
 		return err
 	}
-	server.templates.items.Update, err = template.ParseFiles(filepath.Join("web", "items", "update.html"))
+	server.templates.items.Update, err = template.ParseFiles(filepath.Join(server.config.StaticDir, "items", "update.html"))
 	if err != nil {
 		return err
 	}
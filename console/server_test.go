@@ -0,0 +1,133 @@
+package console
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestServerGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &Server{
+		log:      zap.NewNop(),
+		config:   Config{ShutdownTimeout: 5 * time.Second},
+		listener: listener,
+		server: http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				_, _ = w.Write([]byte("done"))
+			}),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- server.Run(ctx) }()
+
+	type response struct {
+		status int
+		body   string
+		err    error
+	}
+	inFlight := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			inFlight <- response{err: err}
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		inFlight <- response{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	// new connections are refused as soon as listener is closed.
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return true
+		}
+		_ = conn.Close()
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-runErr:
+		t.Fatalf("server stopped before in-flight request finished: %v", err)
+	default:
+	}
+
+	close(release)
+
+	resp := <-inFlight
+	require.NoError(t, resp.err)
+	assert.Equal(t, http.StatusOK, resp.status)
+	assert.Equal(t, "done", resp.body)
+
+	require.NoError(t, <-runErr)
+	require.NoError(t, server.Close())
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &Server{
+		log:      zap.NewNop(),
+		config:   Config{ShutdownTimeout: 100 * time.Millisecond},
+		listener: listener,
+		server: http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+			}),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- server.Run(ctx) }()
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-runErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after shutdown timeout")
+	}
+
+	// stuck request is cut off when timeout expires.
+	require.Error(t, <-requestErr)
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/zeebo/errs"
+
+	"todo/accesstokens"
+)
+
+// ErrAccessTokens indicates that there was an error in access tokens repository.
+var ErrAccessTokens = errs.Class("access token repository error")
+
+type accessTokensDB struct {
+	conn *sql.DB
+}
+
+// Create creates token in the database.
+func (accessTokensDB *accessTokensDB) Create(ctx context.Context, token accesstokens.Token) error {
+	query := `INSERT INTO access_tokens(id, user_id, name, hash, scopes, created_at, expires_at, last_used_at)
+	          VALUES($1,$2,$3,$4,$5,$6,$7,$8)`
+
+	_, err := accessTokensDB.conn.ExecContext(ctx, query, token.ID, token.UserID, token.Name, token.Hash,
+		scopesArray(token.Scopes), token.CreatedAt, token.ExpiresAt, token.LastUsedAt)
+
+	return ErrAccessTokens.Wrap(err)
+}
+
+// GetByHash returns token with hash.
+func (accessTokensDB *accessTokensDB) GetByHash(ctx context.Context, hash []byte) (accesstokens.Token, error) {
+	query := `SELECT id, user_id, name, hash, scopes, created_at, expires_at, last_used_at
+	          FROM access_tokens
+	          WHERE hash = $1`
+
+	token, err := scanAccessToken(accessTokensDB.conn.QueryRowContext(ctx, query, hash))
+	if errs.Is(err, sql.ErrNoRows) {
+		return token, accesstokens.ErrNoToken.Wrap(err)
+	}
+
+	return token, ErrAccessTokens.Wrap(err)
+}
+
+// List returns tokens of user, the most recent first.
+func (accessTokensDB *accessTokensDB) List(ctx context.Context, userID uuid.UUID) (_ []accesstokens.Token, err error) {
+	query := `SELECT id, user_id, name, hash, scopes, created_at, expires_at, last_used_at
+	          FROM access_tokens
+	          WHERE user_id = $1
+	          ORDER BY created_at DESC, id`
+
+	rows, err := accessTokensDB.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, ErrAccessTokens.Wrap(err)
+	}
+
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
+
+	var list []accesstokens.Token
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, ErrAccessTokens.Wrap(err)
+		}
+
+		list = append(list, token)
+	}
+
+	return list, ErrAccessTokens.Wrap(rows.Err())
+}
+
+// UpdateLastUsed sets time token was last used at.
+func (accessTokensDB *accessTokensDB) UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error {
+	query := `UPDATE access_tokens
+	          SET last_used_at = $1
+	          WHERE id = $2`
+
+	_, err := accessTokensDB.conn.ExecContext(ctx, query, lastUsedAt, id)
+
+	return ErrAccessTokens.Wrap(err)
+}
+
+// Delete deletes token of user.
+func (accessTokensDB *accessTokensDB) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	query := `DELETE FROM access_tokens
+	          WHERE id = $1 AND user_id = $2`
+
+	res, err := accessTokensDB.conn.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return ErrAccessTokens.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return accesstokens.ErrNoToken.New("")
+	}
+
+	return ErrAccessTokens.Wrap(err)
+}
+
+// rowScanner is a single row of query result.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAccessToken scans token from row.
+func scanAccessToken(row rowScanner) (accesstokens.Token, error) {
+	var token accesstokens.Token
+	var scopes pq.StringArray
+
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Hash, &scopes,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt)
+	for _, scope := range scopes {
+		token.Scopes = append(token.Scopes, accesstokens.Scope(scope))
+	}
+
+	return token, err
+}
+
+// scopesArray converts scopes to postgres array.
+func scopesArray(scopes []accesstokens.Scope) pq.StringArray {
+	array := make(pq.StringArray, len(scopes))
+	for i, scope := range scopes {
+		array[i] = string(scope)
+	}
+	return array
+}
@@ -1,10 +1,14 @@
 package database
 
 import (
-	"context"
 	"database/sql"
 	"todo"
+	"todo/accesstokens"
+	"todo/identities"
 	"todo/items"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
 	"todo/users"
 
 	_ "github.com/lib/pq" // using postgres driver.
@@ -35,31 +39,6 @@ func New(databaseURL string) (todo.DB, error) {
 	return &database{conn: conn}, nil
 }
 
-// CreateSchema create schema for all tables and databases.
-func (db *database) CreateSchema(ctx context.Context) (err error) {
-	createTableQuery :=
-		`CREATE TABLE IF NOT EXISTS users (
-            id            BYTEA     PRIMARY KEY    NOT NULL,
-            email         VARCHAR                  NOT NULL,
-            password_hash BYTEA                    NOT NULL,
-            created_at    TIMESTAMP WITH TIME ZONE NOT NULL
-        );
-        CREATE TABLE IF NOT EXISTS items (
-            id          BYTEA   PRIMARY KEY                            NOT NULL,
-            user_id     BYTEA   REFERENCES users(id) ON DELETE CASCADE NOT NULL,
-            name        VARCHAR                                        NOT NULL,
-            description VARCHAR                                        NOT NULL,
-            status      VARCHAR                                        NOT NULL
-        );`
-
-	_, err = db.conn.ExecContext(ctx, createTableQuery)
-	if err != nil {
-		return Error.Wrap(err)
-	}
-
-	return nil
-}
-
 // Close closes underlying db connection.
 func (db *database) Close() error {
 	return Error.Wrap(db.conn.Close())
@@ -73,4 +52,29 @@ func (db *database) Users() users.DB {
 // Items provides access to accounts db.
 func (db *database) Items() items.DB {
 	return &itemsDB{conn: db.conn}
-}
\ No newline at end of file
+}
+
+// Sessions provides access to sessions db.
+func (db *database) Sessions() sessions.DB {
+	return &sessionsDB{conn: db.conn}
+}
+
+// Tokens provides access to single-use tokens db.
+func (db *database) Tokens() tokens.DB {
+	return &tokensDB{conn: db.conn}
+}
+
+// TwoFactor provides access to second factors db.
+func (db *database) TwoFactor() twofactor.DB {
+	return &twoFactorDB{conn: db.conn}
+}
+
+// Identities provides access to external identities db.
+func (db *database) Identities() identities.DB {
+	return &identitiesDB{conn: db.conn}
+}
+
+// AccessTokens provides access to personal access tokens db.
+func (db *database) AccessTokens() accesstokens.DB {
+	return &accessTokensDB{conn: db.conn}
+}
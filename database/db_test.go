@@ -0,0 +1,11 @@
+package database_test
+
+import (
+	"testing"
+
+	"todo/database/dbtest"
+)
+
+func TestPostgres(t *testing.T) {
+	dbtest.Run(t, dbtest.Postgres(t))
+}
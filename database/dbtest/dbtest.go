@@ -0,0 +1,1139 @@
+// Package dbtest contains conformance tests which every todo.DB backend must pass
+// and helpers creating isolated databases for them.
+package dbtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo"
+	"todo/accesstokens"
+	"todo/identities"
+	"todo/items"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
+	"todo/users"
+)
+
+// NewDB creates empty migrated database, it must be released with t.Cleanup.
+type NewDB func(t *testing.T) todo.DB
+
+// Run runs conformance tests, each test gets a fresh database from newDB.
+func Run(t *testing.T, newDB NewDB) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, db todo.DB)
+	}{
+		{"users", testUsers},
+		{"users not found", testUsersNotFound},
+		{"users duplicate id", testUsersDuplicateID},
+		{"users update password", testUsersUpdatePassword},
+		{"users duplicate email", testUsersDuplicateEmail},
+		{"users verify", testUsersVerify},
+		{"users update email", testUsersUpdateEmail},
+		{"users list", testUsersList},
+		{"users role and disabled", testUsersRoleAndDisabled},
+		{"items", testItems},
+		{"items not found", testItemsNotFound},
+		{"items duplicate id", testItemsDuplicateID},
+		{"items require user", testItemsRequireUser},
+		{"items scoped by owner", testItemsScopedByOwner},
+		{"items list order", testItemsListOrder},
+		{"items cascade delete", testItemsCascadeDelete},
+		{"items count by user", testItemsCountByUser},
+		{"items concurrent updates", testItemsConcurrentUpdates},
+		{"items status conflict", testItemsStatusConflict},
+		{"sessions", testSessions},
+		{"sessions not found", testSessionsNotFound},
+		{"sessions rotate", testSessionsRotate},
+		{"sessions require user", testSessionsRequireUser},
+		{"sessions revoke all", testSessionsRevokeAll},
+		{"sessions cascade delete", testSessionsCascadeDelete},
+		{"tokens", testTokens},
+		{"tokens require user", testTokensRequireUser},
+		{"tokens cascade delete", testTokensCascadeDelete},
+		{"two factor", testTwoFactor},
+		{"two factor single use", testTwoFactorSingleUse},
+		{"two factor require user", testTwoFactorRequireUser},
+		{"two factor cascade delete", testTwoFactorCascadeDelete},
+		{"identities", testIdentities},
+		{"identities require user", testIdentitiesRequireUser},
+		{"identities cascade delete", testIdentitiesCascadeDelete},
+		{"access tokens", testAccessTokens},
+		{"access tokens scoped by owner", testAccessTokensScopedByOwner},
+		{"access tokens require user", testAccessTokensRequireUser},
+		{"access tokens cascade delete", testAccessTokensCascadeDelete},
+		{"context cancellation", testContextCancellation},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newDB(t))
+		})
+	}
+}
+
+// NewUser returns user with unique id and email.
+func NewUser() users.User {
+	return users.User{
+		ID:        uuid.New(),
+		Email:     uuid.NewString() + "@gmail.com",
+		Password:  []byte("password"),
+		CreatedAt: time.Now().UTC(),
+		Role:      users.RoleUser,
+	}
+}
+
+// NewItem returns item of user with unique id.
+func NewItem(userID uuid.UUID, name string) items.Item {
+	now := time.Now().UTC()
+	return items.Item{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Name:        name,
+		Description: "test description",
+		Status:      items.StatusTODO,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// NewSession returns session of user with unique id.
+func NewSession(userID uuid.UUID) sessions.Session {
+	now := time.Now().UTC()
+	return sessions.Session{
+		ID:          uuid.New(),
+		UserID:      userID,
+		UserAgent:   "test agent",
+		IP:          "127.0.0.1",
+		RefreshHash: []byte(uuid.NewString()),
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+}
+
+// CreateUser stores new user in db.
+func CreateUser(ctx context.Context, t *testing.T, db todo.DB) users.User {
+	user := NewUser()
+	require.NoError(t, db.Users().Create(ctx, user))
+	return user
+}
+
+func testUsers(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := NewUser()
+
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	stored, err := db.Users().GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	stored, err = db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	_, err = db.Users().GetByEmail(ctx, user.Email)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+
+	_, err = db.Users().GetByID(ctx, user.ID)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testUsersNotFound(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	_, err := db.Users().GetByEmail(ctx, "nobody@gmail.com")
+	assert.True(t, users.ErrNoUser.Has(err), err)
+
+	_, err = db.Users().GetByID(ctx, uuid.New())
+	assert.True(t, users.ErrNoUser.Has(err), err)
+
+	err = db.Users().Delete(ctx, uuid.New())
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testUsersDuplicateID(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	duplicate := NewUser()
+	duplicate.ID = user.ID
+	assert.Error(t, db.Users().Create(ctx, duplicate))
+
+	stored, err := db.Users().GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+}
+
+func testUsersUpdatePassword(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	user.Password = []byte("$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$a2V5")
+	require.NoError(t, db.Users().UpdatePassword(ctx, user.ID, user.Password))
+
+	stored, err := db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	err = db.Users().UpdatePassword(ctx, uuid.New(), user.Password)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testUsersDuplicateEmail(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	duplicate := NewUser()
+	duplicate.Email = user.Email
+	err := db.Users().Create(ctx, duplicate)
+	assert.True(t, users.ErrEmailTaken.Has(err), err)
+
+	_, err = db.Users().GetByID(ctx, duplicate.ID)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testUsersVerify(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	stored, err := db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Verified())
+
+	verifiedAt := time.Now().UTC()
+	user.VerifiedAt = &verifiedAt
+	require.NoError(t, db.Users().Verify(ctx, user.ID, verifiedAt))
+
+	stored, err = db.Users().GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	err = db.Users().Verify(ctx, uuid.New(), verifiedAt)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testUsersUpdateEmail(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+
+	verifiedAt := time.Now().UTC()
+	require.NoError(t, db.Users().Verify(ctx, user.ID, verifiedAt))
+
+	// password hash is left as it is.
+	user.Email = "updated-" + user.Email
+	user.VerifiedAt = nil
+	require.NoError(t, db.Users().UpdateEmail(ctx, user.ID, user.Email))
+
+	stored, err := db.Users().GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	err = db.Users().UpdateEmail(ctx, user.ID, other.Email)
+	assert.True(t, users.ErrEmailTaken.Has(err), err)
+
+	stored, err = db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	err = db.Users().UpdateEmail(ctx, uuid.New(), "missing@gmail.com")
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testUsersList(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	list, err := db.Users().List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	second := NewUser()
+	second.Email = "b_second." + second.Email
+	first := NewUser()
+	first.Email = "a%first." + first.Email
+	for _, user := range []users.User{second, first} {
+		require.NoError(t, db.Users().Create(ctx, user))
+	}
+
+	list, err = db.Users().List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	CompareUsers(t, first, list[0])
+	CompareUsers(t, second, list[1])
+
+	// search is not a pattern.
+	list, err = db.Users().List(ctx, "%first.")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	CompareUsers(t, first, list[0])
+
+	list, err = db.Users().List(ctx, "b.second")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testUsersRoleAndDisabled(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	user := NewUser()
+	user.Role = ""
+	require.NoError(t, db.Users().Create(ctx, user))
+	user.Role = users.RoleUser
+
+	stored, err := db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	user.Role = users.RoleAdmin
+	require.NoError(t, db.Users().UpdateRole(ctx, user.ID, user.Role))
+	disabledAt := time.Now().UTC()
+	user.DisabledAt = &disabledAt
+	require.NoError(t, db.Users().UpdateDisabled(ctx, user.ID, user.DisabledAt))
+
+	stored, err = db.Users().GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	user.DisabledAt = nil
+	require.NoError(t, db.Users().UpdateDisabled(ctx, user.ID, nil))
+	stored, err = db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	CompareUsers(t, user, stored)
+
+	err = db.Users().UpdateRole(ctx, uuid.New(), users.RoleAdmin)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+	err = db.Users().UpdateDisabled(ctx, uuid.New(), &disabledAt)
+	assert.True(t, users.ErrNoUser.Has(err), err)
+}
+
+func testItems(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	item := NewItem(user.ID, "task")
+
+	require.NoError(t, db.Items().Create(ctx, item))
+
+	stored, err := db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+
+	item.Name, item.Description, item.UpdatedAt = "updated name", "updated description", item.UpdatedAt.Add(time.Hour)
+	require.NoError(t, db.Items().Update(ctx, item))
+
+	item.Status, item.UpdatedAt = items.StatusInProgress, item.UpdatedAt.Add(time.Hour)
+	require.NoError(t, db.Items().UpdateStatus(ctx, item, items.StatusTODO))
+
+	stored, err = db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+
+	completedAt := item.UpdatedAt.Add(time.Hour)
+	item.Status, item.UpdatedAt, item.CompletedAt = items.StatusCompleted, completedAt, &completedAt
+	require.NoError(t, db.Items().UpdateStatus(ctx, item, items.StatusInProgress))
+
+	stored, err = db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+
+	list, err := db.Items().List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	CompareItems(t, item, list[0])
+
+	require.NoError(t, db.Items().Delete(ctx, user.ID, item.ID))
+
+	_, err = db.Items().Get(ctx, item.UserID, item.ID)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+}
+
+func testItemsNotFound(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	missing := NewItem(user.ID, "missing")
+
+	_, err := db.Items().Get(ctx, missing.UserID, missing.ID)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	err = db.Items().Update(ctx, missing)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	err = db.Items().UpdateStatus(ctx, missing, items.StatusTODO)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	err = db.Items().Delete(ctx, user.ID, missing.ID)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	list, err := db.Items().List(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testItemsDuplicateID(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	item := NewItem(user.ID, "task")
+	require.NoError(t, db.Items().Create(ctx, item))
+
+	duplicate := NewItem(user.ID, "duplicate")
+	duplicate.ID = item.ID
+	assert.Error(t, db.Items().Create(ctx, duplicate))
+
+	stored, err := db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+}
+
+func testItemsCountByUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	owner := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+	CreateUser(ctx, t, db)
+
+	counts, err := db.Items().CountByUser(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	for _, item := range []items.Item{NewItem(owner.ID, "first"), NewItem(owner.ID, "second"), NewItem(other.ID, "other")} {
+		require.NoError(t, db.Items().Create(ctx, item))
+	}
+
+	counts, err = db.Items().CountByUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]int{owner.ID: 2, other.ID: 1}, counts)
+}
+
+func testItemsRequireUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	assert.Error(t, db.Items().Create(ctx, NewItem(uuid.New(), "orphan")))
+}
+
+func testItemsScopedByOwner(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	owner := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+	item := NewItem(owner.ID, "task")
+	require.NoError(t, db.Items().Create(ctx, item))
+
+	update := item
+	update.UserID, update.Name = other.ID, "stolen"
+	err := db.Items().Update(ctx, update)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	update.Status = items.StatusCompleted
+	err = db.Items().UpdateStatus(ctx, update, items.StatusTODO)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	err = db.Items().Delete(ctx, other.ID, item.ID)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	list, err := db.Items().List(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = db.Items().Get(ctx, other.ID, item.ID)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	stored, err := db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+}
+
+func testItemsListOrder(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+
+	// items are listed by name and then by id, regardless of creation order.
+	created := []items.Item{
+		NewItem(user.ID, "b"),
+		NewItem(user.ID, "a"),
+		NewItem(user.ID, "c"),
+		NewItem(user.ID, "a"),
+		NewItem(user.ID, "B"),
+	}
+	for _, item := range created {
+		require.NoError(t, db.Items().Create(ctx, item))
+	}
+	require.NoError(t, db.Items().Create(ctx, NewItem(other.ID, "a")))
+
+	list, err := db.Items().List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, len(created))
+
+	var names []string
+	for i, item := range list {
+		names = append(names, item.Name)
+		assert.Equal(t, user.ID, item.UserID)
+		if i > 0 && list[i-1].Name == item.Name {
+			assert.Negative(t, bytes.Compare(list[i-1].ID[:], item.ID[:]))
+		}
+	}
+	assert.Equal(t, []string{"B", "a", "a", "b", "c"}, names)
+}
+
+func testItemsCascadeDelete(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+
+	item := NewItem(user.ID, "task")
+	require.NoError(t, db.Items().Create(ctx, item))
+	require.NoError(t, db.Items().Create(ctx, NewItem(user.ID, "task2")))
+	otherItem := NewItem(other.ID, "other")
+	require.NoError(t, db.Items().Create(ctx, otherItem))
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	list, err := db.Items().List(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = db.Items().Get(ctx, item.UserID, item.ID)
+	assert.True(t, items.ErrNoItem.Has(err), err)
+
+	stored, err := db.Items().Get(ctx, otherItem.UserID, otherItem.ID)
+	require.NoError(t, err)
+	CompareItems(t, otherItem, stored)
+}
+
+func testItemsConcurrentUpdates(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	item := NewItem(user.ID, "task")
+	require.NoError(t, db.Items().Create(ctx, item))
+
+	const workers = 20
+	names := make(map[string]bool)
+	var moved int64
+	var group sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		update := item
+		update.Name, update.Status = fmt.Sprintf("name %d", i), items.StatusInProgress
+		names[update.Name] = true
+
+		group.Add(1)
+		go func(i int) {
+			defer group.Done()
+
+			assert.NoError(t, db.Items().Update(ctx, update))
+			// only the first status update moves item from TODO, the rest see it moved.
+			switch err := db.Items().UpdateStatus(ctx, update, items.StatusTODO); {
+			case err == nil:
+				atomic.AddInt64(&moved, 1)
+			case !items.ErrConflict.Has(err):
+				t.Errorf("unexpected error %v", err)
+			}
+			_, err := db.Items().Get(ctx, item.UserID, item.ID)
+			assert.NoError(t, err)
+		}(i)
+	}
+	group.Wait()
+
+	assert.EqualValues(t, 1, moved)
+	stored, err := db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	assert.True(t, names[stored.Name], stored.Name)
+	assert.Equal(t, items.StatusInProgress, stored.Status)
+}
+
+func testItemsStatusConflict(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	item := NewItem(user.ID, "task")
+	require.NoError(t, db.Items().Create(ctx, item))
+
+	completedAt := item.UpdatedAt.Add(time.Hour)
+	stale := item
+	stale.Status, stale.UpdatedAt, stale.CompletedAt = items.StatusCompleted, completedAt, &completedAt
+	err := db.Items().UpdateStatus(ctx, stale, items.StatusInProgress)
+	assert.True(t, items.ErrConflict.Has(err), err)
+
+	stored, err := db.Items().Get(ctx, item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+}
+
+func testSessions(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	session := NewSession(user.ID)
+
+	require.NoError(t, db.Sessions().Create(ctx, session))
+	assert.Error(t, db.Sessions().Create(ctx, session))
+
+	stored, err := db.Sessions().Get(ctx, session.ID)
+	require.NoError(t, err)
+	CompareSessions(t, session, stored)
+
+	session.LastSeenAt = session.LastSeenAt.Add(time.Hour)
+	require.NoError(t, db.Sessions().UpdateLastSeen(ctx, session.ID, session.LastSeenAt))
+
+	session.Revoked = true
+	require.NoError(t, db.Sessions().Revoke(ctx, session.ID))
+
+	stored, err = db.Sessions().Get(ctx, session.ID)
+	require.NoError(t, err)
+	CompareSessions(t, session, stored)
+
+	// sessions are listed from the most recent.
+	recent := NewSession(user.ID)
+	recent.CreatedAt = session.CreatedAt.Add(time.Minute)
+	require.NoError(t, db.Sessions().Create(ctx, recent))
+
+	list, err := db.Sessions().List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	CompareSessions(t, recent, list[0])
+	CompareSessions(t, session, list[1])
+}
+
+func testSessionsNotFound(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	_, err := db.Sessions().Get(ctx, uuid.New())
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+
+	err = db.Sessions().UpdateLastSeen(ctx, uuid.New(), time.Now())
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+
+	err = db.Sessions().Revoke(ctx, uuid.New())
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+
+	list, err := db.Sessions().List(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testSessionsRotate(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	session := NewSession(user.ID)
+	require.NoError(t, db.Sessions().Create(ctx, session))
+
+	oldHash := session.RefreshHash
+	session.RefreshHash, session.ExpiresAt = []byte("rotated"), session.ExpiresAt.Add(time.Hour)
+	require.NoError(t, db.Sessions().Rotate(ctx, session.ID, oldHash, session.RefreshHash, session.ExpiresAt))
+
+	// replaced hash can not be rotated again.
+	err := db.Sessions().Rotate(ctx, session.ID, oldHash, []byte("reused"), session.ExpiresAt.Add(time.Hour))
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+
+	stored, err := db.Sessions().Get(ctx, session.ID)
+	require.NoError(t, err)
+	CompareSessions(t, session, stored)
+
+	// revoked session can not be rotated.
+	require.NoError(t, db.Sessions().Revoke(ctx, session.ID))
+	err = db.Sessions().Rotate(ctx, session.ID, session.RefreshHash, []byte("revoked"), session.ExpiresAt)
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+
+	err = db.Sessions().Rotate(ctx, uuid.New(), oldHash, []byte("missing"), session.ExpiresAt)
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+}
+
+func testSessionsRequireUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	assert.Error(t, db.Sessions().Create(ctx, NewSession(uuid.New())))
+}
+
+func testSessionsRevokeAll(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Sessions().Create(ctx, NewSession(user.ID)))
+	}
+	otherSession := NewSession(other.ID)
+	require.NoError(t, db.Sessions().Create(ctx, otherSession))
+
+	require.NoError(t, db.Sessions().RevokeAll(ctx, user.ID))
+
+	list, err := db.Sessions().List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	for _, session := range list {
+		assert.True(t, session.Revoked)
+	}
+
+	stored, err := db.Sessions().Get(ctx, otherSession.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Revoked)
+}
+
+func testSessionsCascadeDelete(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	session := NewSession(user.ID)
+	require.NoError(t, db.Sessions().Create(ctx, session))
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	_, err := db.Sessions().Get(ctx, session.ID)
+	assert.True(t, sessions.ErrNoSession.Has(err), err)
+}
+
+// NewToken returns token of user with unique hash.
+func NewToken(userID uuid.UUID, purpose tokens.Purpose) tokens.Token {
+	now := time.Now().UTC()
+	return tokens.Token{
+		Hash:      tokens.Hash(uuid.NewString()),
+		UserID:    userID,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+}
+
+func testTokens(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	now := time.Now().UTC()
+
+	token := NewToken(user.ID, tokens.PurposePasswordReset)
+	require.NoError(t, db.Tokens().Create(ctx, token))
+	assert.Error(t, db.Tokens().Create(ctx, token), "hash must be unique")
+
+	_, err := db.Tokens().Consume(ctx, "other", token.Hash, now)
+	assert.True(t, tokens.ErrNoToken.Has(err), err)
+
+	stored, err := db.Tokens().Consume(ctx, tokens.PurposePasswordReset, token.Hash, now)
+	require.NoError(t, err)
+	CompareTokens(t, token, stored)
+
+	_, err = db.Tokens().Consume(ctx, tokens.PurposePasswordReset, token.Hash, now)
+	assert.True(t, tokens.ErrNoToken.Has(err), "token must be consumed once")
+
+	t.Run("expired", func(t *testing.T) {
+		expired := NewToken(user.ID, tokens.PurposePasswordReset)
+		require.NoError(t, db.Tokens().Create(ctx, expired))
+
+		_, err := db.Tokens().Consume(ctx, tokens.PurposePasswordReset, expired.Hash, expired.ExpiresAt.Add(time.Second))
+		assert.True(t, tokens.ErrNoToken.Has(err), err)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		token := NewToken(user.ID, tokens.PurposeSecondFactor)
+		require.NoError(t, db.Tokens().Create(ctx, token))
+
+		_, err := db.Tokens().Get(ctx, tokens.PurposePasswordReset, token.Hash, now)
+		assert.True(t, tokens.ErrNoToken.Has(err), err)
+		_, err = db.Tokens().Get(ctx, token.Purpose, token.Hash, token.ExpiresAt.Add(time.Second))
+		assert.True(t, tokens.ErrNoToken.Has(err), err)
+
+		for i := 0; i < 2; i++ {
+			stored, err := db.Tokens().Get(ctx, token.Purpose, token.Hash, now)
+			require.NoError(t, err)
+			CompareTokens(t, token, stored)
+		}
+
+		_, err = db.Tokens().Consume(ctx, token.Purpose, token.Hash, now)
+		assert.NoError(t, err, "get must not use token up")
+	})
+
+	t.Run("delete by user", func(t *testing.T) {
+		other := CreateUser(ctx, t, db)
+		first, second := NewToken(user.ID, tokens.PurposePasswordReset), NewToken(user.ID, tokens.PurposePasswordReset)
+		otherPurpose, otherUser := NewToken(user.ID, "other"), NewToken(other.ID, tokens.PurposePasswordReset)
+		for _, token := range []tokens.Token{first, second, otherPurpose, otherUser} {
+			require.NoError(t, db.Tokens().Create(ctx, token))
+		}
+
+		require.NoError(t, db.Tokens().DeleteByUser(ctx, user.ID, tokens.PurposePasswordReset))
+
+		for _, token := range []tokens.Token{first, second} {
+			_, err := db.Tokens().Consume(ctx, token.Purpose, token.Hash, now)
+			assert.True(t, tokens.ErrNoToken.Has(err), err)
+		}
+		for _, token := range []tokens.Token{otherPurpose, otherUser} {
+			_, err := db.Tokens().Consume(ctx, token.Purpose, token.Hash, now)
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func testTokensRequireUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	assert.Error(t, db.Tokens().Create(ctx, NewToken(uuid.New(), tokens.PurposePasswordReset)))
+}
+
+func testTokensCascadeDelete(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	token := NewToken(user.ID, tokens.PurposePasswordReset)
+	require.NoError(t, db.Tokens().Create(ctx, token))
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	_, err := db.Tokens().Consume(ctx, token.Purpose, token.Hash, time.Now())
+	assert.True(t, tokens.ErrNoToken.Has(err), err)
+}
+
+// NewFactor returns unconfirmed second factor of user with two recovery codes.
+func NewFactor(userID uuid.UUID) twofactor.Factor {
+	return twofactor.Factor{
+		UserID:        userID,
+		Secret:        uuid.NewString(),
+		RecoveryCodes: [][]byte{twofactor.HashRecoveryCode("first"), twofactor.HashRecoveryCode("second")},
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+func testTwoFactor(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	_, err := db.TwoFactor().Get(ctx, user.ID)
+	assert.True(t, twofactor.ErrNoFactor.Has(err), err)
+
+	factor := NewFactor(user.ID)
+	require.NoError(t, db.TwoFactor().Save(ctx, factor))
+
+	stored, err := db.TwoFactor().Get(ctx, user.ID)
+	require.NoError(t, err)
+	CompareFactors(t, factor, stored)
+
+	confirmedAt := time.Now().UTC()
+	factor.ConfirmedAt = &confirmedAt
+	factor.RecoveryCodes = nil
+	require.NoError(t, db.TwoFactor().Save(ctx, factor), "save must replace factor")
+
+	stored, err = db.TwoFactor().Get(ctx, user.ID)
+	require.NoError(t, err)
+	CompareFactors(t, factor, stored)
+
+	require.NoError(t, db.TwoFactor().Delete(ctx, user.ID))
+	_, err = db.TwoFactor().Get(ctx, user.ID)
+	assert.True(t, twofactor.ErrNoFactor.Has(err), err)
+	err = db.TwoFactor().Delete(ctx, user.ID)
+	assert.True(t, twofactor.ErrNoFactor.Has(err), err)
+}
+
+func testTwoFactorSingleUse(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	factor := NewFactor(user.ID)
+	require.NoError(t, db.TwoFactor().Save(ctx, factor))
+
+	require.NoError(t, db.TwoFactor().UseCounter(ctx, user.ID, 10))
+	for _, counter := range []int64{10, 9} {
+		err := db.TwoFactor().UseCounter(ctx, user.ID, counter)
+		assert.True(t, twofactor.ErrNoFactor.Has(err), "counter %d must be rejected: %v", counter, err)
+	}
+	require.NoError(t, db.TwoFactor().UseCounter(ctx, user.ID, 11))
+
+	require.NoError(t, db.TwoFactor().UseRecoveryCode(ctx, user.ID, factor.RecoveryCodes[0]))
+	err := db.TwoFactor().UseRecoveryCode(ctx, user.ID, factor.RecoveryCodes[0])
+	assert.True(t, twofactor.ErrNoFactor.Has(err), "recovery code must be used once: %v", err)
+
+	stored, err := db.TwoFactor().Get(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), stored.LastCounter)
+	assert.Equal(t, factor.RecoveryCodes[1:], stored.RecoveryCodes)
+
+	err = db.TwoFactor().UseCounter(ctx, uuid.New(), 1)
+	assert.True(t, twofactor.ErrNoFactor.Has(err), err)
+}
+
+func testTwoFactorRequireUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	assert.Error(t, db.TwoFactor().Save(ctx, NewFactor(uuid.New())))
+}
+
+func testTwoFactorCascadeDelete(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	require.NoError(t, db.TwoFactor().Save(ctx, NewFactor(user.ID)))
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	_, err := db.TwoFactor().Get(ctx, user.ID)
+	assert.True(t, twofactor.ErrNoFactor.Has(err), err)
+}
+
+// NewIdentity returns identity of user with unique subject.
+func NewIdentity(userID uuid.UUID) identities.Identity {
+	return identities.Identity{
+		Provider:  "https://accounts.example.com",
+		Subject:   uuid.NewString(),
+		UserID:    userID,
+		Email:     uuid.NewString() + "@example.com",
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func testIdentities(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	identity := NewIdentity(user.ID)
+	require.NoError(t, db.Identities().Create(ctx, identity))
+
+	stored, err := db.Identities().Get(ctx, identity.Provider, identity.Subject)
+	require.NoError(t, err)
+	CompareIdentities(t, identity, stored)
+
+	// subjects are unique only within provider.
+	_, err = db.Identities().Get(ctx, "https://other.example.com", identity.Subject)
+	assert.True(t, identities.ErrNoIdentity.Has(err), err)
+
+	other := identity
+	other.Provider = "https://other.example.com"
+	require.NoError(t, db.Identities().Create(ctx, other))
+
+	duplicate := NewIdentity(CreateUser(ctx, t, db).ID)
+	duplicate.Subject = identity.Subject
+	err = db.Identities().Create(ctx, duplicate)
+	assert.True(t, identities.ErrIdentityTaken.Has(err), err)
+
+	stored, err = db.Identities().Get(ctx, identity.Provider, identity.Subject)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, stored.UserID)
+}
+
+func testIdentitiesRequireUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	assert.Error(t, db.Identities().Create(ctx, NewIdentity(uuid.New())))
+}
+
+func testIdentitiesCascadeDelete(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	identity := NewIdentity(user.ID)
+	require.NoError(t, db.Identities().Create(ctx, identity))
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	_, err := db.Identities().Get(ctx, identity.Provider, identity.Subject)
+	assert.True(t, identities.ErrNoIdentity.Has(err), err)
+}
+
+// NewAccessToken returns access token of user with unique hash.
+func NewAccessToken(userID uuid.UUID) accesstokens.Token {
+	now := time.Now().UTC()
+	return accesstokens.Token{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      "ci",
+		Hash:      tokens.Hash(uuid.NewString()),
+		Scopes:    []accesstokens.Scope{accesstokens.ScopeItemsRead, accesstokens.ScopeItemsWrite},
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+}
+
+func testAccessTokens(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+
+	older := NewAccessToken(user.ID)
+	older.CreatedAt = older.CreatedAt.Add(-time.Minute)
+	older.Scopes = []accesstokens.Scope{accesstokens.ScopeItemsRead}
+	require.NoError(t, db.AccessTokens().Create(ctx, older))
+	token := NewAccessToken(user.ID)
+	require.NoError(t, db.AccessTokens().Create(ctx, token))
+
+	stored, err := db.AccessTokens().GetByHash(ctx, token.Hash)
+	require.NoError(t, err)
+	CompareAccessTokens(t, token, stored)
+
+	_, err = db.AccessTokens().GetByHash(ctx, tokens.Hash("unknown"))
+	assert.True(t, accesstokens.ErrNoToken.Has(err), err)
+
+	list, err := db.AccessTokens().List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	CompareAccessTokens(t, token, list[0])
+	CompareAccessTokens(t, older, list[1])
+
+	lastUsedAt := time.Now().UTC()
+	require.NoError(t, db.AccessTokens().UpdateLastUsed(ctx, token.ID, lastUsedAt))
+	stored, err = db.AccessTokens().GetByHash(ctx, token.Hash)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt)
+	assert.WithinDuration(t, lastUsedAt, *stored.LastUsedAt, time.Second)
+
+	require.NoError(t, db.AccessTokens().Delete(ctx, user.ID, token.ID))
+	_, err = db.AccessTokens().GetByHash(ctx, token.Hash)
+	assert.True(t, accesstokens.ErrNoToken.Has(err), err)
+	err = db.AccessTokens().Delete(ctx, user.ID, token.ID)
+	assert.True(t, accesstokens.ErrNoToken.Has(err), err)
+}
+
+func testAccessTokensScopedByOwner(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	owner := CreateUser(ctx, t, db)
+	other := CreateUser(ctx, t, db)
+	token := NewAccessToken(owner.ID)
+	require.NoError(t, db.AccessTokens().Create(ctx, token))
+
+	list, err := db.AccessTokens().List(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	err = db.AccessTokens().Delete(ctx, other.ID, token.ID)
+	assert.True(t, accesstokens.ErrNoToken.Has(err), err)
+	_, err = db.AccessTokens().GetByHash(ctx, token.Hash)
+	assert.NoError(t, err)
+}
+
+func testAccessTokensRequireUser(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+
+	assert.Error(t, db.AccessTokens().Create(ctx, NewAccessToken(uuid.New())))
+}
+
+func testAccessTokensCascadeDelete(t *testing.T, db todo.DB) {
+	ctx := context.Background()
+	user := CreateUser(ctx, t, db)
+	token := NewAccessToken(user.ID)
+	require.NoError(t, db.AccessTokens().Create(ctx, token))
+
+	require.NoError(t, db.Users().Delete(ctx, user.ID))
+
+	_, err := db.AccessTokens().GetByHash(ctx, token.Hash)
+	assert.True(t, accesstokens.ErrNoToken.Has(err), err)
+}
+
+func testContextCancellation(t *testing.T, db todo.DB) {
+	user := CreateUser(context.Background(), t, db)
+	item := NewItem(user.ID, "task")
+	require.NoError(t, db.Items().Create(context.Background(), item))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Error(t, db.Users().Create(ctx, NewUser()))
+	_, err := db.Users().GetByEmail(ctx, user.Email)
+	assert.Error(t, err)
+	assert.Error(t, db.Items().Create(ctx, NewItem(user.ID, "canceled")))
+	_, err = db.Items().List(ctx, user.ID)
+	assert.Error(t, err)
+	_, err = db.Items().Get(ctx, item.UserID, item.ID)
+	assert.Error(t, err)
+	assert.Error(t, db.Items().UpdateStatus(ctx, item, items.StatusTODO))
+	assert.Error(t, db.Items().Delete(ctx, user.ID, item.ID))
+	assert.Error(t, db.Sessions().Create(ctx, NewSession(user.ID)))
+	_, err = db.Sessions().List(ctx, user.ID)
+	assert.Error(t, err)
+	assert.Error(t, db.Sessions().RevokeAll(ctx, user.ID))
+	assert.Error(t, db.Users().Delete(ctx, user.ID))
+
+	// nothing was changed by canceled calls.
+	stored, err := db.Items().Get(context.Background(), item.UserID, item.ID)
+	require.NoError(t, err)
+	CompareItems(t, item, stored)
+}
+
+// CompareUsers asserts that users are equal, time is compared with storage precision.
+func CompareUsers(t *testing.T, expected, actual users.User) {
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.Email, actual.Email)
+	assert.Equal(t, expected.Password, actual.Password)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+	if assert.Equal(t, expected.Verified(), actual.Verified()) && expected.Verified() {
+		assert.WithinDuration(t, *expected.VerifiedAt, *actual.VerifiedAt, time.Second)
+	}
+	assert.Equal(t, expected.Role, actual.Role)
+	if assert.Equal(t, expected.Disabled(), actual.Disabled()) && expected.Disabled() {
+		assert.WithinDuration(t, *expected.DisabledAt, *actual.DisabledAt, time.Second)
+	}
+}
+
+// CompareSessions asserts that sessions are equal, time is compared with storage precision.
+func CompareSessions(t *testing.T, expected, actual sessions.Session) {
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.UserAgent, actual.UserAgent)
+	assert.Equal(t, expected.IP, actual.IP)
+	assert.Equal(t, expected.RefreshHash, actual.RefreshHash)
+	assert.Equal(t, expected.RememberMe, actual.RememberMe)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+	assert.WithinDuration(t, expected.LastSeenAt, actual.LastSeenAt, time.Second)
+	assert.WithinDuration(t, expected.ExpiresAt, actual.ExpiresAt, time.Second)
+	assert.Equal(t, expected.Revoked, actual.Revoked)
+}
+
+// CompareTokens asserts that tokens are equal.
+func CompareTokens(t *testing.T, expected, actual tokens.Token) {
+	assert.Equal(t, expected.Hash, actual.Hash)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.Purpose, actual.Purpose)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+	assert.WithinDuration(t, expected.ExpiresAt, actual.ExpiresAt, time.Second)
+}
+
+// CompareFactors asserts that second factors are equal, time is compared with storage precision.
+func CompareFactors(t *testing.T, expected, actual twofactor.Factor) {
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.Secret, actual.Secret)
+	assert.Equal(t, len(expected.RecoveryCodes), len(actual.RecoveryCodes))
+	for i := range expected.RecoveryCodes {
+		if i < len(actual.RecoveryCodes) {
+			assert.Equal(t, expected.RecoveryCodes[i], actual.RecoveryCodes[i])
+		}
+	}
+	assert.Equal(t, expected.LastCounter, actual.LastCounter)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+	if assert.Equal(t, expected.Confirmed(), actual.Confirmed()) && expected.Confirmed() {
+		assert.WithinDuration(t, *expected.ConfirmedAt, *actual.ConfirmedAt, time.Second)
+	}
+}
+
+// CompareItems asserts that items are equal, time is compared with storage precision.
+func CompareItems(t *testing.T, expected, actual items.Item) {
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, expected.Description, actual.Description)
+	assert.Equal(t, expected.Status, actual.Status)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+	assert.WithinDuration(t, expected.UpdatedAt, actual.UpdatedAt, time.Second)
+	if assert.Equal(t, expected.CompletedAt == nil, actual.CompletedAt == nil) && expected.CompletedAt != nil {
+		assert.WithinDuration(t, *expected.CompletedAt, *actual.CompletedAt, time.Second)
+	}
+}
+
+// CompareIdentities asserts that identities are equal, time is compared with storage precision.
+func CompareIdentities(t *testing.T, expected, actual identities.Identity) {
+	assert.Equal(t, expected.Provider, actual.Provider)
+	assert.Equal(t, expected.Subject, actual.Subject)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.Email, actual.Email)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+}
+
+// CompareAccessTokens asserts that access tokens are equal, time is compared with storage precision.
+func CompareAccessTokens(t *testing.T, expected, actual accesstokens.Token) {
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, expected.Hash, actual.Hash)
+	assert.Equal(t, expected.Scopes, actual.Scopes)
+	assert.WithinDuration(t, expected.CreatedAt, actual.CreatedAt, time.Second)
+	assert.WithinDuration(t, expected.ExpiresAt, actual.ExpiresAt, time.Second)
+	if assert.Equal(t, expected.LastUsedAt == nil, actual.LastUsedAt == nil) && expected.LastUsedAt != nil {
+		assert.WithinDuration(t, *expected.LastUsedAt, *actual.LastUsedAt, time.Second)
+	}
+}
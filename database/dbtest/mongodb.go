@@ -0,0 +1,55 @@
+package dbtest
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo"
+	"todo/database/mongodb"
+)
+
+// MongoDBEnv is an environment variable with url of mongod used in tests, e.g. mongodb://localhost:27017.
+const MongoDBEnv = "TODO_TEST_MONGODB"
+
+// MongoDB returns NewDB which creates each database with unique name in mongod from MongoDBEnv,
+// the database is dropped when test finishes. Test is skipped if MongoDBEnv is not set.
+func MongoDB(t *testing.T) NewDB {
+	databaseURL := os.Getenv(MongoDBEnv)
+	if databaseURL == "" {
+		t.Skip(MongoDBEnv + " is not set")
+	}
+
+	return func(t *testing.T) todo.DB {
+		ctx := context.Background()
+
+		parsed, err := url.Parse(databaseURL)
+		require.NoError(t, err)
+		name := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		parsed.Path = "/" + name
+
+		db, err := mongodb.New(ctx, parsed.String())
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+
+			client, err := mongo.Connect(ctx, options.Client().ApplyURI(databaseURL))
+			require.NoError(t, err)
+			require.NoError(t, client.Database(name).Drop(ctx))
+			require.NoError(t, client.Disconnect(ctx))
+		})
+
+		require.NoError(t, db.MigrateToLatest(ctx))
+		require.NoError(t, db.CheckVersion(ctx))
+
+		return db
+	}
+}
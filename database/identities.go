@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/zeebo/errs"
+
+	"todo/identities"
+)
+
+// ErrIdentities indicates that there was an error in identities repository.
+var ErrIdentities = errs.Class("identity repository error")
+
+type identitiesDB struct {
+	conn *sql.DB
+}
+
+// Create links identity to user.
+func (identitiesDB *identitiesDB) Create(ctx context.Context, identity identities.Identity) error {
+	query := `INSERT INTO identities(provider, subject, user_id, email, created_at)
+	          VALUES($1,$2,$3,$4,$5)`
+
+	_, err := identitiesDB.conn.ExecContext(ctx, query, identity.Provider, identity.Subject,
+		identity.UserID, identity.Email, identity.CreatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "identities_pkey" {
+		return identities.ErrIdentityTaken.New("%s %s", identity.Provider, identity.Subject)
+	}
+
+	return ErrIdentities.Wrap(err)
+}
+
+// Get returns identity of subject at provider.
+func (identitiesDB *identitiesDB) Get(ctx context.Context, provider, subject string) (identities.Identity, error) {
+	var identity identities.Identity
+	query := `SELECT provider, subject, user_id, email, created_at
+	          FROM identities
+	          WHERE provider = $1 AND subject = $2`
+
+	err := identitiesDB.conn.QueryRowContext(ctx, query, provider, subject).Scan(&identity.Provider,
+		&identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt)
+	if errs.Is(err, sql.ErrNoRows) {
+		return identity, identities.ErrNoIdentity.Wrap(err)
+	}
+
+	return identity, ErrIdentities.Wrap(err)
+}
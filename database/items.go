@@ -18,19 +18,22 @@ type itemsDB struct {
 
 // Create creates item in the database.
 func (itemsDB *itemsDB) Create(ctx context.Context, item items.Item) error {
-	query := `INSERT INTO items(id, user_id, name, description, status)
-	          VALUES($1,$2,$3,$4,$5)`
+	query := `INSERT INTO items(` + itemColumns + `)
+	          VALUES($1,$2,$3,$4,$5,$6,$7,$8)`
 
-	_, err := itemsDB.conn.ExecContext(ctx, query, item.ID, item.UserID, item.Name, item.Description, item.Status)
+	_, err := itemsDB.conn.ExecContext(ctx, query, item.ID, item.UserID, item.Name, item.Description, item.Status,
+		item.CreatedAt, item.UpdatedAt, item.CompletedAt)
 
 	return ErrItems.Wrap(err)
 }
 
-// List returns all items from the database.
+// List returns all user items from the database ordered by name and id.
 func (itemsDB *itemsDB) List(ctx context.Context, userID uuid.UUID) (_ []items.Item, err error) {
-	query := `SELECT id, user_id, name, description, status
+	// names are compared byte by byte regardless of database collation, like in other backends.
+	query := `SELECT ` + itemColumns + `
 	          FROM items
-	          WHERE user_id = $1`
+	          WHERE user_id = $1
+	          ORDER BY name COLLATE "C", id`
 
 	rows, err := itemsDB.conn.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -43,8 +46,7 @@ func (itemsDB *itemsDB) List(ctx context.Context, userID uuid.UUID) (_ []items.I
 
 	var userItems []items.Item
 	for rows.Next() {
-		var item items.Item
-		err = rows.Scan(&item.ID, &item.UserID, &item.Name, &item.Description, &item.Status)
+		item, err := scanItem(rows)
 		if err != nil {
 			return nil, ErrItems.Wrap(err)
 		}
@@ -55,15 +57,13 @@ func (itemsDB *itemsDB) List(ctx context.Context, userID uuid.UUID) (_ []items.I
 	return userItems, ErrItems.Wrap(rows.Err())
 }
 
-// Get returns item by id from the database.
-func (itemsDB *itemsDB) Get(ctx context.Context, id uuid.UUID) (items.Item, error) {
-	var item items.Item
-	query := `SELECT id, user_id, name, description, status
+// Get returns item by id owned by user from the database.
+func (itemsDB *itemsDB) Get(ctx context.Context, userID, id uuid.UUID) (items.Item, error) {
+	query := `SELECT ` + itemColumns + `
 	          FROM items
-	          WHERE id = $1`
+	          WHERE id = $1 AND user_id = $2`
 
-	err := itemsDB.conn.QueryRowContext(ctx, query, id).Scan(&item.ID,
-		&item.UserID, &item.Name, &item.Description, &item.Status)
+	item, err := scanItem(itemsDB.conn.QueryRowContext(ctx, query, id, userID))
 	if errs.Is(err, sql.ErrNoRows) {
 		return item, items.ErrNoItem.Wrap(err)
 	}
@@ -71,13 +71,13 @@ func (itemsDB *itemsDB) Get(ctx context.Context, id uuid.UUID) (items.Item, erro
 	return item, ErrItems.Wrap(err)
 }
 
-// Update updates name and description of item in the database.
+// Update updates name, description and update time of item owned by item.UserID in the database.
 func (itemsDB *itemsDB) Update(ctx context.Context, item items.Item) error {
 	query := `UPDATE items
-	          SET name = $1, description = $2
-	          WHERE id = $3`
+	          SET name = $1, description = $2, updated_at = $3
+	          WHERE id = $4 AND user_id = $5`
 
-	res, err := itemsDB.conn.ExecContext(ctx, query, item.Name, item.Description, item.ID)
+	res, err := itemsDB.conn.ExecContext(ctx, query, item.Name, item.Description, item.UpdatedAt, item.ID, item.UserID)
 	if err != nil {
 		return ErrItems.Wrap(err)
 	}
@@ -90,31 +90,42 @@ func (itemsDB *itemsDB) Update(ctx context.Context, item items.Item) error {
 	return ErrItems.Wrap(err)
 }
 
-// UpdateStatus updates status of item in the database.
-func (itemsDB *itemsDB) UpdateStatus(ctx context.Context, id uuid.UUID, newStatus items.Status) error {
+// UpdateStatus updates status, update and completion times of item owned by item.UserID in the database
+// if its stored status is still from, otherwise it is ErrConflict.
+func (itemsDB *itemsDB) UpdateStatus(ctx context.Context, item items.Item, from items.Status) error {
 	query := `UPDATE items
-	          SET status = $1
-	          WHERE id = $2`
+	          SET status = $1, updated_at = $2, completed_at = $3
+	          WHERE id = $4 AND user_id = $5 AND status = $6`
 
-	res, err := itemsDB.conn.ExecContext(ctx, query, newStatus, id)
+	res, err := itemsDB.conn.ExecContext(ctx, query, item.Status, item.UpdatedAt, item.CompletedAt, item.ID, item.UserID, from)
 	if err != nil {
 		return ErrItems.Wrap(err)
 	}
 
 	rowsCount, err := res.RowsAffected()
-	if err == nil && rowsCount == 0 {
-		return items.ErrNoItem.New("")
+	if err != nil || rowsCount > 0 {
+		return ErrItems.Wrap(err)
 	}
 
-	return ErrItems.Wrap(err)
+	// nothing is updated either because item is missing or because its status was changed meanwhile.
+	var exists bool
+	err = itemsDB.conn.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM items WHERE id = $1 AND user_id = $2)`, item.ID, item.UserID).Scan(&exists)
+	switch {
+	case err != nil:
+		return ErrItems.Wrap(err)
+	case !exists:
+		return items.ErrNoItem.New("")
+	default:
+		return items.ErrConflict.New("status is not %s", from)
+	}
 }
 
-// Delete deletes item from the database.
-func (itemsDB *itemsDB) Delete(ctx context.Context, id uuid.UUID) error {
+// Delete deletes item owned by user from the database.
+func (itemsDB *itemsDB) Delete(ctx context.Context, userID, id uuid.UUID) error {
 	query := `DELETE FROM items
-	          WHERE id = $1`
+	          WHERE id = $1 AND user_id = $2`
 
-	res, err := itemsDB.conn.ExecContext(ctx, query, id)
+	res, err := itemsDB.conn.ExecContext(ctx, query, id, userID)
 	if err != nil {
 		return ErrItems.Wrap(err)
 	}
@@ -126,3 +137,44 @@ func (itemsDB *itemsDB) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return ErrItems.Wrap(err)
 }
+
+// CountByUser returns number of items of every user who has any from the database.
+func (itemsDB *itemsDB) CountByUser(ctx context.Context) (_ map[uuid.UUID]int, err error) {
+	query := `SELECT user_id, count(*)
+	          FROM items
+	          GROUP BY user_id`
+
+	rows, err := itemsDB.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, ErrItems.Wrap(err)
+	}
+
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var userID uuid.UUID
+		var count int
+		if err = rows.Scan(&userID, &count); err != nil {
+			return nil, ErrItems.Wrap(err)
+		}
+
+		counts[userID] = count
+	}
+
+	return counts, ErrItems.Wrap(rows.Err())
+}
+
+// itemColumns are columns of items table in the order scanItem reads them.
+const itemColumns = `id, user_id, name, description, status, created_at, updated_at, completed_at`
+
+// scanItem scans item from row with itemColumns.
+func scanItem(row rowScanner) (items.Item, error) {
+	var item items.Item
+	err := row.Scan(&item.ID, &item.UserID, &item.Name, &item.Description, &item.Status,
+		&item.CreatedAt, &item.UpdatedAt, &item.CompletedAt)
+
+	return item, err
+}
@@ -0,0 +1,139 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/accesstokens"
+)
+
+// ErrAccessTokens indicates that there was an error in access tokens repository.
+var ErrAccessTokens = errs.Class("access token repository error")
+
+type accessTokensDB struct {
+	db *database
+}
+
+// Create creates token in the database.
+func (accessTokensDB *accessTokensDB) Create(ctx context.Context, token accesstokens.Token) error {
+	if err := ctx.Err(); err != nil {
+		return ErrAccessTokens.Wrap(err)
+	}
+
+	accessTokensDB.db.mu.Lock()
+	defer accessTokensDB.db.mu.Unlock()
+
+	if _, ok := accessTokensDB.db.accessTokens[token.ID]; ok {
+		return ErrAccessTokens.New("access token already exists")
+	}
+	for _, existing := range accessTokensDB.db.accessTokens {
+		if string(existing.Hash) == string(token.Hash) {
+			return ErrAccessTokens.New("access token hash already exists")
+		}
+	}
+	if _, ok := accessTokensDB.db.users[token.UserID]; !ok {
+		return ErrAccessTokens.New("user %s does not exist", token.UserID)
+	}
+
+	accessTokensDB.db.accessTokens[token.ID] = cloneAccessToken(token)
+
+	return nil
+}
+
+// GetByHash returns token with hash.
+func (accessTokensDB *accessTokensDB) GetByHash(ctx context.Context, hash []byte) (accesstokens.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return accesstokens.Token{}, ErrAccessTokens.Wrap(err)
+	}
+
+	accessTokensDB.db.mu.RLock()
+	defer accessTokensDB.db.mu.RUnlock()
+
+	for _, token := range accessTokensDB.db.accessTokens {
+		if string(token.Hash) == string(hash) {
+			return cloneAccessToken(token), nil
+		}
+	}
+
+	return accesstokens.Token{}, accesstokens.ErrNoToken.New("")
+}
+
+// List returns tokens of user, the most recent first.
+func (accessTokensDB *accessTokensDB) List(ctx context.Context, userID uuid.UUID) ([]accesstokens.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrAccessTokens.Wrap(err)
+	}
+
+	accessTokensDB.db.mu.RLock()
+	defer accessTokensDB.db.mu.RUnlock()
+
+	var list []accesstokens.Token
+	for _, token := range accessTokensDB.db.accessTokens {
+		if token.UserID == userID {
+			list = append(list, cloneAccessToken(token))
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.After(list[j].CreatedAt)
+		}
+		return list[i].ID.String() < list[j].ID.String()
+	})
+
+	return list, nil
+}
+
+// UpdateLastUsed sets time token was last used at.
+func (accessTokensDB *accessTokensDB) UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return ErrAccessTokens.Wrap(err)
+	}
+
+	accessTokensDB.db.mu.Lock()
+	defer accessTokensDB.db.mu.Unlock()
+
+	token, ok := accessTokensDB.db.accessTokens[id]
+	if !ok {
+		return nil
+	}
+
+	token.LastUsedAt = &lastUsedAt
+	accessTokensDB.db.accessTokens[id] = token
+
+	return nil
+}
+
+// Delete deletes token of user.
+func (accessTokensDB *accessTokensDB) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return ErrAccessTokens.Wrap(err)
+	}
+
+	accessTokensDB.db.mu.Lock()
+	defer accessTokensDB.db.mu.Unlock()
+
+	token, ok := accessTokensDB.db.accessTokens[id]
+	if !ok || token.UserID != userID {
+		return accesstokens.ErrNoToken.New("")
+	}
+
+	delete(accessTokensDB.db.accessTokens, id)
+
+	return nil
+}
+
+// cloneAccessToken returns copy of token which shares no memory with it.
+func cloneAccessToken(token accesstokens.Token) accesstokens.Token {
+	token.Hash = cloneBytes(token.Hash)
+	token.Scopes = append([]accesstokens.Scope(nil), token.Scopes...)
+	if token.LastUsedAt != nil {
+		lastUsedAt := *token.LastUsedAt
+		token.LastUsedAt = &lastUsedAt
+	}
+	return token
+}
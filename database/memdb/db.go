@@ -0,0 +1,117 @@
+// Package memdb provides in-memory implementation of todo.DB.
+// It keeps no data between restarts and is intended for development, demos and tests.
+package memdb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo"
+	"todo/accesstokens"
+	"todo/identities"
+	"todo/items"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
+	"todo/users"
+)
+
+// Error indicates that there was an error in in-memory database.
+var Error = errs.Class("memdb error")
+
+// ensures that database implements todo.DB.
+var _ todo.DB = (*database)(nil)
+
+// database keeps all tables in maps guarded by single lock.
+//
+// architecture: Master Database
+type database struct {
+	mu sync.RWMutex
+
+	users    map[uuid.UUID]users.User
+	items    map[uuid.UUID]items.Item
+	sessions map[uuid.UUID]sessions.Session
+	// tokens are keyed by hash.
+	tokens map[string]tokens.Token
+	// secondFactors are keyed by user id.
+	secondFactors map[uuid.UUID]twofactor.Factor
+	// identities are keyed by provider and subject.
+	identities map[identityKey]identities.Identity
+	// accessTokens are keyed by id.
+	accessTokens map[uuid.UUID]accesstokens.Token
+}
+
+// New returns todo.DB in-memory implementation.
+func New() todo.DB {
+	return &database{
+		users:    make(map[uuid.UUID]users.User),
+		items:    make(map[uuid.UUID]items.Item),
+		sessions: make(map[uuid.UUID]sessions.Session),
+		tokens:   make(map[string]tokens.Token),
+
+		secondFactors: make(map[uuid.UUID]twofactor.Factor),
+		identities:    make(map[identityKey]identities.Identity),
+		accessTokens:  make(map[uuid.UUID]accesstokens.Token),
+	}
+}
+
+// MigrateToLatest does nothing, in-memory database has no schema.
+func (db *database) MigrateToLatest(ctx context.Context) error {
+	return Error.Wrap(ctx.Err())
+}
+
+// CheckVersion does nothing, in-memory database is always up to date.
+func (db *database) CheckVersion(ctx context.Context) error {
+	return Error.Wrap(ctx.Err())
+}
+
+// Close does nothing, data is kept until database is garbage collected.
+func (db *database) Close() error {
+	return nil
+}
+
+// Users provides access to users db.
+func (db *database) Users() users.DB {
+	return &usersDB{db: db}
+}
+
+// Items provides access to items db.
+func (db *database) Items() items.DB {
+	return &itemsDB{db: db}
+}
+
+// Sessions provides access to sessions db.
+func (db *database) Sessions() sessions.DB {
+	return &sessionsDB{db: db}
+}
+
+// Tokens provides access to single-use tokens db.
+func (db *database) Tokens() tokens.DB {
+	return &tokensDB{db: db}
+}
+
+// TwoFactor provides access to second factors db.
+func (db *database) TwoFactor() twofactor.DB {
+	return &twoFactorDB{db: db}
+}
+
+// Identities provides access to external identities db.
+func (db *database) Identities() identities.DB {
+	return &identitiesDB{db: db}
+}
+
+// AccessTokens provides access to personal access tokens db.
+func (db *database) AccessTokens() accesstokens.DB {
+	return &accessTokensDB{db: db}
+}
+
+// cloneBytes returns copy of b, so stored values are not shared with callers.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,61 @@
+package memdb
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+
+	"todo/identities"
+)
+
+// ErrIdentities indicates that there was an error in identities repository.
+var ErrIdentities = errs.Class("identity repository error")
+
+// identityKey is a key of identity, subjects are unique only within provider.
+type identityKey struct {
+	provider string
+	subject  string
+}
+
+type identitiesDB struct {
+	db *database
+}
+
+// Create links identity to user.
+func (identitiesDB *identitiesDB) Create(ctx context.Context, identity identities.Identity) error {
+	if err := ctx.Err(); err != nil {
+		return ErrIdentities.Wrap(err)
+	}
+
+	identitiesDB.db.mu.Lock()
+	defer identitiesDB.db.mu.Unlock()
+
+	key := identityKey{provider: identity.Provider, subject: identity.Subject}
+	if _, ok := identitiesDB.db.identities[key]; ok {
+		return identities.ErrIdentityTaken.New("%s %s", identity.Provider, identity.Subject)
+	}
+	if _, ok := identitiesDB.db.users[identity.UserID]; !ok {
+		return ErrIdentities.New("user %s does not exist", identity.UserID)
+	}
+
+	identitiesDB.db.identities[key] = identity
+
+	return nil
+}
+
+// Get returns identity of subject at provider.
+func (identitiesDB *identitiesDB) Get(ctx context.Context, provider, subject string) (identities.Identity, error) {
+	if err := ctx.Err(); err != nil {
+		return identities.Identity{}, ErrIdentities.Wrap(err)
+	}
+
+	identitiesDB.db.mu.RLock()
+	defer identitiesDB.db.mu.RUnlock()
+
+	identity, ok := identitiesDB.db.identities[identityKey{provider: provider, subject: subject}]
+	if !ok {
+		return identities.Identity{}, identities.ErrNoIdentity.New("")
+	}
+
+	return identity, nil
+}
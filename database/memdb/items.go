@@ -0,0 +1,174 @@
+package memdb
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/items"
+)
+
+// ErrItems indicates that there was an error in items repository.
+var ErrItems = errs.Class("item repository error")
+
+type itemsDB struct {
+	db *database
+}
+
+// Create creates item in the database.
+func (itemsDB *itemsDB) Create(ctx context.Context, item items.Item) error {
+	if err := ctx.Err(); err != nil {
+		return ErrItems.Wrap(err)
+	}
+
+	itemsDB.db.mu.Lock()
+	defer itemsDB.db.mu.Unlock()
+
+	if _, ok := itemsDB.db.items[item.ID]; ok {
+		return ErrItems.New("item %s already exists", item.ID)
+	}
+	if _, ok := itemsDB.db.users[item.UserID]; !ok {
+		return ErrItems.New("user %s does not exist", item.UserID)
+	}
+
+	itemsDB.db.items[item.ID] = cloneItem(item)
+
+	return nil
+}
+
+// List returns all user items from the database ordered by name and id.
+func (itemsDB *itemsDB) List(ctx context.Context, userID uuid.UUID) ([]items.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrItems.Wrap(err)
+	}
+
+	itemsDB.db.mu.RLock()
+	defer itemsDB.db.mu.RUnlock()
+
+	var userItems []items.Item
+	for _, item := range itemsDB.db.items {
+		if item.UserID == userID {
+			userItems = append(userItems, cloneItem(item))
+		}
+	}
+
+	sort.Slice(userItems, func(i, j int) bool {
+		if userItems[i].Name != userItems[j].Name {
+			return userItems[i].Name < userItems[j].Name
+		}
+		return bytes.Compare(userItems[i].ID[:], userItems[j].ID[:]) < 0
+	})
+
+	return userItems, nil
+}
+
+// CountByUser returns number of items of every user who has any from the database.
+func (itemsDB *itemsDB) CountByUser(ctx context.Context) (map[uuid.UUID]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrItems.Wrap(err)
+	}
+
+	itemsDB.db.mu.RLock()
+	defer itemsDB.db.mu.RUnlock()
+
+	counts := make(map[uuid.UUID]int)
+	for _, item := range itemsDB.db.items {
+		counts[item.UserID]++
+	}
+
+	return counts, nil
+}
+
+// Get returns item by id owned by user from the database.
+func (itemsDB *itemsDB) Get(ctx context.Context, userID, id uuid.UUID) (items.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return items.Item{}, ErrItems.Wrap(err)
+	}
+
+	itemsDB.db.mu.RLock()
+	defer itemsDB.db.mu.RUnlock()
+
+	item, ok := itemsDB.db.items[id]
+	if !ok || item.UserID != userID {
+		return items.Item{}, items.ErrNoItem.New("")
+	}
+
+	return cloneItem(item), nil
+}
+
+// Update updates name, description and update time of item owned by item.UserID in the database.
+func (itemsDB *itemsDB) Update(ctx context.Context, item items.Item) error {
+	return itemsDB.update(ctx, item.UserID, item.ID, func(stored *items.Item) error {
+		stored.Name = item.Name
+		stored.Description = item.Description
+		stored.UpdatedAt = item.UpdatedAt
+		return nil
+	})
+}
+
+// UpdateStatus updates status, update and completion times of item owned by item.UserID in the database
+// if its stored status is still from, otherwise it is ErrConflict.
+func (itemsDB *itemsDB) UpdateStatus(ctx context.Context, item items.Item, from items.Status) error {
+	return itemsDB.update(ctx, item.UserID, item.ID, func(stored *items.Item) error {
+		if stored.Status != from {
+			return items.ErrConflict.New("status is not %s", from)
+		}
+
+		stored.Status = item.Status
+		stored.UpdatedAt = item.UpdatedAt
+		stored.CompletedAt = cloneItem(item).CompletedAt
+		return nil
+	})
+}
+
+// Delete deletes item owned by user from the database.
+func (itemsDB *itemsDB) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return ErrItems.Wrap(err)
+	}
+
+	itemsDB.db.mu.Lock()
+	defer itemsDB.db.mu.Unlock()
+
+	if item, ok := itemsDB.db.items[id]; !ok || item.UserID != userID {
+		return items.ErrNoItem.New("")
+	}
+	delete(itemsDB.db.items, id)
+
+	return nil
+}
+
+// update applies fn to stored item owned by user under write lock, item is kept as is if fn fails.
+func (itemsDB *itemsDB) update(ctx context.Context, userID, id uuid.UUID, fn func(stored *items.Item) error) error {
+	if err := ctx.Err(); err != nil {
+		return ErrItems.Wrap(err)
+	}
+
+	itemsDB.db.mu.Lock()
+	defer itemsDB.db.mu.Unlock()
+
+	stored, ok := itemsDB.db.items[id]
+	if !ok || stored.UserID != userID {
+		return items.ErrNoItem.New("")
+	}
+
+	if err := fn(&stored); err != nil {
+		return err
+	}
+	itemsDB.db.items[id] = stored
+
+	return nil
+}
+
+// cloneItem returns copy of item which shares no memory with it.
+func cloneItem(item items.Item) items.Item {
+	if item.CompletedAt != nil {
+		completedAt := *item.CompletedAt
+		item.CompletedAt = &completedAt
+	}
+
+	return item
+}
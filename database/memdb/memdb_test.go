@@ -0,0 +1,19 @@
+package memdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"todo"
+	"todo/database/dbtest"
+	"todo/database/memdb"
+)
+
+func TestMemDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) todo.DB {
+		db := memdb.New()
+		t.Cleanup(func() { require.NoError(t, db.Close()) })
+		return db
+	})
+}
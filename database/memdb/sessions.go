@@ -0,0 +1,153 @@
+package memdb
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/sessions"
+)
+
+// ErrSessions indicates that there was an error in sessions repository.
+var ErrSessions = errs.Class("session repository error")
+
+type sessionsDB struct {
+	db *database
+}
+
+// Create creates session in the database.
+func (sessionsDB *sessionsDB) Create(ctx context.Context, session sessions.Session) error {
+	if err := ctx.Err(); err != nil {
+		return ErrSessions.Wrap(err)
+	}
+
+	sessionsDB.db.mu.Lock()
+	defer sessionsDB.db.mu.Unlock()
+
+	if _, ok := sessionsDB.db.sessions[session.ID]; ok {
+		return ErrSessions.New("session %s already exists", session.ID)
+	}
+	if _, ok := sessionsDB.db.users[session.UserID]; !ok {
+		return ErrSessions.New("user %s does not exist", session.UserID)
+	}
+
+	session.RefreshHash = cloneBytes(session.RefreshHash)
+	sessionsDB.db.sessions[session.ID] = session
+
+	return nil
+}
+
+// Get returns session by id from the database.
+func (sessionsDB *sessionsDB) Get(ctx context.Context, id uuid.UUID) (sessions.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return sessions.Session{}, ErrSessions.Wrap(err)
+	}
+
+	sessionsDB.db.mu.RLock()
+	defer sessionsDB.db.mu.RUnlock()
+
+	session, ok := sessionsDB.db.sessions[id]
+	if !ok {
+		return sessions.Session{}, sessions.ErrNoSession.New("")
+	}
+
+	session.RefreshHash = cloneBytes(session.RefreshHash)
+	return session, nil
+}
+
+// List returns all sessions of user from the database, the most recent first.
+func (sessionsDB *sessionsDB) List(ctx context.Context, userID uuid.UUID) ([]sessions.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrSessions.Wrap(err)
+	}
+
+	sessionsDB.db.mu.RLock()
+	defer sessionsDB.db.mu.RUnlock()
+
+	var userSessions []sessions.Session
+	for _, session := range sessionsDB.db.sessions {
+		if session.UserID == userID {
+			session.RefreshHash = cloneBytes(session.RefreshHash)
+			userSessions = append(userSessions, session)
+		}
+	}
+
+	sort.Slice(userSessions, func(i, j int) bool {
+		if !userSessions[i].CreatedAt.Equal(userSessions[j].CreatedAt) {
+			return userSessions[i].CreatedAt.After(userSessions[j].CreatedAt)
+		}
+		return bytes.Compare(userSessions[i].ID[:], userSessions[j].ID[:]) < 0
+	})
+
+	return userSessions, nil
+}
+
+// Rotate replaces refresh token hash of active session and extends its expiration time.
+func (sessionsDB *sessionsDB) Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash []byte, expiresAt time.Time) error {
+	return sessionsDB.update(ctx, id, func(stored *sessions.Session) bool {
+		if stored.Revoked || !bytes.Equal(stored.RefreshHash, oldHash) {
+			return false
+		}
+
+		stored.RefreshHash = cloneBytes(newHash)
+		stored.ExpiresAt = expiresAt
+		return true
+	})
+}
+
+// UpdateLastSeen sets time of the latest request made within session.
+func (sessionsDB *sessionsDB) UpdateLastSeen(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	return sessionsDB.update(ctx, id, func(stored *sessions.Session) bool {
+		stored.LastSeenAt = lastSeenAt
+		return true
+	})
+}
+
+// Revoke marks session as revoked.
+func (sessionsDB *sessionsDB) Revoke(ctx context.Context, id uuid.UUID) error {
+	return sessionsDB.update(ctx, id, func(stored *sessions.Session) bool {
+		stored.Revoked = true
+		return true
+	})
+}
+
+// RevokeAll marks all sessions of user as revoked.
+func (sessionsDB *sessionsDB) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return ErrSessions.Wrap(err)
+	}
+
+	sessionsDB.db.mu.Lock()
+	defer sessionsDB.db.mu.Unlock()
+
+	for id, session := range sessionsDB.db.sessions {
+		if session.UserID == userID {
+			session.Revoked = true
+			sessionsDB.db.sessions[id] = session
+		}
+	}
+
+	return nil
+}
+
+// update applies fn to stored session under write lock, ErrNoSession is returned when fn reports no match.
+func (sessionsDB *sessionsDB) update(ctx context.Context, id uuid.UUID, fn func(stored *sessions.Session) bool) error {
+	if err := ctx.Err(); err != nil {
+		return ErrSessions.Wrap(err)
+	}
+
+	sessionsDB.db.mu.Lock()
+	defer sessionsDB.db.mu.Unlock()
+
+	stored, ok := sessionsDB.db.sessions[id]
+	if !ok || !fn(&stored) {
+		return sessions.ErrNoSession.New("")
+	}
+	sessionsDB.db.sessions[id] = stored
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package memdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/tokens"
+)
+
+// ErrTokens indicates that there was an error in tokens repository.
+var ErrTokens = errs.Class("token repository error")
+
+type tokensDB struct {
+	db *database
+}
+
+// Create creates token in the database.
+func (tokensDB *tokensDB) Create(ctx context.Context, token tokens.Token) error {
+	if err := ctx.Err(); err != nil {
+		return ErrTokens.Wrap(err)
+	}
+
+	tokensDB.db.mu.Lock()
+	defer tokensDB.db.mu.Unlock()
+
+	if _, ok := tokensDB.db.tokens[string(token.Hash)]; ok {
+		return ErrTokens.New("token already exists")
+	}
+	if _, ok := tokensDB.db.users[token.UserID]; !ok {
+		return ErrTokens.New("user %s does not exist", token.UserID)
+	}
+
+	token.Hash = cloneBytes(token.Hash)
+	tokensDB.db.tokens[string(token.Hash)] = token
+
+	return nil
+}
+
+// Consume deletes token with hash and purpose which expires after now and returns it.
+func (tokensDB *tokensDB) Consume(ctx context.Context, purpose tokens.Purpose, hash []byte, now time.Time) (tokens.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return tokens.Token{}, ErrTokens.Wrap(err)
+	}
+
+	tokensDB.db.mu.Lock()
+	defer tokensDB.db.mu.Unlock()
+
+	token, ok := tokensDB.db.tokens[string(hash)]
+	if !ok || token.Purpose != purpose || !token.ExpiresAt.After(now) {
+		return tokens.Token{}, tokens.ErrNoToken.New("")
+	}
+
+	delete(tokensDB.db.tokens, string(hash))
+	return token, nil
+}
+
+// Get returns token with hash and purpose which expires after now without using it up.
+func (tokensDB *tokensDB) Get(ctx context.Context, purpose tokens.Purpose, hash []byte, now time.Time) (tokens.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return tokens.Token{}, ErrTokens.Wrap(err)
+	}
+
+	tokensDB.db.mu.RLock()
+	defer tokensDB.db.mu.RUnlock()
+
+	token, ok := tokensDB.db.tokens[string(hash)]
+	if !ok || token.Purpose != purpose || !token.ExpiresAt.After(now) {
+		return tokens.Token{}, tokens.ErrNoToken.New("")
+	}
+
+	token.Hash = cloneBytes(token.Hash)
+	return token, nil
+}
+
+// DeleteByUser deletes all tokens of user with purpose.
+func (tokensDB *tokensDB) DeleteByUser(ctx context.Context, userID uuid.UUID, purpose tokens.Purpose) error {
+	if err := ctx.Err(); err != nil {
+		return ErrTokens.Wrap(err)
+	}
+
+	tokensDB.db.mu.Lock()
+	defer tokensDB.db.mu.Unlock()
+
+	for key, token := range tokensDB.db.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(tokensDB.db.tokens, key)
+		}
+	}
+
+	return nil
+}
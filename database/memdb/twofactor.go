@@ -0,0 +1,132 @@
+package memdb
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/twofactor"
+)
+
+// ErrTwoFactor indicates that there was an error in second factors repository.
+var ErrTwoFactor = errs.Class("second factor repository error")
+
+type twoFactorDB struct {
+	db *database
+}
+
+// Save creates or replaces factor of user.
+func (twoFactorDB *twoFactorDB) Save(ctx context.Context, factor twofactor.Factor) error {
+	if err := ctx.Err(); err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+
+	twoFactorDB.db.mu.Lock()
+	defer twoFactorDB.db.mu.Unlock()
+
+	if _, ok := twoFactorDB.db.users[factor.UserID]; !ok {
+		return ErrTwoFactor.New("user %s does not exist", factor.UserID)
+	}
+
+	twoFactorDB.db.secondFactors[factor.UserID] = cloneFactor(factor)
+
+	return nil
+}
+
+// Get returns factor of user.
+func (twoFactorDB *twoFactorDB) Get(ctx context.Context, userID uuid.UUID) (twofactor.Factor, error) {
+	if err := ctx.Err(); err != nil {
+		return twofactor.Factor{}, ErrTwoFactor.Wrap(err)
+	}
+
+	twoFactorDB.db.mu.RLock()
+	defer twoFactorDB.db.mu.RUnlock()
+
+	factor, ok := twoFactorDB.db.secondFactors[userID]
+	if !ok {
+		return twofactor.Factor{}, twofactor.ErrNoFactor.New("")
+	}
+
+	return cloneFactor(factor), nil
+}
+
+// UseCounter records that code of counter is used.
+func (twoFactorDB *twoFactorDB) UseCounter(ctx context.Context, userID uuid.UUID, counter int64) error {
+	return twoFactorDB.update(ctx, userID, func(factor *twofactor.Factor) bool {
+		if factor.LastCounter >= counter {
+			return false
+		}
+
+		factor.LastCounter = counter
+		return true
+	})
+}
+
+// UseRecoveryCode deletes recovery code with hash.
+func (twoFactorDB *twoFactorDB) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	return twoFactorDB.update(ctx, userID, func(factor *twofactor.Factor) bool {
+		for i, code := range factor.RecoveryCodes {
+			if bytes.Equal(code, hash) {
+				factor.RecoveryCodes = append(factor.RecoveryCodes[:i:i], factor.RecoveryCodes[i+1:]...)
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Delete deletes factor of user.
+func (twoFactorDB *twoFactorDB) Delete(ctx context.Context, userID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+
+	twoFactorDB.db.mu.Lock()
+	defer twoFactorDB.db.mu.Unlock()
+
+	if _, ok := twoFactorDB.db.secondFactors[userID]; !ok {
+		return twofactor.ErrNoFactor.New("")
+	}
+
+	delete(twoFactorDB.db.secondFactors, userID)
+
+	return nil
+}
+
+// update applies change to factor of user, ErrNoFactor is returned if there is no factor or change is rejected.
+func (twoFactorDB *twoFactorDB) update(ctx context.Context, userID uuid.UUID, change func(factor *twofactor.Factor) bool) error {
+	if err := ctx.Err(); err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+
+	twoFactorDB.db.mu.Lock()
+	defer twoFactorDB.db.mu.Unlock()
+
+	factor, ok := twoFactorDB.db.secondFactors[userID]
+	if !ok || !change(&factor) {
+		return twofactor.ErrNoFactor.New("")
+	}
+
+	twoFactorDB.db.secondFactors[userID] = factor
+
+	return nil
+}
+
+// cloneFactor returns copy of factor which shares no memory with original.
+func cloneFactor(factor twofactor.Factor) twofactor.Factor {
+	codes := make([][]byte, len(factor.RecoveryCodes))
+	for i, code := range factor.RecoveryCodes {
+		codes[i] = cloneBytes(code)
+	}
+	factor.RecoveryCodes = codes
+
+	if factor.ConfirmedAt != nil {
+		confirmedAt := *factor.ConfirmedAt
+		factor.ConfirmedAt = &confirmedAt
+	}
+
+	return factor
+}
@@ -0,0 +1,268 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/users"
+)
+
+// ErrUsers indicates that there was an error in users repository.
+var ErrUsers = errs.Class("user repository error")
+
+type usersDB struct {
+	db *database
+}
+
+// Create creates user in the database.
+func (usersDB *usersDB) Create(ctx context.Context, user users.User) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	if _, ok := usersDB.db.users[user.ID]; ok {
+		return ErrUsers.New("user %s already exists", user.ID)
+	}
+	for _, existing := range usersDB.db.users {
+		if existing.Email == user.Email {
+			return users.ErrEmailTaken.New("%s", user.Email)
+		}
+	}
+
+	if user.Role == "" {
+		user.Role = users.RoleUser
+	}
+	usersDB.db.users[user.ID] = cloneUser(user)
+
+	return nil
+}
+
+// GetByID returns user by id from the database.
+func (usersDB *usersDB) GetByID(ctx context.Context, id uuid.UUID) (users.User, error) {
+	if err := ctx.Err(); err != nil {
+		return users.User{}, ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.RLock()
+	defer usersDB.db.mu.RUnlock()
+
+	user, ok := usersDB.db.users[id]
+	if !ok {
+		return users.User{}, users.ErrNoUser.New("")
+	}
+
+	return cloneUser(user), nil
+}
+
+// GetByEmail returns user by email form the database.
+func (usersDB *usersDB) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	if err := ctx.Err(); err != nil {
+		return users.User{}, ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.RLock()
+	defer usersDB.db.mu.RUnlock()
+
+	for _, user := range usersDB.db.users {
+		if user.Email == email {
+			return cloneUser(user), nil
+		}
+	}
+
+	return users.User{}, users.ErrNoUser.New("")
+}
+
+// List returns users whose email contains search in the database ordered by email.
+func (usersDB *usersDB) List(ctx context.Context, search string) ([]users.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.RLock()
+	defer usersDB.db.mu.RUnlock()
+
+	var list []users.User
+	for _, user := range usersDB.db.users {
+		if strings.Contains(user.Email, search) {
+			list = append(list, cloneUser(user))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Email < list[j].Email
+	})
+
+	return list, nil
+}
+
+// UpdatePassword replaces password hash of user in the database.
+func (usersDB *usersDB) UpdatePassword(ctx context.Context, id uuid.UUID, hash []byte) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	user, ok := usersDB.db.users[id]
+	if !ok {
+		return users.ErrNoUser.New("")
+	}
+
+	user.Password = cloneBytes(hash)
+	usersDB.db.users[id] = user
+
+	return nil
+}
+
+// UpdateEmail replaces email of user and marks it as unverified in the database.
+func (usersDB *usersDB) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	stored, ok := usersDB.db.users[id]
+	if !ok {
+		return users.ErrNoUser.New("")
+	}
+	for _, existing := range usersDB.db.users {
+		if existing.ID != id && existing.Email == email {
+			return users.ErrEmailTaken.New("%s", email)
+		}
+	}
+
+	stored.Email = email
+	stored.VerifiedAt = nil
+	usersDB.db.users[id] = stored
+
+	return nil
+}
+
+// Verify marks email of user as verified at verifiedAt in the database.
+func (usersDB *usersDB) Verify(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	user, ok := usersDB.db.users[id]
+	if !ok {
+		return users.ErrNoUser.New("")
+	}
+
+	user.VerifiedAt = &verifiedAt
+	usersDB.db.users[id] = user
+
+	return nil
+}
+
+// UpdateRole replaces role of user in the database.
+func (usersDB *usersDB) UpdateRole(ctx context.Context, id uuid.UUID, role users.Role) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	user, ok := usersDB.db.users[id]
+	if !ok {
+		return users.ErrNoUser.New("")
+	}
+
+	user.Role = role
+	usersDB.db.users[id] = user
+
+	return nil
+}
+
+// UpdateDisabled sets time user was disabled at in the database, nil enables user.
+func (usersDB *usersDB) UpdateDisabled(ctx context.Context, id uuid.UUID, disabledAt *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	user, ok := usersDB.db.users[id]
+	if !ok {
+		return users.ErrNoUser.New("")
+	}
+
+	user.DisabledAt = disabledAt
+	usersDB.db.users[id] = cloneUser(user)
+
+	return nil
+}
+
+// cloneUser returns copy of user which shares no memory with original.
+func cloneUser(user users.User) users.User {
+	user.Password = cloneBytes(user.Password)
+	if user.VerifiedAt != nil {
+		verifiedAt := *user.VerifiedAt
+		user.VerifiedAt = &verifiedAt
+	}
+	if user.DisabledAt != nil {
+		disabledAt := *user.DisabledAt
+		user.DisabledAt = &disabledAt
+	}
+
+	return user
+}
+
+// Delete deletes user with all its items, sessions, tokens, second factor, identities and access tokens from the database.
+func (usersDB *usersDB) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	usersDB.db.mu.Lock()
+	defer usersDB.db.mu.Unlock()
+
+	if _, ok := usersDB.db.users[id]; !ok {
+		return users.ErrNoUser.New("")
+	}
+
+	delete(usersDB.db.users, id)
+	for itemID, item := range usersDB.db.items {
+		if item.UserID == id {
+			delete(usersDB.db.items, itemID)
+		}
+	}
+	for sessionID, session := range usersDB.db.sessions {
+		if session.UserID == id {
+			delete(usersDB.db.sessions, sessionID)
+		}
+	}
+	for key, token := range usersDB.db.tokens {
+		if token.UserID == id {
+			delete(usersDB.db.tokens, key)
+		}
+	}
+	delete(usersDB.db.secondFactors, id)
+	for key, identity := range usersDB.db.identities {
+		if identity.UserID == id {
+			delete(usersDB.db.identities, key)
+		}
+	}
+	for tokenID, token := range usersDB.db.accessTokens {
+		if token.UserID == id {
+			delete(usersDB.db.accessTokens, tokenID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// ErrMigrations indicates that there was an error during schema migration.
+var ErrMigrations = errs.Class("migrations error")
+
+// ErrSchemaVersion indicates that db schema version does not match migrations known to the app.
+var ErrSchemaVersion = errs.Class("schema version mismatch")
+
+// migrationsLockID is a key of postgres advisory lock held while migrations run,
+// so several app instances never migrate the same database concurrently.
+const migrationsLockID = 7_315_002_001
+
+// migrationFiles contains numbered migrations, e.g. 0001_initial_schema.up.sql and 0001_initial_schema.down.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	// migrationFileName matches migration file names: version, description and direction.
+	migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	// migrationDescription matches allowed migration descriptions.
+	migrationDescription = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Migrator provides versioned schema migrations.
+type Migrator interface {
+	// MigrateUp applies given number of pending migrations, all of them if steps is zero.
+	MigrateUp(ctx context.Context, steps int) error
+	// MigrateDown reverts given number of applied migrations, starting from the latest.
+	MigrateDown(ctx context.Context, steps int) error
+	// MigrationStatus returns all known migrations and whether they are applied.
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+}
+
+// ensures that database implements Migrator.
+var _ Migrator = (*database)(nil)
+
+// Migration describes single versioned schema change.
+type Migration struct {
+	Version     int
+	Description string
+	Up          string
+	Down        string
+
+	hasUp, hasDown bool
+}
+
+// MigrationStatus describes state of single migration in the database.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+}
+
+// Migrations returns all migrations embedded into the binary ordered by version.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+// loadMigrations reads migrations from directory, every version must have both up and down file
+// and versions must go one after another starting from 1.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, ErrMigrations.Wrap(err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, ErrMigrations.New("invalid migration file name %q", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, ErrMigrations.Wrap(err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, ErrMigrations.Wrap(err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Description: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Description != match[2] {
+			return nil, ErrMigrations.New("migration %d has different descriptions: %q and %q", version, migration.Description, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up, migration.hasUp = string(body), true
+		} else {
+			migration.Down, migration.hasDown = string(body), true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if !migration.hasUp || !migration.hasDown {
+			return nil, ErrMigrations.New("migration %d must have both up and down files", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, ErrMigrations.New("migration %d is missing", i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// CreateMigration creates up and down files for the next migration in dir and returns their paths.
+func CreateMigration(dir, description string) (up, down string, err error) {
+	description = strings.ToLower(strings.Join(strings.Fields(description), "_"))
+	if !migrationDescription.MatchString(description) {
+		return "", "", ErrMigrations.New("description must contain only letters, digits and spaces")
+	}
+
+	migrations, err := loadMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := fmt.Sprintf("%04d_%s", len(migrations)+1, description)
+	up = filepath.Join(dir, prefix+".up.sql")
+	down = filepath.Join(dir, prefix+".down.sql")
+
+	for _, file := range []struct{ path, direction string }{{up, "up"}, {down, "down"}} {
+		header := fmt.Sprintf("-- %s: %s migration.\n", prefix, file.direction)
+
+		f, err := os.OpenFile(file.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return "", "", ErrMigrations.Wrap(err)
+		}
+		_, err = f.WriteString(header)
+		if err = errs.Combine(err, f.Close()); err != nil {
+			return "", "", ErrMigrations.Wrap(err)
+		}
+	}
+
+	return up, down, nil
+}
+
+// MigrateToLatest applies all pending migrations.
+func (db *database) MigrateToLatest(ctx context.Context) error {
+	return db.MigrateUp(ctx, 0)
+}
+
+// CheckVersion returns ErrSchemaVersion if schema is behind or ahead of embedded migrations.
+func (db *database) CheckVersion(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	current, err := db.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := len(migrations)
+	switch {
+	case current < latest:
+		return ErrSchemaVersion.New("schema version %d is behind %d, run migrations", current, latest)
+	case current > latest:
+		return ErrSchemaVersion.New("schema version %d is ahead of %d known to this binary", current, latest)
+	}
+
+	return nil
+}
+
+// MigrateUp applies given number of pending migrations, all of them if steps is zero.
+func (db *database) MigrateUp(ctx context.Context, steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	return db.withMigrationsLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		var done int
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && done == steps {
+				break
+			}
+
+			err = inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations(version, description, applied_at) VALUES($1, $2, $3)`,
+					migration.Version, migration.Description, time.Now().UTC())
+				return err
+			})
+			if err != nil {
+				return ErrMigrations.New("migration %d %s: %v", migration.Version, migration.Description, err)
+			}
+
+			done++
+		}
+
+		return nil
+	})
+}
+
+// MigrateDown reverts given number of applied migrations, starting from the latest.
+func (db *database) MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	return db.withMigrationsLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			err = inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return ErrMigrations.New("migration %d %s: %v", migration.Version, migration.Description, err)
+			}
+
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// MigrationStatus returns all known migrations and whether they are applied.
+func (db *database) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	err = db.withMigrationsLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, MigrationStatus{
+				Version:     migration.Version,
+				Description: migration.Description,
+				Applied:     ok,
+				AppliedAt:   appliedAt,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// schemaVersion returns the latest applied migration version, zero for an empty database.
+func (db *database) schemaVersion(ctx context.Context) (int, error) {
+	var exists bool
+	err := db.conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil || !exists {
+		return 0, ErrMigrations.Wrap(err)
+	}
+
+	var version sql.NullInt64
+	err = db.conn.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, ErrMigrations.Wrap(err)
+	}
+
+	return int(version.Int64), nil
+}
+
+// withMigrationsLock runs fn on a single connection holding migrations advisory lock.
+func (db *database) withMigrationsLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return ErrMigrations.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, ErrMigrations.Wrap(conn.Close()))
+	}()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationsLockID); err != nil {
+		return ErrMigrations.Wrap(err)
+	}
+	defer func() {
+		// lock is released with background context, so it is not leaked when ctx is canceled.
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationsLockID)
+		err = errs.Combine(err, ErrMigrations.Wrap(unlockErr))
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	    version     INTEGER                  PRIMARY KEY NOT NULL,
+	    description VARCHAR                              NOT NULL,
+	    applied_at  TIMESTAMP WITH TIME ZONE             NOT NULL
+	)`)
+	if err != nil {
+		return ErrMigrations.Wrap(err)
+	}
+
+	return fn(conn)
+}
+
+// appliedMigrations returns applied migration versions with time they were applied.
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (_ map[int]time.Time, err error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, ErrMigrations.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, ErrMigrations.Wrap(err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, ErrMigrations.Wrap(rows.Err())
+}
+
+// inTx runs fn in transaction which is committed if fn succeeds and rolled back otherwise.
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, tx.Rollback())
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(tx)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Run("embedded", func(t *testing.T) {
+		migrations, err := Migrations()
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version)
+			assert.NotEmpty(t, migration.Description)
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := map[string]fstest.MapFS{
+			"missing down": {
+				"m/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			"gap": {
+				"m/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"m/0001_a.down.sql": {Data: []byte("SELECT 1;")},
+				"m/0003_c.up.sql":   {Data: []byte("SELECT 1;")},
+				"m/0003_c.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			"different descriptions": {
+				"m/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"m/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			"invalid name": {
+				"m/first.sql": {Data: []byte("SELECT 1;")},
+			},
+		}
+
+		for name, fsys := range cases {
+			_, err := loadMigrations(fsys, "m")
+			assert.True(t, ErrMigrations.Has(err), name)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "migrations")
+		require.NoError(t, err)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		up, down, err := CreateMigration(dir, "Add Users Index")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "0001_add_users_index.up.sql"), up)
+		assert.Equal(t, filepath.Join(dir, "0001_add_users_index.down.sql"), down)
+
+		up, _, err = CreateMigration(dir, "second")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "0002_second.up.sql"), up)
+
+		migrations, err := loadMigrations(os.DirFS(dir), ".")
+		require.NoError(t, err)
+		assert.Len(t, migrations, 2)
+
+		_, _, err = CreateMigration(dir, "drop; table")
+		assert.Error(t, err)
+	})
+}
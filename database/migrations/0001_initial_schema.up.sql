@@ -0,0 +1,14 @@
+-- tables may already exist on deployments created before versioned migrations.
+CREATE TABLE IF NOT EXISTS users (
+    id            BYTEA     PRIMARY KEY    NOT NULL,
+    email         VARCHAR                  NOT NULL,
+    password_hash BYTEA                    NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE TABLE IF NOT EXISTS items (
+    id          BYTEA   PRIMARY KEY                            NOT NULL,
+    user_id     BYTEA   REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    name        VARCHAR                                        NOT NULL,
+    description VARCHAR                                        NOT NULL,
+    status      VARCHAR                                        NOT NULL
+);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS items_user_id_idx ON items (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE sessions (
+    id           BYTEA     PRIMARY KEY                            NOT NULL,
+    user_id      BYTEA     REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    user_agent   VARCHAR                                          NOT NULL,
+    ip           VARCHAR                                          NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE                         NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE                         NOT NULL,
+    revoked      BOOLEAN                                          NOT NULL DEFAULT FALSE
+);
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
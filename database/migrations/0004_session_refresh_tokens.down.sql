@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS remember_me;
+ALTER TABLE sessions DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS refresh_hash;
@@ -0,0 +1,4 @@
+-- sessions opened before refresh tokens have no refresh token and expire right away.
+ALTER TABLE sessions ADD COLUMN refresh_hash BYTEA                    NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN expires_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE sessions ADD COLUMN remember_me  BOOLEAN                  NOT NULL DEFAULT FALSE;
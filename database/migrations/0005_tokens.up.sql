@@ -0,0 +1,8 @@
+CREATE TABLE tokens (
+    hash       BYTEA     PRIMARY KEY                            NOT NULL,
+    user_id    BYTEA     REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    purpose    VARCHAR                                          NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE                         NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE                         NOT NULL
+);
+CREATE INDEX tokens_user_id_purpose_idx ON tokens (user_id, purpose);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS verified_at;
+DROP INDEX IF EXISTS users_email_key;
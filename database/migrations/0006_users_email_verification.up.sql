@@ -0,0 +1,19 @@
+-- emails are unique regardless of case, the app stores them in lower case.
+-- users whose emails differ only in case or surrounding spaces have to be merged or renamed by hand
+-- before this migration, it lists them instead of failing on the unique index.
+DO $$
+DECLARE
+	duplicates TEXT;
+BEGIN
+	SELECT string_agg(email, ', ' ORDER BY email) INTO duplicates FROM (
+		SELECT lower(btrim(email)) AS email FROM users GROUP BY lower(btrim(email)) HAVING count(*) > 1
+	) AS duplicated;
+	IF duplicates IS NOT NULL THEN
+		RAISE EXCEPTION 'emails of several users differ only in case, merge or rename them and migrate again: %', duplicates;
+	END IF;
+END $$;
+UPDATE users SET email = lower(btrim(email));
+CREATE UNIQUE INDEX users_email_key ON users (email);
+-- users registered before email verification are trusted.
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMP WITH TIME ZONE;
+UPDATE users SET verified_at = created_at;
@@ -0,0 +1,8 @@
+CREATE TABLE second_factors (
+    user_id        BYTEA     PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    secret         VARCHAR                                                     NOT NULL,
+    recovery_codes BYTEA[]                                                     NOT NULL,
+    last_counter   BIGINT                                                      NOT NULL,
+    created_at     TIMESTAMP WITH TIME ZONE                                    NOT NULL,
+    confirmed_at   TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE identities (
+    provider   VARCHAR                                          NOT NULL,
+    subject    VARCHAR                                          NOT NULL,
+    user_id    BYTEA     REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    email      VARCHAR                                          NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE                         NOT NULL,
+    PRIMARY KEY (provider, subject)
+);
+CREATE INDEX identities_user_id_idx ON identities (user_id);
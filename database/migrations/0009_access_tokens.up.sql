@@ -0,0 +1,11 @@
+CREATE TABLE access_tokens (
+    id           BYTEA     PRIMARY KEY                            NOT NULL,
+    user_id      BYTEA     REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    name         VARCHAR                                          NOT NULL,
+    hash         BYTEA     UNIQUE                                 NOT NULL,
+    scopes       VARCHAR[]                                        NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE                         NOT NULL,
+    expires_at   TIMESTAMP WITH TIME ZONE                         NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX access_tokens_user_id_idx ON access_tokens (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN role VARCHAR NOT NULL DEFAULT 'user' CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE;
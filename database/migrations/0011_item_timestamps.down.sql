@@ -0,0 +1,3 @@
+ALTER TABLE items DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE items DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE items DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,5 @@
+ALTER TABLE items ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE items ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE items ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+-- when existing items were completed is unknown, migration time keeps completed items completed.
+UPDATE items SET completed_at = updated_at WHERE status = 'Completed';
@@ -0,0 +1,99 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo/accesstokens"
+)
+
+// ErrAccessTokens indicates that there was an error in access tokens repository.
+var ErrAccessTokens = errs.Class("access token repository error")
+
+type accessTokensDB struct {
+	db *database
+}
+
+// collection returns access tokens collection.
+func (accessTokensDB *accessTokensDB) collection() *mongo.Collection {
+	return accessTokensDB.db.db.Collection(accessTokensCollection)
+}
+
+// Create creates token in the database, user must exist.
+func (accessTokensDB *accessTokensDB) Create(ctx context.Context, token accesstokens.Token) error {
+	count, err := accessTokensDB.db.db.Collection(usersCollection).CountDocuments(ctx, bson.M{"id": token.UserID})
+	if err != nil {
+		return ErrAccessTokens.Wrap(err)
+	}
+	if count == 0 {
+		return ErrAccessTokens.New("user %s does not exist", token.UserID)
+	}
+
+	_, err = accessTokensDB.collection().InsertOne(ctx, token)
+
+	return ErrAccessTokens.Wrap(err)
+}
+
+// GetByHash returns token with hash.
+func (accessTokensDB *accessTokensDB) GetByHash(ctx context.Context, hash []byte) (accesstokens.Token, error) {
+	var token accesstokens.Token
+
+	err := accessTokensDB.collection().FindOne(ctx, bson.M{"hash": hash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, accesstokens.ErrNoToken.Wrap(err)
+	}
+
+	return token, ErrAccessTokens.Wrap(err)
+}
+
+// List returns tokens of user, the most recent first.
+func (accessTokensDB *accessTokensDB) List(ctx context.Context, userID uuid.UUID) (_ []accesstokens.Token, err error) {
+	sort := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: 1}})
+
+	cursor, err := accessTokensDB.collection().Find(ctx, bson.M{"user_id": userID}, sort)
+	if err != nil {
+		return nil, ErrAccessTokens.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, ErrAccessTokens.Wrap(cursor.Close(ctx)))
+	}()
+
+	var list []accesstokens.Token
+	for cursor.Next(ctx) {
+		var token accesstokens.Token
+		if err = cursor.Decode(&token); err != nil {
+			return nil, ErrAccessTokens.Wrap(err)
+		}
+
+		list = append(list, token)
+	}
+
+	return list, ErrAccessTokens.Wrap(cursor.Err())
+}
+
+// UpdateLastUsed sets time token was last used at.
+func (accessTokensDB *accessTokensDB) UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error {
+	_, err := accessTokensDB.collection().UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"last_used_at": lastUsedAt}})
+
+	return ErrAccessTokens.Wrap(err)
+}
+
+// Delete deletes token of user.
+func (accessTokensDB *accessTokensDB) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := accessTokensDB.collection().DeleteOne(ctx, bson.M{"id": id, "user_id": userID})
+	if err != nil {
+		return ErrAccessTokens.Wrap(err)
+	}
+	if res.DeletedCount == 0 {
+		return accesstokens.ErrNoToken.New("")
+	}
+
+	return nil
+}
@@ -0,0 +1,362 @@
+// Package mongodb provides MongoDB implementation of todo.DB.
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo"
+	"todo/accesstokens"
+	"todo/identities"
+	"todo/items"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
+	"todo/users"
+)
+
+// Error indicates that there was an error in database.
+var Error = errs.Class("mongodb error")
+
+// ErrSchemaVersion indicates that collections miss indexes or data migrations required by the app.
+var ErrSchemaVersion = errs.Class("schema version mismatch")
+
+// schemaVersion is a number of data migrations made by MigrateToLatest, it is increased with every new one:
+// 1 normalizes emails and marks users registered before email verification as verified,
+// 2 adds timestamps to items.
+const schemaVersion = 2
+
+// defaultDatabase is used when database url has no database name.
+const defaultDatabase = "todo"
+
+const (
+	usersCollection    = "users"
+	itemsCollection    = "items"
+	sessionsCollection = "sessions"
+	tokensCollection   = "tokens"
+
+	secondFactorsCollection = "second_factors"
+	identitiesCollection    = "identities"
+	accessTokensCollection  = "access_tokens"
+
+	// schemaCollection holds the single document with schema version of database.
+	schemaCollection = "schema"
+	schemaDocumentID = "version"
+)
+
+// ensures that database implements todo.DB.
+var _ todo.DB = (*database)(nil)
+
+// database combines access to different collections with a record of the mongo client.
+//
+// architecture: Master Database
+type database struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// New returns todo.DB mongodb implementation, database name is taken from url path.
+func New(ctx context.Context, databaseURL string) (todo.DB, error) {
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	name := strings.TrimPrefix(parsed.Path, "/")
+	if name == "" {
+		name = defaultDatabase
+	}
+
+	opts := options.Client().ApplyURI(databaseURL).SetRegistry(registry())
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	return &database{client: client, db: client.Database(name)}, nil
+}
+
+// indexes describes indexes required by the app for each collection.
+var indexes = map[string][]mongo.IndexModel{
+	usersCollection: {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("users_id").SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("users_email").SetUnique(true)},
+	},
+	itemsCollection: {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("items_id").SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("items_user_id")},
+	},
+	sessionsCollection: {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("sessions_id").SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("sessions_user_id")},
+	},
+	tokensCollection: {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("tokens_hash").SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}, Options: options.Index().SetName("tokens_user_id_purpose")},
+	},
+	secondFactorsCollection: {
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("second_factors_user_id").SetUnique(true)},
+	},
+	identitiesCollection: {
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetName("identities_provider_subject").SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("identities_user_id")},
+	},
+	accessTokensCollection: {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("access_tokens_id").SetUnique(true)},
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("access_tokens_hash").SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("access_tokens_user_id")},
+	},
+}
+
+// MigrateToLatest normalizes existing documents, creates indexes and records schema version,
+// collections are created by mongo on first insert.
+func (db *database) MigrateToLatest(ctx context.Context) error {
+	if err := db.checkDuplicateEmails(ctx); err != nil {
+		return err
+	}
+
+	// emails are unique regardless of case, emails of existing users are trimmed and lower cased
+	// like emails of new users before the unique index is built.
+	collection := db.db.Collection(usersCollection)
+	_, err := collection.UpdateMany(ctx, bson.M{},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"email": normalizedEmail}}}},
+	)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	// users registered before email verification have no verified_at field at all, they are trusted.
+	// New users always have the field.
+	_, err = collection.UpdateMany(ctx,
+		bson.M{"verified_at": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"verified_at": "$created_at"}}}},
+	)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	// items created before timestamps have none of them, like in sql migration they are created and updated
+	// at migration time and completed items are completed at it, so that they stay completed.
+	now := time.Now().UTC()
+	for _, backfill := range []struct{ filter, set bson.M }{
+		{bson.M{"created_at": bson.M{"$exists": false}}, bson.M{"created_at": now}},
+		{bson.M{"updated_at": bson.M{"$exists": false}}, bson.M{"updated_at": now}},
+		{bson.M{"completed_at": bson.M{"$exists": false}, "status": items.StatusCompleted}, bson.M{"completed_at": now}},
+	} {
+		_, err = db.db.Collection(itemsCollection).UpdateMany(ctx, backfill.filter, bson.M{"$set": backfill.set})
+		if err != nil {
+			return Error.Wrap(err)
+		}
+	}
+
+	for collection, models := range indexes {
+		_, err := db.db.Collection(collection).Indexes().CreateMany(ctx, models)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+	}
+
+	// version is written last, so that interrupted migration is run again.
+	_, err = db.db.Collection(schemaCollection).UpdateOne(ctx,
+		bson.M{"_id": schemaDocumentID},
+		bson.M{"$set": bson.M{"version": schemaVersion}},
+		options.Update().SetUpsert(true),
+	)
+
+	return Error.Wrap(err)
+}
+
+// normalizedEmail is an aggregation expression of trimmed lower cased email of user.
+var normalizedEmail = bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}
+
+// checkDuplicateEmails returns error listing emails shared by several users once normalized,
+// such users have to be merged or renamed by hand before the unique index can be built.
+func (db *database) checkDuplicateEmails(ctx context.Context) (err error) {
+	cursor, err := db.db.Collection(usersCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": normalizedEmail, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, Error.Wrap(cursor.Close(ctx)))
+	}()
+
+	var duplicates []string
+	for cursor.Next(ctx) {
+		var group struct {
+			Email string `bson:"_id"`
+		}
+		if err = cursor.Decode(&group); err != nil {
+			return Error.Wrap(err)
+		}
+		duplicates = append(duplicates, group.Email)
+	}
+	if err = cursor.Err(); err != nil {
+		return Error.Wrap(err)
+	}
+
+	if len(duplicates) > 0 {
+		return Error.New("emails of several users differ only in case, merge or rename them and migrate again: %s",
+			strings.Join(duplicates, ", "))
+	}
+
+	return nil
+}
+
+// CheckVersion returns ErrSchemaVersion if data migrations of database are behind or ahead of the app
+// or any of required indexes is missing.
+func (db *database) CheckVersion(ctx context.Context) error {
+	var schema struct {
+		Version int `bson:"version"`
+	}
+	err := db.db.Collection(schemaCollection).FindOne(ctx, bson.M{"_id": schemaDocumentID}).Decode(&schema)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return Error.Wrap(err)
+	}
+
+	switch {
+	case schema.Version < schemaVersion:
+		return ErrSchemaVersion.New("schema version %d is behind %d, run migrations", schema.Version, schemaVersion)
+	case schema.Version > schemaVersion:
+		return ErrSchemaVersion.New("schema version %d is ahead of %d known to this binary", schema.Version, schemaVersion)
+	}
+
+	for collection, models := range indexes {
+		existing, err := db.indexNames(ctx, collection)
+		if err != nil {
+			return err
+		}
+
+		for _, model := range models {
+			if _, ok := existing[*model.Options.Name]; !ok {
+				return ErrSchemaVersion.New("index %s is missing, run migrations", *model.Options.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// indexNames returns names of all indexes of collection.
+func (db *database) indexNames(ctx context.Context, collection string) (_ map[string]struct{}, err error) {
+	cursor, err := db.db.Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, Error.Wrap(cursor.Close(ctx)))
+	}()
+
+	names := make(map[string]struct{})
+	for cursor.Next(ctx) {
+		var index struct {
+			Name string `bson:"name"`
+		}
+		if err = cursor.Decode(&index); err != nil {
+			return nil, Error.Wrap(err)
+		}
+		names[index.Name] = struct{}{}
+	}
+
+	return names, Error.Wrap(cursor.Err())
+}
+
+// Close closes underlying db connection.
+func (db *database) Close() error {
+	return Error.Wrap(db.client.Disconnect(context.Background()))
+}
+
+// Users provides access to users db.
+func (db *database) Users() users.DB {
+	return &usersDB{db: db}
+}
+
+// Items provides access to items db.
+func (db *database) Items() items.DB {
+	return &itemsDB{db: db}
+}
+
+// Sessions provides access to sessions db.
+func (db *database) Sessions() sessions.DB {
+	return &sessionsDB{db: db}
+}
+
+// Tokens provides access to single-use tokens db.
+func (db *database) Tokens() tokens.DB {
+	return &tokensDB{db: db}
+}
+
+// TwoFactor provides access to second factors db.
+func (db *database) TwoFactor() twofactor.DB {
+	return &twoFactorDB{db: db}
+}
+
+// Identities provides access to external identities db.
+func (db *database) Identities() identities.DB {
+	return &identitiesDB{db: db}
+}
+
+// AccessTokens provides access to personal access tokens db.
+func (db *database) AccessTokens() accesstokens.DB {
+	return &accessTokensDB{db: db}
+}
+
+// typeUUID is reflect type of uuid.UUID.
+var typeUUID = reflect.TypeOf(uuid.UUID{})
+
+// registry returns bson registry which stores uuid.UUID as binary uuid instead of array of bytes.
+func registry() *bsoncodec.Registry {
+	return bson.NewRegistryBuilder().
+		RegisterTypeEncoder(typeUUID, bsoncodec.ValueEncoderFunc(encodeUUID)).
+		RegisterTypeDecoder(typeUUID, bsoncodec.ValueDecoderFunc(decodeUUID)).
+		Build()
+}
+
+// encodeUUID encodes uuid.UUID as binary of uuid subtype.
+func encodeUUID(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != typeUUID {
+		return bsoncodec.ValueEncoderError{Name: "encodeUUID", Types: []reflect.Type{typeUUID}, Received: val}
+	}
+
+	id := val.Interface().(uuid.UUID)
+	return vw.WriteBinaryWithSubtype(id[:], bsontype.BinaryUUID)
+}
+
+// decodeUUID decodes binary of uuid subtype into uuid.UUID.
+func decodeUUID(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != typeUUID {
+		return bsoncodec.ValueDecoderError{Name: "decodeUUID", Types: []reflect.Type{typeUUID}, Received: val}
+	}
+
+	data, subtype, err := vr.ReadBinary()
+	if err != nil {
+		return err
+	}
+	if subtype != bsontype.BinaryUUID {
+		return errs.New("unexpected binary subtype %d for uuid", subtype)
+	}
+
+	id, err := uuid.FromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	val.Set(reflect.ValueOf(id))
+	return nil
+}
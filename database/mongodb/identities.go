@@ -0,0 +1,54 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"todo/identities"
+)
+
+// ErrIdentities indicates that there was an error in identities repository.
+var ErrIdentities = errs.Class("identity repository error")
+
+type identitiesDB struct {
+	db *database
+}
+
+// collection returns identities collection.
+func (identitiesDB *identitiesDB) collection() *mongo.Collection {
+	return identitiesDB.db.db.Collection(identitiesCollection)
+}
+
+// Create links identity to user, user must exist.
+func (identitiesDB *identitiesDB) Create(ctx context.Context, identity identities.Identity) error {
+	count, err := identitiesDB.db.db.Collection(usersCollection).CountDocuments(ctx, bson.M{"id": identity.UserID})
+	if err != nil {
+		return ErrIdentities.Wrap(err)
+	}
+	if count == 0 {
+		return ErrIdentities.New("user %s does not exist", identity.UserID)
+	}
+
+	_, err = identitiesDB.collection().InsertOne(ctx, identity)
+	if mongo.IsDuplicateKeyError(err) {
+		return identities.ErrIdentityTaken.New("%s %s", identity.Provider, identity.Subject)
+	}
+
+	return ErrIdentities.Wrap(err)
+}
+
+// Get returns identity of subject at provider.
+func (identitiesDB *identitiesDB) Get(ctx context.Context, provider, subject string) (identities.Identity, error) {
+	var identity identities.Identity
+
+	err := identitiesDB.collection().FindOne(ctx, bson.M{"provider": provider, "subject": subject}).Decode(&identity)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return identity, identities.ErrNoIdentity.Wrap(err)
+	}
+
+	return identity, ErrIdentities.Wrap(err)
+}
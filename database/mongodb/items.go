@@ -0,0 +1,163 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo/items"
+)
+
+// ErrItems indicates that there was an error in items repository.
+var ErrItems = errs.Class("item repository error")
+
+type itemsDB struct {
+	db *database
+}
+
+// collection returns items collection.
+func (itemsDB *itemsDB) collection() *mongo.Collection {
+	return itemsDB.db.db.Collection(itemsCollection)
+}
+
+// Create creates item in the database, owner must exist.
+func (itemsDB *itemsDB) Create(ctx context.Context, item items.Item) error {
+	count, err := itemsDB.db.db.Collection(usersCollection).CountDocuments(ctx, bson.M{"id": item.UserID})
+	if err != nil {
+		return ErrItems.Wrap(err)
+	}
+	if count == 0 {
+		return ErrItems.New("user %s does not exist", item.UserID)
+	}
+
+	_, err = itemsDB.collection().InsertOne(ctx, item)
+
+	return ErrItems.Wrap(err)
+}
+
+// List returns all user items from the database ordered by name and id.
+func (itemsDB *itemsDB) List(ctx context.Context, userID uuid.UUID) (_ []items.Item, err error) {
+	sort := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}})
+
+	cursor, err := itemsDB.collection().Find(ctx, bson.M{"user_id": userID}, sort)
+	if err != nil {
+		return nil, ErrItems.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, ErrItems.Wrap(cursor.Close(ctx)))
+	}()
+
+	var userItems []items.Item
+	for cursor.Next(ctx) {
+		var item items.Item
+		if err = cursor.Decode(&item); err != nil {
+			return nil, ErrItems.Wrap(err)
+		}
+
+		userItems = append(userItems, item)
+	}
+
+	return userItems, ErrItems.Wrap(cursor.Err())
+}
+
+// CountByUser returns number of items of every user who has any from the database.
+func (itemsDB *itemsDB) CountByUser(ctx context.Context) (_ map[uuid.UUID]int, err error) {
+	pipeline := mongo.Pipeline{{{Key: "$group", Value: bson.M{"_id": "$user_id", "count": bson.M{"$sum": 1}}}}}
+
+	cursor, err := itemsDB.collection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, ErrItems.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, ErrItems.Wrap(cursor.Close(ctx)))
+	}()
+
+	counts := make(map[uuid.UUID]int)
+	for cursor.Next(ctx) {
+		var group struct {
+			UserID uuid.UUID `bson:"_id"`
+			Count  int       `bson:"count"`
+		}
+		if err = cursor.Decode(&group); err != nil {
+			return nil, ErrItems.Wrap(err)
+		}
+
+		counts[group.UserID] = group.Count
+	}
+
+	return counts, ErrItems.Wrap(cursor.Err())
+}
+
+// Get returns item by id owned by user from the database.
+func (itemsDB *itemsDB) Get(ctx context.Context, userID, id uuid.UUID) (items.Item, error) {
+	var item items.Item
+
+	err := itemsDB.collection().FindOne(ctx, bson.M{"id": id, "user_id": userID}).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return item, items.ErrNoItem.Wrap(err)
+	}
+
+	return item, ErrItems.Wrap(err)
+}
+
+// Update updates name, description and update time of item owned by item.UserID in the database.
+func (itemsDB *itemsDB) Update(ctx context.Context, item items.Item) error {
+	return itemsDB.update(ctx, item.UserID, item.ID, bson.M{"name": item.Name, "description": item.Description, "updated_at": item.UpdatedAt})
+}
+
+// UpdateStatus updates status, update and completion times of item owned by item.UserID in the database
+// if its stored status is still from, otherwise it is ErrConflict.
+func (itemsDB *itemsDB) UpdateStatus(ctx context.Context, item items.Item, from items.Status) error {
+	filter := bson.M{"id": item.ID, "user_id": item.UserID, "status": from}
+	fields := bson.M{"status": item.Status, "updated_at": item.UpdatedAt, "completed_at": item.CompletedAt}
+
+	res, err := itemsDB.collection().UpdateOne(ctx, filter, bson.M{"$set": fields})
+	if err != nil {
+		return ErrItems.Wrap(err)
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// nothing is matched either because item is missing or because its status was changed meanwhile.
+	count, err := itemsDB.collection().CountDocuments(ctx, bson.M{"id": item.ID, "user_id": item.UserID})
+	switch {
+	case err != nil:
+		return ErrItems.Wrap(err)
+	case count == 0:
+		return items.ErrNoItem.New("")
+	default:
+		return items.ErrConflict.New("status is not %s", from)
+	}
+}
+
+// Delete deletes item owned by user from the database.
+func (itemsDB *itemsDB) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := itemsDB.collection().DeleteOne(ctx, bson.M{"id": id, "user_id": userID})
+	if err != nil {
+		return ErrItems.Wrap(err)
+	}
+	if res.DeletedCount == 0 {
+		return items.ErrNoItem.New("")
+	}
+
+	return nil
+}
+
+// update sets fields of item with given id owned by user.
+func (itemsDB *itemsDB) update(ctx context.Context, userID, id uuid.UUID, fields bson.M) error {
+	res, err := itemsDB.collection().UpdateOne(ctx, bson.M{"id": id, "user_id": userID}, bson.M{"$set": fields})
+	if err != nil {
+		return ErrItems.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return items.ErrNoItem.New("")
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package mongodb_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo"
+	"todo/database/dbtest"
+	"todo/database/mongodb"
+	"todo/items"
+	"todo/users"
+)
+
+func TestMongoDB(t *testing.T) {
+	dbtest.Run(t, dbtest.MongoDB(t))
+}
+
+// legacyDatabase returns database which is not migrated yet and its raw handle to shape documents
+// like older versions of the app stored them. Test is skipped if dbtest.MongoDBEnv is not set.
+func legacyDatabase(t *testing.T) (todo.DB, *mongo.Database) {
+	databaseURL := os.Getenv(dbtest.MongoDBEnv)
+	if databaseURL == "" {
+		t.Skip(dbtest.MongoDBEnv + " is not set")
+	}
+	ctx := context.Background()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(databaseURL))
+	require.NoError(t, err)
+	name := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	t.Cleanup(func() {
+		require.NoError(t, client.Database(name).Drop(ctx))
+		require.NoError(t, client.Disconnect(ctx))
+	})
+
+	parsed, err := url.Parse(databaseURL)
+	require.NoError(t, err)
+	parsed.Path = "/" + name
+	db, err := mongodb.New(ctx, parsed.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	return db, client.Database(name)
+}
+
+func TestMigrateLegacyUsers(t *testing.T) {
+	ctx := context.Background()
+	db, raw := legacyDatabase(t)
+
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	// users stored before email verification have no verified_at and emails as they were typed.
+	_, err := raw.Collection("users").UpdateMany(ctx, bson.M{},
+		bson.M{"$set": bson.M{"email": " User@GMail.com"}, "$unset": bson.M{"verified_at": ""}},
+	)
+	require.NoError(t, err)
+
+	err = db.CheckVersion(ctx)
+	assert.True(t, mongodb.ErrSchemaVersion.Has(err), err)
+
+	require.NoError(t, db.MigrateToLatest(ctx))
+	require.NoError(t, db.CheckVersion(ctx))
+
+	stored, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, stored.ID)
+	assert.True(t, stored.Verified(), "users registered before verification are trusted")
+
+	// database migrated by newer binary is refused as well.
+	_, err = raw.Collection("schema").UpdateOne(ctx, bson.M{"_id": "version"}, bson.M{"$inc": bson.M{"version": 1}})
+	require.NoError(t, err)
+	err = db.CheckVersion(ctx)
+	assert.True(t, mongodb.ErrSchemaVersion.Has(err), err)
+}
+
+func TestMigrateItemTimestamps(t *testing.T) {
+	ctx := context.Background()
+	db, raw := legacyDatabase(t)
+	require.NoError(t, db.MigrateToLatest(ctx))
+
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+	for _, status := range []items.Status{items.StatusTODO, items.StatusCompleted} {
+		item := items.Item{ID: uuid.New(), UserID: user.ID, Name: string(status), Status: status}
+		require.NoError(t, db.Items().Create(ctx, item))
+	}
+
+	// items stored before timestamps have no such fields at all and schema is at the previous version.
+	_, err := raw.Collection("items").UpdateMany(ctx, bson.M{},
+		bson.M{"$unset": bson.M{"created_at": "", "updated_at": "", "completed_at": ""}},
+	)
+	require.NoError(t, err)
+	_, err = raw.Collection("schema").UpdateOne(ctx, bson.M{"_id": "version"}, bson.M{"$set": bson.M{"version": 1}})
+	require.NoError(t, err)
+
+	err = db.CheckVersion(ctx)
+	assert.True(t, mongodb.ErrSchemaVersion.Has(err), err)
+
+	migratedAt := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, db.MigrateToLatest(ctx))
+	require.NoError(t, db.CheckVersion(ctx))
+
+	list, err := db.Items().List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	for _, item := range list {
+		assert.False(t, item.CreatedAt.Before(migratedAt), item.CreatedAt)
+		assert.Equal(t, item.CreatedAt, item.UpdatedAt)
+		if item.Status == items.StatusCompleted {
+			require.NotNil(t, item.CompletedAt, "completed item must stay completed")
+			assert.Equal(t, item.UpdatedAt, *item.CompletedAt)
+		} else {
+			assert.Nil(t, item.CompletedAt)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo/sessions"
+)
+
+// ErrSessions indicates that there was an error in sessions repository.
+var ErrSessions = errs.Class("session repository error")
+
+type sessionsDB struct {
+	db *database
+}
+
+// collection returns sessions collection.
+func (sessionsDB *sessionsDB) collection() *mongo.Collection {
+	return sessionsDB.db.db.Collection(sessionsCollection)
+}
+
+// Create creates session in the database, user must exist.
+func (sessionsDB *sessionsDB) Create(ctx context.Context, session sessions.Session) error {
+	count, err := sessionsDB.db.db.Collection(usersCollection).CountDocuments(ctx, bson.M{"id": session.UserID})
+	if err != nil {
+		return ErrSessions.Wrap(err)
+	}
+	if count == 0 {
+		return ErrSessions.New("user %s does not exist", session.UserID)
+	}
+
+	_, err = sessionsDB.collection().InsertOne(ctx, session)
+
+	return ErrSessions.Wrap(err)
+}
+
+// Get returns session by id from the database.
+func (sessionsDB *sessionsDB) Get(ctx context.Context, id uuid.UUID) (sessions.Session, error) {
+	var session sessions.Session
+
+	err := sessionsDB.collection().FindOne(ctx, bson.M{"id": id}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return session, sessions.ErrNoSession.Wrap(err)
+	}
+
+	return session, ErrSessions.Wrap(err)
+}
+
+// List returns all sessions of user from the database, the most recent first.
+func (sessionsDB *sessionsDB) List(ctx context.Context, userID uuid.UUID) (_ []sessions.Session, err error) {
+	sort := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: 1}})
+
+	cursor, err := sessionsDB.collection().Find(ctx, bson.M{"user_id": userID}, sort)
+	if err != nil {
+		return nil, ErrSessions.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, ErrSessions.Wrap(cursor.Close(ctx)))
+	}()
+
+	var userSessions []sessions.Session
+	for cursor.Next(ctx) {
+		var session sessions.Session
+		if err = cursor.Decode(&session); err != nil {
+			return nil, ErrSessions.Wrap(err)
+		}
+
+		userSessions = append(userSessions, session)
+	}
+
+	return userSessions, ErrSessions.Wrap(cursor.Err())
+}
+
+// Rotate replaces refresh token hash of active session and extends its expiration time.
+func (sessionsDB *sessionsDB) Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash []byte, expiresAt time.Time) error {
+	filter := bson.M{"id": id, "refresh_hash": oldHash, "revoked": false}
+	return sessionsDB.update(ctx, filter, bson.M{"refresh_hash": newHash, "expires_at": expiresAt})
+}
+
+// UpdateLastSeen sets time of the latest request made within session.
+func (sessionsDB *sessionsDB) UpdateLastSeen(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	return sessionsDB.update(ctx, bson.M{"id": id}, bson.M{"last_seen_at": lastSeenAt})
+}
+
+// Revoke marks session as revoked.
+func (sessionsDB *sessionsDB) Revoke(ctx context.Context, id uuid.UUID) error {
+	return sessionsDB.update(ctx, bson.M{"id": id}, bson.M{"revoked": true})
+}
+
+// RevokeAll marks all sessions of user as revoked.
+func (sessionsDB *sessionsDB) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	_, err := sessionsDB.collection().UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"revoked": true}})
+
+	return ErrSessions.Wrap(err)
+}
+
+// update sets fields of session which matches filter.
+func (sessionsDB *sessionsDB) update(ctx context.Context, filter, fields bson.M) error {
+	res, err := sessionsDB.collection().UpdateOne(ctx, filter, bson.M{"$set": fields})
+	if err != nil {
+		return ErrSessions.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return sessions.ErrNoSession.New("")
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"todo/tokens"
+)
+
+// ErrTokens indicates that there was an error in tokens repository.
+var ErrTokens = errs.Class("token repository error")
+
+type tokensDB struct {
+	db *database
+}
+
+// collection returns tokens collection.
+func (tokensDB *tokensDB) collection() *mongo.Collection {
+	return tokensDB.db.db.Collection(tokensCollection)
+}
+
+// Create creates token in the database, user must exist.
+func (tokensDB *tokensDB) Create(ctx context.Context, token tokens.Token) error {
+	count, err := tokensDB.db.db.Collection(usersCollection).CountDocuments(ctx, bson.M{"id": token.UserID})
+	if err != nil {
+		return ErrTokens.Wrap(err)
+	}
+	if count == 0 {
+		return ErrTokens.New("user %s does not exist", token.UserID)
+	}
+
+	_, err = tokensDB.collection().InsertOne(ctx, token)
+
+	return ErrTokens.Wrap(err)
+}
+
+// Consume deletes token with hash and purpose which expires after now and returns it.
+func (tokensDB *tokensDB) Consume(ctx context.Context, purpose tokens.Purpose, hash []byte, now time.Time) (tokens.Token, error) {
+	var token tokens.Token
+
+	filter := bson.M{"hash": hash, "purpose": purpose, "expires_at": bson.M{"$gt": now}}
+	err := tokensDB.collection().FindOneAndDelete(ctx, filter).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, tokens.ErrNoToken.Wrap(err)
+	}
+
+	return token, ErrTokens.Wrap(err)
+}
+
+// Get returns token with hash and purpose which expires after now without using it up.
+func (tokensDB *tokensDB) Get(ctx context.Context, purpose tokens.Purpose, hash []byte, now time.Time) (tokens.Token, error) {
+	var token tokens.Token
+
+	filter := bson.M{"hash": hash, "purpose": purpose, "expires_at": bson.M{"$gt": now}}
+	err := tokensDB.collection().FindOne(ctx, filter).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, tokens.ErrNoToken.Wrap(err)
+	}
+
+	return token, ErrTokens.Wrap(err)
+}
+
+// DeleteByUser deletes all tokens of user with purpose.
+func (tokensDB *tokensDB) DeleteByUser(ctx context.Context, userID uuid.UUID, purpose tokens.Purpose) error {
+	_, err := tokensDB.collection().DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+
+	return ErrTokens.Wrap(err)
+}
@@ -0,0 +1,99 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo/twofactor"
+)
+
+// ErrTwoFactor indicates that there was an error in second factors repository.
+var ErrTwoFactor = errs.Class("second factor repository error")
+
+type twoFactorDB struct {
+	db *database
+}
+
+// collection returns second factors collection.
+func (twoFactorDB *twoFactorDB) collection() *mongo.Collection {
+	return twoFactorDB.db.db.Collection(secondFactorsCollection)
+}
+
+// Save creates or replaces factor of user, user must exist.
+func (twoFactorDB *twoFactorDB) Save(ctx context.Context, factor twofactor.Factor) error {
+	count, err := twoFactorDB.db.db.Collection(usersCollection).CountDocuments(ctx, bson.M{"id": factor.UserID})
+	if err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+	if count == 0 {
+		return ErrTwoFactor.New("user %s does not exist", factor.UserID)
+	}
+
+	if factor.RecoveryCodes == nil {
+		factor.RecoveryCodes = [][]byte{}
+	}
+
+	_, err = twoFactorDB.collection().ReplaceOne(ctx, bson.M{"user_id": factor.UserID}, factor, options.Replace().SetUpsert(true))
+
+	return ErrTwoFactor.Wrap(err)
+}
+
+// Get returns factor of user.
+func (twoFactorDB *twoFactorDB) Get(ctx context.Context, userID uuid.UUID) (twofactor.Factor, error) {
+	var factor twofactor.Factor
+
+	err := twoFactorDB.collection().FindOne(ctx, bson.M{"user_id": userID}).Decode(&factor)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return factor, twofactor.ErrNoFactor.Wrap(err)
+	}
+
+	return factor, ErrTwoFactor.Wrap(err)
+}
+
+// UseCounter records that code of counter is used.
+func (twoFactorDB *twoFactorDB) UseCounter(ctx context.Context, userID uuid.UUID, counter int64) error {
+	return twoFactorDB.updateOne(ctx,
+		bson.M{"user_id": userID, "last_counter": bson.M{"$lt": counter}},
+		bson.M{"$set": bson.M{"last_counter": counter}},
+	)
+}
+
+// UseRecoveryCode deletes recovery code with hash.
+func (twoFactorDB *twoFactorDB) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	return twoFactorDB.updateOne(ctx,
+		bson.M{"user_id": userID, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+}
+
+// Delete deletes factor of user.
+func (twoFactorDB *twoFactorDB) Delete(ctx context.Context, userID uuid.UUID) error {
+	res, err := twoFactorDB.collection().DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+	if res.DeletedCount == 0 {
+		return twofactor.ErrNoFactor.New("")
+	}
+
+	return nil
+}
+
+// updateOne applies update to factor which matches filter, ErrNoFactor is returned if there is no such factor.
+func (twoFactorDB *twoFactorDB) updateOne(ctx context.Context, filter, update bson.M) error {
+	res, err := twoFactorDB.collection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return twofactor.ErrNoFactor.New("")
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"todo/users"
+)
+
+// ErrUsers indicates that there was an error in users repository.
+var ErrUsers = errs.Class("user repository error")
+
+type usersDB struct {
+	db *database
+}
+
+// collection returns users collection.
+func (usersDB *usersDB) collection() *mongo.Collection {
+	return usersDB.db.db.Collection(usersCollection)
+}
+
+// Create creates user in the database.
+func (usersDB *usersDB) Create(ctx context.Context, user users.User) error {
+	if user.Role == "" {
+		user.Role = users.RoleUser
+	}
+
+	_, err := usersDB.collection().InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "users_email") {
+		return users.ErrEmailTaken.New("%s", user.Email)
+	}
+
+	return ErrUsers.Wrap(err)
+}
+
+// GetByID returns user by id from the database.
+func (usersDB *usersDB) GetByID(ctx context.Context, id uuid.UUID) (users.User, error) {
+	return usersDB.findOne(ctx, bson.M{"id": id})
+}
+
+// GetByEmail returns user by email form the database.
+func (usersDB *usersDB) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	return usersDB.findOne(ctx, bson.M{"email": email})
+}
+
+// findOne returns user which matches filter.
+func (usersDB *usersDB) findOne(ctx context.Context, filter bson.M) (users.User, error) {
+	var user users.User
+
+	err := usersDB.collection().FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return user, users.ErrNoUser.Wrap(err)
+	}
+
+	return withDefaultRole(user), ErrUsers.Wrap(err)
+}
+
+// List returns users whose email contains search in the database ordered by email.
+func (usersDB *usersDB) List(ctx context.Context, search string) (_ []users.User, err error) {
+	filter := bson.M{"email": bson.M{"$regex": regexp.QuoteMeta(search)}}
+
+	cursor, err := usersDB.collection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "email", Value: 1}}))
+	if err != nil {
+		return nil, ErrUsers.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, cursor.Close(ctx))
+	}()
+
+	var list []users.User
+	for cursor.Next(ctx) {
+		var user users.User
+		if err = cursor.Decode(&user); err != nil {
+			return nil, ErrUsers.Wrap(err)
+		}
+		list = append(list, withDefaultRole(user))
+	}
+
+	return list, ErrUsers.Wrap(cursor.Err())
+}
+
+// withDefaultRole returns user with RoleUser if user was created before roles were introduced.
+func withDefaultRole(user users.User) users.User {
+	if user.Role == "" {
+		user.Role = users.RoleUser
+	}
+	return user
+}
+
+// UpdatePassword replaces password hash of user in the database.
+func (usersDB *usersDB) UpdatePassword(ctx context.Context, id uuid.UUID, hash []byte) error {
+	res, err := usersDB.collection().UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"password": hash}})
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return nil
+}
+
+// UpdateEmail replaces email of user and marks it as unverified in the database.
+func (usersDB *usersDB) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	update := bson.M{"$set": bson.M{"email": email, "verified_at": nil}}
+
+	res, err := usersDB.collection().UpdateOne(ctx, bson.M{"id": id}, update)
+	if mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "users_email") {
+		return users.ErrEmailTaken.New("%s", email)
+	}
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return nil
+}
+
+// Verify marks email of user as verified at verifiedAt in the database.
+func (usersDB *usersDB) Verify(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error {
+	res, err := usersDB.collection().UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"verified_at": verifiedAt}})
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return nil
+}
+
+// UpdateRole replaces role of user in the database.
+func (usersDB *usersDB) UpdateRole(ctx context.Context, id uuid.UUID, role users.Role) error {
+	res, err := usersDB.collection().UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return nil
+}
+
+// UpdateDisabled sets time user was disabled at in the database, nil enables user.
+func (usersDB *usersDB) UpdateDisabled(ctx context.Context, id uuid.UUID, disabledAt *time.Time) error {
+	res, err := usersDB.collection().UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"disabled_at": disabledAt}})
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+	if res.MatchedCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return nil
+}
+
+// Delete deletes user with its items, sessions, tokens, second factor, identities and access tokens from the database.
+// Mongo has no foreign keys, so they are deleted right after the user.
+func (usersDB *usersDB) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := usersDB.collection().DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+	if res.DeletedCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	for _, collection := range []string{itemsCollection, sessionsCollection, tokensCollection, secondFactorsCollection, identitiesCollection, accessTokensCollection} {
+		_, err = usersDB.db.db.Collection(collection).DeleteMany(ctx, bson.M{"user_id": id})
+		if err != nil {
+			return ErrUsers.Wrap(err)
+		}
+	}
+
+	return nil
+}
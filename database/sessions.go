@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/sessions"
+)
+
+// ErrSessions indicates that there was an error in sessions repository.
+var ErrSessions = errs.Class("session repository error")
+
+type sessionsDB struct {
+	conn *sql.DB
+}
+
+// Create creates session in the database.
+func (sessionsDB *sessionsDB) Create(ctx context.Context, session sessions.Session) error {
+	query := `INSERT INTO sessions(id, user_id, user_agent, ip, refresh_hash, remember_me, created_at, last_seen_at, expires_at, revoked)
+	          VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+
+	_, err := sessionsDB.conn.ExecContext(ctx, query, session.ID, session.UserID, session.UserAgent, session.IP,
+		session.RefreshHash, session.RememberMe, session.CreatedAt, session.LastSeenAt, session.ExpiresAt, session.Revoked)
+
+	return ErrSessions.Wrap(err)
+}
+
+// Get returns session by id from the database.
+func (sessionsDB *sessionsDB) Get(ctx context.Context, id uuid.UUID) (sessions.Session, error) {
+	var session sessions.Session
+	query := `SELECT id, user_id, user_agent, ip, refresh_hash, remember_me, created_at, last_seen_at, expires_at, revoked
+	          FROM sessions
+	          WHERE id = $1`
+
+	err := sessionsDB.conn.QueryRowContext(ctx, query, id).Scan(&session.ID, &session.UserID, &session.UserAgent,
+		&session.IP, &session.RefreshHash, &session.RememberMe, &session.CreatedAt, &session.LastSeenAt,
+		&session.ExpiresAt, &session.Revoked)
+	if errs.Is(err, sql.ErrNoRows) {
+		return session, sessions.ErrNoSession.Wrap(err)
+	}
+
+	return session, ErrSessions.Wrap(err)
+}
+
+// List returns all sessions of user from the database, the most recent first.
+func (sessionsDB *sessionsDB) List(ctx context.Context, userID uuid.UUID) (_ []sessions.Session, err error) {
+	query := `SELECT id, user_id, user_agent, ip, refresh_hash, remember_me, created_at, last_seen_at, expires_at, revoked
+	          FROM sessions
+	          WHERE user_id = $1
+	          ORDER BY created_at DESC, id`
+
+	rows, err := sessionsDB.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, ErrSessions.Wrap(err)
+	}
+
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
+
+	var userSessions []sessions.Session
+	for rows.Next() {
+		var session sessions.Session
+		err = rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.RefreshHash,
+			&session.RememberMe, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.Revoked)
+		if err != nil {
+			return nil, ErrSessions.Wrap(err)
+		}
+
+		userSessions = append(userSessions, session)
+	}
+
+	return userSessions, ErrSessions.Wrap(rows.Err())
+}
+
+// Rotate replaces refresh token hash of active session and extends its expiration time.
+func (sessionsDB *sessionsDB) Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash []byte, expiresAt time.Time) error {
+	query := `UPDATE sessions
+	          SET refresh_hash = $1, expires_at = $2
+	          WHERE id = $3 AND refresh_hash = $4 AND NOT revoked`
+
+	return sessionsDB.exec(ctx, query, newHash, expiresAt, id, oldHash)
+}
+
+// UpdateLastSeen sets time of the latest request made within session.
+func (sessionsDB *sessionsDB) UpdateLastSeen(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	query := `UPDATE sessions
+	          SET last_seen_at = $1
+	          WHERE id = $2`
+
+	return sessionsDB.exec(ctx, query, lastSeenAt, id)
+}
+
+// Revoke marks session as revoked.
+func (sessionsDB *sessionsDB) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE sessions
+	          SET revoked = TRUE
+	          WHERE id = $1`
+
+	return sessionsDB.exec(ctx, query, id)
+}
+
+// RevokeAll marks all sessions of user as revoked.
+func (sessionsDB *sessionsDB) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE sessions
+	          SET revoked = TRUE
+	          WHERE user_id = $1`
+
+	_, err := sessionsDB.conn.ExecContext(ctx, query, userID)
+
+	return ErrSessions.Wrap(err)
+}
+
+// exec executes query which changes single session, ErrNoSession is returned when nothing is changed.
+func (sessionsDB *sessionsDB) exec(ctx context.Context, query string, args ...interface{}) error {
+	res, err := sessionsDB.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return ErrSessions.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return sessions.ErrNoSession.New("")
+	}
+
+	return ErrSessions.Wrap(err)
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/tokens"
+)
+
+// ErrTokens indicates that there was an error in tokens repository.
+var ErrTokens = errs.Class("token repository error")
+
+type tokensDB struct {
+	conn *sql.DB
+}
+
+// Create creates token in the database.
+func (tokensDB *tokensDB) Create(ctx context.Context, token tokens.Token) error {
+	query := `INSERT INTO tokens(hash, user_id, purpose, created_at, expires_at)
+	          VALUES($1,$2,$3,$4,$5)`
+
+	_, err := tokensDB.conn.ExecContext(ctx, query, token.Hash, token.UserID, token.Purpose, token.CreatedAt, token.ExpiresAt)
+
+	return ErrTokens.Wrap(err)
+}
+
+// Consume deletes token with hash and purpose which expires after now and returns it.
+func (tokensDB *tokensDB) Consume(ctx context.Context, purpose tokens.Purpose, hash []byte, now time.Time) (tokens.Token, error) {
+	var token tokens.Token
+	query := `DELETE FROM tokens
+	          WHERE hash = $1 AND purpose = $2 AND expires_at > $3
+	          RETURNING hash, user_id, purpose, created_at, expires_at`
+
+	err := tokensDB.conn.QueryRowContext(ctx, query, hash, purpose, now).Scan(&token.Hash, &token.UserID,
+		&token.Purpose, &token.CreatedAt, &token.ExpiresAt)
+	if errs.Is(err, sql.ErrNoRows) {
+		return token, tokens.ErrNoToken.Wrap(err)
+	}
+
+	return token, ErrTokens.Wrap(err)
+}
+
+// Get returns token with hash and purpose which expires after now without using it up.
+func (tokensDB *tokensDB) Get(ctx context.Context, purpose tokens.Purpose, hash []byte, now time.Time) (tokens.Token, error) {
+	var token tokens.Token
+	query := `SELECT hash, user_id, purpose, created_at, expires_at
+	          FROM tokens
+	          WHERE hash = $1 AND purpose = $2 AND expires_at > $3`
+
+	err := tokensDB.conn.QueryRowContext(ctx, query, hash, purpose, now).Scan(&token.Hash, &token.UserID,
+		&token.Purpose, &token.CreatedAt, &token.ExpiresAt)
+	if errs.Is(err, sql.ErrNoRows) {
+		return token, tokens.ErrNoToken.Wrap(err)
+	}
+
+	return token, ErrTokens.Wrap(err)
+}
+
+// DeleteByUser deletes all tokens of user with purpose.
+func (tokensDB *tokensDB) DeleteByUser(ctx context.Context, userID uuid.UUID, purpose tokens.Purpose) error {
+	query := `DELETE FROM tokens
+	          WHERE user_id = $1 AND purpose = $2`
+
+	_, err := tokensDB.conn.ExecContext(ctx, query, userID, purpose)
+
+	return ErrTokens.Wrap(err)
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/zeebo/errs"
+
+	"todo/twofactor"
+)
+
+// ErrTwoFactor indicates that there was an error in second factors repository.
+var ErrTwoFactor = errs.Class("second factor repository error")
+
+type twoFactorDB struct {
+	conn *sql.DB
+}
+
+// Save creates or replaces factor of user.
+func (twoFactorDB *twoFactorDB) Save(ctx context.Context, factor twofactor.Factor) error {
+	query := `INSERT INTO second_factors(user_id, secret, recovery_codes, last_counter, created_at, confirmed_at)
+	          VALUES($1,$2,$3,$4,$5,$6)
+	          ON CONFLICT (user_id) DO UPDATE
+	          SET secret = EXCLUDED.secret, recovery_codes = EXCLUDED.recovery_codes, last_counter = EXCLUDED.last_counter,
+	              created_at = EXCLUDED.created_at, confirmed_at = EXCLUDED.confirmed_at`
+
+	recoveryCodes := pq.ByteaArray(factor.RecoveryCodes)
+	if recoveryCodes == nil {
+		recoveryCodes = pq.ByteaArray{}
+	}
+
+	_, err := twoFactorDB.conn.ExecContext(ctx, query, factor.UserID, factor.Secret, recoveryCodes,
+		factor.LastCounter, factor.CreatedAt, factor.ConfirmedAt)
+
+	return ErrTwoFactor.Wrap(err)
+}
+
+// Get returns factor of user.
+func (twoFactorDB *twoFactorDB) Get(ctx context.Context, userID uuid.UUID) (twofactor.Factor, error) {
+	var factor twofactor.Factor
+	query := `SELECT user_id, secret, recovery_codes, last_counter, created_at, confirmed_at
+	          FROM second_factors
+	          WHERE user_id = $1`
+
+	err := twoFactorDB.conn.QueryRowContext(ctx, query, userID).Scan(&factor.UserID, &factor.Secret,
+		(*pq.ByteaArray)(&factor.RecoveryCodes), &factor.LastCounter, &factor.CreatedAt, &factor.ConfirmedAt)
+	if errs.Is(err, sql.ErrNoRows) {
+		return factor, twofactor.ErrNoFactor.Wrap(err)
+	}
+
+	return factor, ErrTwoFactor.Wrap(err)
+}
+
+// UseCounter records that code of counter is used.
+func (twoFactorDB *twoFactorDB) UseCounter(ctx context.Context, userID uuid.UUID, counter int64) error {
+	query := `UPDATE second_factors
+	          SET last_counter = $2
+	          WHERE user_id = $1 AND last_counter < $2`
+
+	return twoFactorDB.execOne(ctx, query, userID, counter)
+}
+
+// UseRecoveryCode deletes recovery code with hash.
+func (twoFactorDB *twoFactorDB) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	query := `UPDATE second_factors
+	          SET recovery_codes = array_remove(recovery_codes, $2)
+	          WHERE user_id = $1 AND $2 = ANY(recovery_codes)`
+
+	return twoFactorDB.execOne(ctx, query, userID, hash)
+}
+
+// Delete deletes factor of user.
+func (twoFactorDB *twoFactorDB) Delete(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM second_factors
+	          WHERE user_id = $1`
+
+	return twoFactorDB.execOne(ctx, query, userID)
+}
+
+// execOne executes query which must change one row, ErrNoFactor is returned otherwise.
+func (twoFactorDB *twoFactorDB) execOne(ctx context.Context, query string, args ...interface{}) error {
+	res, err := twoFactorDB.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return ErrTwoFactor.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return twofactor.ErrNoFactor.New("")
+	}
+
+	return ErrTwoFactor.Wrap(err)
+}
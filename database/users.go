@@ -3,8 +3,13 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/zeebo/errs"
+
 	"todo/users"
 )
 
@@ -17,23 +22,45 @@ type usersDB struct {
 
 // Create creates user in the database.
 func (usersDB *usersDB) Create(ctx context.Context, user users.User) error {
-	query := `INSERT INTO users(id, email, password_hash, created_at)
-	          VALUES($1,$2,$3,$4)`
+	if user.Role == "" {
+		user.Role = users.RoleUser
+	}
+
+	query := `INSERT INTO users(id, email, password_hash, created_at, verified_at, role, disabled_at)
+	          VALUES($1,$2,$3,$4,$5,$6,$7)`
 
-	_, err := usersDB.conn.ExecContext(ctx, query, user.ID, user.Email, user.Password, user.CreatedAt)
+	_, err := usersDB.conn.ExecContext(ctx, query, user.ID, user.Email, user.Password, user.CreatedAt, user.VerifiedAt,
+		user.Role, user.DisabledAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_email_key" {
+		return users.ErrEmailTaken.New("%s", user.Email)
+	}
 
 	return ErrUsers.Wrap(err)
 }
 
+// GetByID returns user by id from the database.
+func (usersDB *usersDB) GetByID(ctx context.Context, id uuid.UUID) (users.User, error) {
+	query := `SELECT ` + userColumns + `
+	          FROM users
+	          WHERE id = $1`
+
+	user, err := scanUser(usersDB.conn.QueryRowContext(ctx, query, id))
+	if errs.Is(err, sql.ErrNoRows) {
+		return user, users.ErrNoUser.Wrap(err)
+	}
+
+	return user, ErrUsers.Wrap(err)
+}
+
 // GetByEmail returns user by email form the database.
 func (usersDB *usersDB) GetByEmail(ctx context.Context, email string) (users.User, error) {
-	var user users.User
-	query := `SELECT id, email, password_hash, created_at
+	query := `SELECT ` + userColumns + `
 	          FROM users
 	          WHERE email = $1`
 
-	err := usersDB.conn.QueryRowContext(ctx, query, email).Scan(&user.ID,
-		&user.Email, &user.Password, &user.CreatedAt)
+	user, err := scanUser(usersDB.conn.QueryRowContext(ctx, query, email))
 	if errs.Is(err, sql.ErrNoRows) {
 		return user, users.ErrNoUser.Wrap(err)
 	}
@@ -41,6 +68,134 @@ func (usersDB *usersDB) GetByEmail(ctx context.Context, email string) (users.Use
 	return user, ErrUsers.Wrap(err)
 }
 
+// List returns users whose email contains search in the database ordered by email.
+func (usersDB *usersDB) List(ctx context.Context, search string) (_ []users.User, err error) {
+	// search is matched literally, so that % and _ in it are not wildcards.
+	query := `SELECT ` + userColumns + `
+	          FROM users
+	          WHERE strpos(email, $1) > 0
+	          ORDER BY email COLLATE "C"`
+
+	rows, err := usersDB.conn.QueryContext(ctx, query, search)
+	if err != nil {
+		return nil, ErrUsers.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
+
+	var list []users.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, ErrUsers.Wrap(err)
+		}
+		list = append(list, user)
+	}
+
+	return list, ErrUsers.Wrap(rows.Err())
+}
+
+// UpdatePassword replaces password hash of user in the database.
+func (usersDB *usersDB) UpdatePassword(ctx context.Context, id uuid.UUID, hash []byte) error {
+	query := `UPDATE users
+	          SET password_hash = $1
+	          WHERE id = $2`
+
+	res, err := usersDB.conn.ExecContext(ctx, query, hash, id)
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return ErrUsers.Wrap(err)
+}
+
+// UpdateEmail replaces email of user and marks it as unverified in the database.
+func (usersDB *usersDB) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	query := `UPDATE users
+	          SET email = $1, verified_at = NULL
+	          WHERE id = $2`
+
+	res, err := usersDB.conn.ExecContext(ctx, query, email, id)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_email_key" {
+		return users.ErrEmailTaken.New("%s", email)
+	}
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return ErrUsers.Wrap(err)
+}
+
+// Verify marks email of user as verified at verifiedAt in the database.
+func (usersDB *usersDB) Verify(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error {
+	query := `UPDATE users
+	          SET verified_at = $1
+	          WHERE id = $2`
+
+	res, err := usersDB.conn.ExecContext(ctx, query, verifiedAt, id)
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return ErrUsers.Wrap(err)
+}
+
+// UpdateRole replaces role of user in the database.
+func (usersDB *usersDB) UpdateRole(ctx context.Context, id uuid.UUID, role users.Role) error {
+	query := `UPDATE users
+	          SET role = $1
+	          WHERE id = $2`
+
+	res, err := usersDB.conn.ExecContext(ctx, query, role, id)
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return ErrUsers.Wrap(err)
+}
+
+// UpdateDisabled sets time user was disabled at in the database, nil enables user.
+func (usersDB *usersDB) UpdateDisabled(ctx context.Context, id uuid.UUID, disabledAt *time.Time) error {
+	query := `UPDATE users
+	          SET disabled_at = $1
+	          WHERE id = $2`
+
+	res, err := usersDB.conn.ExecContext(ctx, query, disabledAt, id)
+	if err != nil {
+		return ErrUsers.Wrap(err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err == nil && rowsCount == 0 {
+		return users.ErrNoUser.New("")
+	}
+
+	return ErrUsers.Wrap(err)
+}
+
 // Delete deletes user from the database.
 func (usersDB *usersDB) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users
@@ -58,3 +213,14 @@ func (usersDB *usersDB) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return ErrUsers.Wrap(err)
 }
+
+// userColumns are columns of users table in the order scanUser reads them.
+const userColumns = `id, email, password_hash, created_at, verified_at, role, disabled_at`
+
+// scanUser scans user from row with userColumns.
+func scanUser(row rowScanner) (users.User, error) {
+	var user users.User
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.VerifiedAt, &user.Role, &user.DisabledAt)
+
+	return user, err
+}
@@ -1,6 +1,6 @@
 module todo
 
-go 1.15
+go 1.16
 
 require (
 	github.com/google/uuid v1.3.0
@@ -12,4 +12,7 @@ require (
 	go.uber.org/zap v1.19.1
 	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	rsc.io/qr v0.2.0
 )
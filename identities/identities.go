@@ -0,0 +1,38 @@
+// Package identities links accounts of external OpenID Connect providers to users.
+package identities
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+)
+
+var (
+	// ErrNoIdentity indicates that identity is not linked to any user.
+	ErrNoIdentity = errs.Class("identity does not exist")
+
+	// ErrIdentityTaken indicates that identity is already linked to a user.
+	ErrIdentityTaken = errs.Class("identity is already linked")
+)
+
+// DB is exposing access to identities db.
+type DB interface {
+	// Create links identity to user, ErrIdentityTaken is returned when it is linked already.
+	Create(ctx context.Context, identity Identity) error
+	// Get returns identity of subject at provider.
+	Get(ctx context.Context, provider, subject string) (Identity, error)
+}
+
+// Identity is an account of user at external provider.
+type Identity struct {
+	// Provider is issuer url of provider.
+	Provider string `bson:"provider"`
+	// Subject is id of account at provider, it never changes unlike email.
+	Subject string    `bson:"subject"`
+	UserID  uuid.UUID `bson:"user_id"`
+	// Email is email of account at provider when identity was linked.
+	Email     string    `bson:"email"`
+	CreatedAt time.Time `bson:"created_at"`
+}
@@ -1,37 +1,58 @@
 package items
 
 import (
+	"bytes"
 	"context"
-	"github.com/zeebo/errs"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/zeebo/errs"
 )
 
-var ErrNoItem = errs.Class("item does not exist")
+var (
+	// ErrNoItem indicates that item does not exist.
+	ErrNoItem = errs.Class("item does not exist")
+
+	// ErrForbidden indicates that acting user is not allowed to access item.
+	ErrForbidden = errs.Class("item access forbidden")
+
+	// ErrValidation indicates that item data or requested change is invalid.
+	ErrValidation = errs.Class("item validation error")
+
+	// ErrConflict indicates that item was changed by other request meanwhile.
+	ErrConflict = errs.Class("item conflict")
+)
 
 // DB is exposing access to items db.
 type DB interface {
 	// Create creates item in the database.
 	Create(ctx context.Context, item Item) error
-	// List returns all items from the database.
+	// List returns all user items from the database ordered by name and id.
 	List(ctx context.Context, userID uuid.UUID) ([]Item, error)
-	// Get returns item by id from the database.
-	Get(ctx context.Context, id uuid.UUID) (Item, error)
-	// Update updates name and description of item in the database.
+	// Get returns item by id owned by user from the database.
+	Get(ctx context.Context, userID, id uuid.UUID) (Item, error)
+	// Update updates name, description and update time of item owned by item.UserID in the database.
 	Update(ctx context.Context, item Item) error
-	// UpdateStatus updates status of item in the database.
-	UpdateStatus(ctx context.Context, id uuid.UUID, newStatus Status) error
-	// Delete deletes item from the database.
-	Delete(ctx context.Context, id uuid.UUID) error
+	// UpdateStatus updates status, update and completion times of item owned by item.UserID in the database
+	// if its stored status is still from, otherwise it is ErrConflict.
+	UpdateStatus(ctx context.Context, item Item, from Status) error
+	// Delete deletes item owned by user from the database.
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	// CountByUser returns number of items of every user who has any from the database.
+	CountByUser(ctx context.Context) (map[uuid.UUID]int, error)
 }
 
 // Item defines item list.
 type Item struct {
-	ID          uuid.UUID `bson:"id"`
-	UserID      uuid.UUID `json:"userId"`
-	Name        string    `bson:"name"`
-	Description string    `bson:"description"`
-	Status      Status    `bson:"status"`
+	ID          uuid.UUID `json:"id" bson:"id"`
+	UserID      uuid.UUID `json:"userId" bson:"user_id"`
+	Name        string    `json:"name" bson:"name"`
+	Description string    `json:"description" bson:"description"`
+	Status      Status    `json:"status" bson:"status"`
+	CreatedAt   time.Time `json:"createdAt" bson:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updated_at"`
+	// CompletedAt is when item reached StatusCompleted, nil until then.
+	CompletedAt *time.Time `json:"completedAt,omitempty" bson:"completed_at"`
 }
 
 // Status defines list of possible statuses of items.
@@ -45,3 +66,62 @@ const (
 	// StatusCompleted defines type of status of item which is completed.
 	StatusCompleted Status = "Completed"
 )
+
+// Sort is an order items are listed in.
+type Sort string
+
+const (
+	// SortName lists items by name, it is the default order.
+	SortName Sort = "name"
+	// SortCreated lists the most recently created items first.
+	SortCreated Sort = "created"
+	// SortUpdated lists the most recently updated items first.
+	SortUpdated Sort = "updated"
+	// SortCompleted lists the most recently completed items first, items which are not completed go last.
+	SortCompleted Sort = "completed"
+)
+
+// Sorts are all orders items can be listed in.
+var Sorts = []Sort{SortName, SortCreated, SortUpdated, SortCompleted}
+
+// ParseSort returns order with key, empty key is SortName and unknown key is ErrValidation.
+func ParseSort(key string) (Sort, error) {
+	if key == "" {
+		return SortName, nil
+	}
+
+	for _, sort := range Sorts {
+		if Sort(key) == sort {
+			return sort, nil
+		}
+	}
+
+	return "", ErrValidation.New("unknown sort %q", key)
+}
+
+// Less reports whether item a is listed before item b, ties are listed by name and id.
+func (sort Sort) Less(a, b Item) bool {
+	switch sort {
+	case SortCreated:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+	case SortUpdated:
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+	case SortCompleted:
+		switch {
+		case a.CompletedAt == nil && b.CompletedAt == nil:
+		case a.CompletedAt == nil || b.CompletedAt == nil:
+			return b.CompletedAt == nil
+		case !a.CompletedAt.Equal(*b.CompletedAt):
+			return a.CompletedAt.After(*b.CompletedAt)
+		}
+	}
+
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
@@ -2,15 +2,21 @@ package items
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/zeebo/errs"
+
+	"todo/pkg/auth"
+	"todo/users"
 )
 
 // Error indicates that there was an error in items service.
 var Error = errs.Class("items service error")
 
 // Service is handling items related logic.
+// Acting user is taken from auth claims of context and can access only own items.
 type Service struct {
 	items DB
 }
@@ -22,62 +28,155 @@ func New(items DB) *Service {
 	}
 }
 
-// Create creates item.
-func (service *Service) Create(ctx context.Context, userID uuid.UUID, name, description string) error {
+// Create creates item owned by acting user.
+func (service *Service) Create(ctx context.Context, name, description string) (Item, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+	if name == "" {
+		return Item{}, ErrValidation.New("name is required")
+	}
+
+	createdAt := now()
 	item := Item{
 		ID:          uuid.New(),
 		UserID:      userID,
 		Name:        name,
 		Description: description,
 		Status:      StatusTODO,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+
+	if err = service.items.Create(ctx, item); err != nil {
+		return Item{}, Error.Wrap(err)
 	}
 
-	return Error.Wrap(service.items.Create(ctx, item))
+	return item, nil
 }
 
-// List returns all items.
-func (service *Service) List(ctx context.Context, userID uuid.UUID) ([]Item, error) {
+// List returns all items of acting user in order by.
+func (service *Service) List(ctx context.Context, by Sort) ([]Item, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.ListByUser(ctx, userID, by)
+}
+
+// ListByUser returns all items of user in order by, it serves operators who are not acting users.
+func (service *Service) ListByUser(ctx context.Context, userID uuid.UUID, by Sort) ([]Item, error) {
+	if _, err := ParseSort(string(by)); err != nil {
+		return nil, err
+	}
+
 	items, err := service.items.List(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
 
-	return items, Error.Wrap(err)
+	sort.SliceStable(items, func(i, j int) bool {
+		return by.Less(items[i], items[j])
+	})
+
+	return items, nil
 }
 
-// Get returns item by id.
+// Get returns item by id if it is owned by acting user, items of other users are ErrNoItem
+// so that their existence is not disclosed.
 func (service *Service) Get(ctx context.Context, id uuid.UUID) (Item, error) {
-	item, err := service.items.Get(ctx, id)
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	item, err := service.items.Get(ctx, userID, id)
 
 	return item, Error.Wrap(err)
 }
 
 // Update changes name and description of item.
 func (service *Service) Update(ctx context.Context, id uuid.UUID, name, description string) error {
-	return Error.Wrap(service.items.Update(ctx, Item{
-		ID:          id,
-		Name:        name,
-		Description: description,
-	}))
+	if name == "" {
+		return ErrValidation.New("name is required")
+	}
+
+	item, err := service.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	item.Name, item.Description, item.UpdatedAt = name, description, now()
+
+	return Error.Wrap(service.items.Update(ctx, item))
 }
 
 // UpdateStatus updates status of certain item.
 func (service *Service) UpdateStatus(ctx context.Context, id uuid.UUID) error {
 	item, err := service.Get(ctx, id)
 	if err != nil {
-		return Error.Wrap(err)
+		return err
 	}
 
+	from := item.Status
+	item.UpdatedAt = now()
 	switch item.Status {
 	case StatusTODO:
 		item.Status = StatusInProgress
 	case StatusInProgress:
 		item.Status = StatusCompleted
+		item.CompletedAt = &item.UpdatedAt
 	case StatusCompleted:
-		return Error.New("could not update status of item")
+		return ErrValidation.New("item %s is already completed", id)
+	}
+
+	err = service.items.UpdateStatus(ctx, item, from)
+	if ErrConflict.Has(err) {
+		return ErrConflict.New("item %s was moved from %s by other request, reload it", id, from)
 	}
 
-	return Error.Wrap(service.items.UpdateStatus(ctx, id, item.Status))
+	return Error.Wrap(err)
 }
 
 // Delete deletes certain item.
 func (service *Service) Delete(ctx context.Context, id uuid.UUID) error {
-	return Error.Wrap(service.items.Delete(ctx, id))
+	item, err := service.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return Error.Wrap(service.items.Delete(ctx, item.UserID, id))
+}
+
+// CountByUser returns number of items of every user who has any,
+// acting user has to be allowed to manage users.
+func (service *Service) CountByUser(ctx context.Context) (map[uuid.UUID]int, error) {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return nil, ErrForbidden.Wrap(err)
+	}
+	if !users.Role(claims.Role).Can(users.PermissionManageUsers) {
+		return nil, ErrForbidden.New("user %s is not allowed to count items of other users", claims.UserID)
+	}
+
+	counts, err := service.items.CountByUser(ctx)
+
+	return counts, Error.Wrap(err)
+}
+
+// now returns current time with millisecond precision, which every database stores.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// actingUser returns id of user from auth claims of context.
+func actingUser(ctx context.Context) (uuid.UUID, error) {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return uuid.UUID{}, ErrForbidden.Wrap(err)
+	}
+
+	return claims.UserID, nil
 }
@@ -0,0 +1,201 @@
+package items_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/database/memdb"
+	"todo/items"
+	"todo/pkg/auth"
+	"todo/users"
+)
+
+func TestServiceOwnership(t *testing.T) {
+	db := memdb.New()
+	service := items.New(db.Items())
+
+	newUser := func() context.Context {
+		user := users.User{ID: uuid.New(), Email: uuid.NewString() + "@gmail.com", CreatedAt: time.Now()}
+		require.NoError(t, db.Users().Create(context.Background(), user))
+		return auth.SetClaims(context.Background(), auth.Claims{UserID: user.ID, Email: user.Email})
+	}
+	owner, intruder := newUser(), newUser()
+
+	item, err := service.Create(owner, "task", "description")
+	require.NoError(t, err)
+	list, err := service.List(owner, items.SortName)
+	require.NoError(t, err)
+	require.Equal(t, []items.Item{item}, list)
+
+	t.Run("intruder does not see item", func(t *testing.T) {
+		list, err := service.List(intruder, items.SortName)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("intruder can not access item", func(t *testing.T) {
+		// items of other users do not exist for intruder.
+		_, err := service.Get(intruder, item.ID)
+		assert.True(t, items.ErrNoItem.Has(err), err)
+
+		err = service.Update(intruder, item.ID, "stolen", "stolen")
+		assert.True(t, items.ErrNoItem.Has(err), err)
+
+		err = service.UpdateStatus(intruder, item.ID)
+		assert.True(t, items.ErrNoItem.Has(err), err)
+
+		err = service.Delete(intruder, item.ID)
+		assert.True(t, items.ErrNoItem.Has(err), err)
+
+		stored, err := service.Get(owner, item.ID)
+		require.NoError(t, err)
+		assert.Equal(t, item, stored)
+	})
+
+	t.Run("anonymous can not access items", func(t *testing.T) {
+		_, err := service.List(context.Background(), items.SortName)
+		assert.True(t, items.ErrForbidden.Has(err), err)
+
+		_, err = service.Create(context.Background(), "task", "description")
+		assert.True(t, items.ErrForbidden.Has(err), err)
+
+		_, err = service.Get(context.Background(), item.ID)
+		assert.True(t, items.ErrForbidden.Has(err), err)
+	})
+
+	t.Run("only admins count items of users", func(t *testing.T) {
+		_, err := service.CountByUser(owner)
+		assert.True(t, items.ErrForbidden.Has(err), err)
+
+		claims, err := auth.GetClaims(owner)
+		require.NoError(t, err)
+		claims.Role = string(users.RoleAdmin)
+		counts, err := service.CountByUser(auth.SetClaims(context.Background(), claims))
+		require.NoError(t, err)
+		assert.Equal(t, map[uuid.UUID]int{claims.UserID: 1}, counts)
+	})
+
+	t.Run("operators list items of user", func(t *testing.T) {
+		claims, err := auth.GetClaims(owner)
+		require.NoError(t, err)
+		list, err := service.ListByUser(context.Background(), claims.UserID, items.SortCreated)
+		require.NoError(t, err)
+		assert.Equal(t, []items.Item{item}, list)
+
+		_, err = service.ListByUser(context.Background(), claims.UserID, "size")
+		assert.True(t, items.ErrValidation.Has(err), err)
+	})
+
+	t.Run("missing item", func(t *testing.T) {
+		_, err := service.Get(owner, uuid.New())
+		assert.True(t, items.ErrNoItem.Has(err), err)
+	})
+
+	t.Run("owner manages item", func(t *testing.T) {
+		require.NoError(t, service.Update(owner, item.ID, "updated", "updated description"))
+		require.NoError(t, service.UpdateStatus(owner, item.ID))
+
+		stored, err := service.Get(owner, item.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "updated", stored.Name)
+		assert.Equal(t, items.StatusInProgress, stored.Status)
+
+		require.NoError(t, service.Delete(owner, item.ID))
+		_, err = service.Get(owner, item.ID)
+		assert.True(t, items.ErrNoItem.Has(err), err)
+	})
+}
+
+func TestServiceTimestamps(t *testing.T) {
+	db := memdb.New()
+	service := items.New(db.Items())
+
+	user := users.User{ID: uuid.New(), Email: uuid.NewString() + "@gmail.com", CreatedAt: time.Now()}
+	require.NoError(t, db.Users().Create(context.Background(), user))
+	ctx := auth.SetClaims(context.Background(), auth.Claims{UserID: user.ID, Email: user.Email})
+
+	// times are kept with millisecond precision, so items are created apart.
+	older, err := service.Create(ctx, "b", "")
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	newer, err := service.Create(ctx, "a", "")
+	require.NoError(t, err)
+	assert.Equal(t, newer.CreatedAt, newer.UpdatedAt)
+	assert.Nil(t, newer.CompletedAt)
+
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, service.Update(ctx, older.ID, "b", "updated"))
+	stored, err := service.Get(ctx, older.ID)
+	require.NoError(t, err)
+	assert.Equal(t, older.CreatedAt, stored.CreatedAt)
+	assert.True(t, stored.UpdatedAt.After(stored.CreatedAt))
+
+	require.NoError(t, service.UpdateStatus(ctx, older.ID))
+	stored, err = service.Get(ctx, older.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.CompletedAt, "item in progress is not completed")
+
+	require.NoError(t, service.UpdateStatus(ctx, older.ID))
+	stored, err = service.Get(ctx, older.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.CompletedAt)
+	assert.Equal(t, stored.UpdatedAt, *stored.CompletedAt)
+
+	for _, test := range []struct {
+		sort  items.Sort
+		order []uuid.UUID
+	}{
+		{items.SortName, []uuid.UUID{newer.ID, older.ID}},
+		{items.SortCreated, []uuid.UUID{newer.ID, older.ID}},
+		{items.SortUpdated, []uuid.UUID{older.ID, newer.ID}},
+		{items.SortCompleted, []uuid.UUID{older.ID, newer.ID}},
+	} {
+		list, err := service.List(ctx, test.sort)
+		require.NoError(t, err)
+
+		var order []uuid.UUID
+		for _, item := range list {
+			order = append(order, item.ID)
+		}
+		assert.Equal(t, test.order, order, test.sort)
+	}
+
+	_, err = service.List(ctx, "priority")
+	assert.True(t, items.ErrValidation.Has(err), err)
+}
+
+// staleItems returns item as it was read before other request changed it.
+type staleItems struct {
+	items.DB
+	item items.Item
+}
+
+func (stale staleItems) Get(ctx context.Context, userID, id uuid.UUID) (items.Item, error) {
+	return stale.item, nil
+}
+
+func TestServiceStatusConflict(t *testing.T) {
+	db := memdb.New()
+	service := items.New(db.Items())
+
+	user := users.User{ID: uuid.New(), Email: uuid.NewString() + "@gmail.com", CreatedAt: time.Now()}
+	require.NoError(t, db.Users().Create(context.Background(), user))
+	ctx := auth.SetClaims(context.Background(), auth.Claims{UserID: user.ID, Email: user.Email})
+
+	item, err := service.Create(ctx, "task", "")
+	require.NoError(t, err)
+	stale := items.New(staleItems{DB: db.Items(), item: item})
+
+	require.NoError(t, service.UpdateStatus(ctx, item.ID))
+	err = stale.UpdateStatus(ctx, item.ID)
+	assert.True(t, items.ErrConflict.Has(err), err)
+
+	stored, err := service.Get(ctx, item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, items.StatusInProgress, stored.Status, "item must be moved only once")
+}
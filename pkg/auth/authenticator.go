@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+var (
+	// ErrNoCredentials indicates that request has no credentials supported by authenticator.
+	ErrNoCredentials = errs.Class("no credentials")
+
+	// ErrUnauthenticated indicates that request credentials are invalid.
+	ErrUnauthenticated = errs.Class("unauthenticated")
+)
+
+// APIKeyHeader is a header which carries api key.
+const APIKeyHeader = "X-API-Key"
+
+// TokenAuthorizer validates token and returns claims it was issued for.
+type TokenAuthorizer interface {
+	Authorize(ctx context.Context, token string) (Claims, error)
+}
+
+// Authenticator authenticates http requests.
+type Authenticator interface {
+	// Authenticate returns claims of request author.
+	// ErrNoCredentials is returned when request has no credentials supported by authenticator.
+	Authenticate(r *http.Request) (Claims, error)
+}
+
+// CookieAuthenticator authenticates requests by token from auth cookie.
+type CookieAuthenticator struct {
+	Cookie *CookieAuth
+	Tokens TokenAuthorizer
+}
+
+// Authenticate implements Authenticator.
+func (authenticator CookieAuthenticator) Authenticate(r *http.Request) (Claims, error) {
+	token, err := authenticator.Cookie.GetToken(r)
+	if err != nil || token == "" {
+		return Claims{}, ErrNoCredentials.New("no auth cookie")
+	}
+
+	return authorize(r.Context(), authenticator.Tokens, token)
+}
+
+// BearerAuthenticator authenticates requests by token from "Authorization: Bearer" header.
+type BearerAuthenticator struct {
+	Tokens TokenAuthorizer
+}
+
+// Authenticate implements Authenticator.
+func (authenticator BearerAuthenticator) Authenticate(r *http.Request) (Claims, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return Claims{}, ErrNoCredentials.New("no authorization header")
+	}
+
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return Claims{}, ErrUnauthenticated.New("unsupported authorization scheme")
+	}
+
+	return authorize(r.Context(), authenticator.Tokens, strings.TrimSpace(header[len(prefix):]))
+}
+
+// APIKeyAuthenticator authenticates requests by key from APIKeyHeader.
+// Only keys starting with Prefix are accepted, so that session tokens are not sent as api keys.
+type APIKeyAuthenticator struct {
+	Keys   TokenAuthorizer
+	Prefix string
+}
+
+// Authenticate implements Authenticator.
+func (authenticator APIKeyAuthenticator) Authenticate(r *http.Request) (Claims, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return Claims{}, ErrNoCredentials.New("no api key")
+	}
+	if !strings.HasPrefix(key, authenticator.Prefix) {
+		return Claims{}, ErrUnauthenticated.New("api key must be personal access token")
+	}
+
+	return authorize(r.Context(), authenticator.Keys, key)
+}
+
+// Chain tries authenticators in order, the first one which finds its credentials decides the result.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (chain Chain) Authenticate(r *http.Request) (Claims, error) {
+	for _, authenticator := range chain {
+		claims, err := authenticator.Authenticate(r)
+		if ErrNoCredentials.Has(err) {
+			continue
+		}
+
+		return claims, err
+	}
+
+	return Claims{}, ErrNoCredentials.New("request has no credentials")
+}
+
+// Middleware authenticates requests and puts claims into request context.
+// Credentials which are missing or expired are renewed by renewer if it is not nil.
+// Requests which fail authentication are passed to unauthenticated and never reach next handler.
+func Middleware(authenticator Authenticator, renewer Renewer, unauthenticated func(w http.ResponseWriter, r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticator.Authenticate(r)
+			if err != nil && renewer != nil {
+				var renewErr error
+				claims, renewErr = renewer.Renew(w, r)
+				if !ErrNoCredentials.Has(renewErr) {
+					err = renewErr
+				}
+			}
+			if err != nil {
+				unauthenticated(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(SetClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// authorize validates token and wraps failures with ErrUnauthenticated.
+func authorize(ctx context.Context, tokens TokenAuthorizer, token string) (Claims, error) {
+	claims, err := tokens.Authorize(ctx, token)
+	if err != nil {
+		return Claims{}, ErrUnauthenticated.Wrap(err)
+	}
+
+	return claims, nil
+}
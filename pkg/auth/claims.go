@@ -1,7 +1,6 @@
 package auth
 
 import (
-	"bytes"
 	"encoding/json"
 	"time"
 
@@ -15,27 +14,108 @@ var ClaimsError = errs.Class("admin auth claims error")
 // Claims represents data signed by server and used for authentication.
 // TODO: add id everywhere.
 type Claims struct {
+	// ID is a unique id of token, it is generated when token is created.
+	ID        uuid.UUID `json:"id"`
 	UserID    uuid.UUID `json:"userId"`
+	SessionID uuid.UUID `json:"sessionId"`
 	Email     string    `json:"email"`
+	// EmailVerified is true when user confirmed ownership of email.
+	EmailVerified bool `json:"emailVerified"`
+	// Role of user, it is refreshed on every request so that role changes apply immediately.
+	Role      string    `json:"role"`
+	IssuedAt  time.Time `json:"issuedAt"`
 	ExpiresAt time.Time `json:"expiresAt"`
+	// TwoFactorRequired is true when user must enroll second factor before doing anything else.
+	// It is set on every request from live data and is never put into token.
+	TwoFactorRequired bool `json:"-"`
+	// Scopes limit what personal access token can do, they are nil for session tokens which can do everything.
+	// Scopes are taken from stored token on every request and are never put into JWT.
+	Scopes []string `json:"-"`
 }
 
-// JSON returns json representation of Claims.
-func (c *Claims) JSON() ([]byte, error) {
-	buffer := bytes.NewBuffer(nil)
+// Session reports whether claims are issued for session rather than for personal access token.
+func (c *Claims) Session() bool {
+	return c.Scopes == nil
+}
+
+// HasScope reports whether claims allow scope, session claims allow every scope.
+func (c *Claims) HasScope(scope string) bool {
+	if c.Session() {
+		return true
+	}
 
-	err := json.NewEncoder(buffer).Encode(c)
-	return buffer.Bytes(), ClaimsError.Wrap(err)
+	for _, granted := range c.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
 }
 
-// FromJSON returns Claims instance, parsed from JSON.
-func FromJSON(data []byte) (*Claims, error) {
-	claims := new(Claims)
+// JWTClaims is a payload of auth token, registered RFC 7519 claims are mapped from Claims
+// and the rest are private claims. Times are seconds since unix epoch.
+type JWTClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ID        string `json:"jti"`
+	SessionID string `json:"sid"`
+	Email     string `json:"email"`
+	// EmailVerified has the name of OpenID Connect standard claim.
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role,omitempty"`
+}
 
-	err := json.NewDecoder(bytes.NewReader(data)).Decode(claims)
-	if err != nil {
+// JWT returns Claims mapped to JWTClaims.
+func (c *Claims) JWT() JWTClaims {
+	claims := JWTClaims{
+		Subject:       c.UserID.String(),
+		IssuedAt:      c.IssuedAt.Unix(),
+		ID:            c.ID.String(),
+		SessionID:     c.SessionID.String(),
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+		Role:          c.Role,
+	}
+	if !c.ExpiresAt.IsZero() {
+		claims.ExpiresAt = c.ExpiresAt.Unix()
+	}
+
+	return claims
+}
+
+// FromJWT returns Claims instance, parsed from JWT payload.
+func FromJWT(payload []byte) (*Claims, error) {
+	var jwt JWTClaims
+	if err := json.Unmarshal(payload, &jwt); err != nil {
 		return nil, ClaimsError.Wrap(err)
 	}
 
+	userID, err := uuid.Parse(jwt.Subject)
+	if err != nil {
+		return nil, ClaimsError.New("invalid sub: %v", err)
+	}
+	id, err := uuid.Parse(jwt.ID)
+	if err != nil {
+		return nil, ClaimsError.New("invalid jti: %v", err)
+	}
+	sessionID, err := uuid.Parse(jwt.SessionID)
+	if err != nil {
+		return nil, ClaimsError.New("invalid sid: %v", err)
+	}
+
+	claims := &Claims{
+		ID:            id,
+		UserID:        userID,
+		SessionID:     sessionID,
+		Email:         jwt.Email,
+		EmailVerified: jwt.EmailVerified,
+		Role:          jwt.Role,
+		IssuedAt:      time.Unix(jwt.IssuedAt, 0).UTC(),
+	}
+	if jwt.ExpiresAt != 0 {
+		claims.ExpiresAt = time.Unix(jwt.ExpiresAt, 0).UTC()
+	}
+
 	return claims, nil
 }
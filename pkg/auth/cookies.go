@@ -33,26 +33,80 @@ func (cookieAuth *CookieAuth) GetToken(r *http.Request) (string, error) {
 	return cookie.Value, nil
 }
 
-// SetTokenCookie sets parametrized token cookie that is not accessible from js.
-func (cookieAuth *CookieAuth) SetTokenCookie(w http.ResponseWriter, token string) {
+// GetRefreshToken retrieves refresh token from request.
+func (cookieAuth *CookieAuth) GetRefreshToken(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(cookieAuth.refreshName())
+	if err != nil {
+		return "", err
+	}
+
+	return cookie.Value, nil
+}
+
+// SetTokens sets access and refresh token cookies that are not accessible from js.
+// Refresh cookie is kept after browser is closed only when tokens.RememberMe is set.
+func (cookieAuth *CookieAuth) SetTokens(w http.ResponseWriter, tokens Tokens) {
+	cookieAuth.setCookie(w, cookieAuth.settings.Name, tokens.AccessToken, tokens.AccessExpiresAt)
+
+	var refreshExpires time.Time
+	if tokens.RememberMe {
+		refreshExpires = tokens.RefreshExpiresAt
+	}
+	cookieAuth.setCookie(w, cookieAuth.refreshName(), tokens.RefreshToken, refreshExpires)
+}
+
+// RemoveTokenCookie removes auth cookies that are not accessible from js.
+func (cookieAuth *CookieAuth) RemoveTokenCookie(w http.ResponseWriter) {
+	cookieAuth.setCookie(w, cookieAuth.settings.Name, "", time.Unix(0, 0))
+	cookieAuth.setCookie(w, cookieAuth.refreshName(), "", time.Unix(0, 0))
+}
+
+// SetLoginState sets cookie which keeps pending login at external provider until provider sends user back.
+// It is SameSite lax, since strict cookies are not sent when provider redirects back to the app.
+func (cookieAuth *CookieAuth) SetLoginState(w http.ResponseWriter, value string, expires time.Time) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     cookieAuth.settings.Name,
-		Value:    token,
+		Name:     cookieAuth.loginStateName(),
+		Value:    value,
 		Path:     cookieAuth.settings.Path,
-		Expires:  time.Now().Add(time.Hour * 24),
+		Expires:  expires,
 		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
 
-// RemoveTokenCookie removes auth cookie that is not accessible from js.
-func (cookieAuth *CookieAuth) RemoveTokenCookie(w http.ResponseWriter) {
+// GetLoginState retrieves pending login at external provider from request.
+func (cookieAuth *CookieAuth) GetLoginState(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(cookieAuth.loginStateName())
+	if err != nil {
+		return "", err
+	}
+
+	return cookie.Value, nil
+}
+
+// RemoveLoginState removes pending login cookie, so that it is used once.
+func (cookieAuth *CookieAuth) RemoveLoginState(w http.ResponseWriter) {
+	cookieAuth.SetLoginState(w, "", time.Unix(0, 0))
+}
+
+// setCookie sets cookie which is removed when browser is closed if expires is zero.
+func (cookieAuth *CookieAuth) setCookie(w http.ResponseWriter, name, value string, expires time.Time) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     cookieAuth.settings.Name,
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Unix(0, 0),
+		Name:     name,
+		Value:    value,
+		Path:     cookieAuth.settings.Path,
+		Expires:  expires,
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
 	})
 }
+
+// refreshName returns name of refresh token cookie.
+func (cookieAuth *CookieAuth) refreshName() string {
+	return cookieAuth.settings.Name + "_refresh"
+}
+
+// loginStateName returns name of pending login cookie.
+func (cookieAuth *CookieAuth) loginStateName() string {
+	return cookieAuth.settings.Name + "_login"
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"sort"
+)
+
+// JSONWebKey is a RFC 7517 public key which verifies tokens.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JSONWebKeySet is a RFC 7517 set of public keys.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns public keys of keyring sorted by id, HS256 secrets are shared and never published.
+func (keyring *Keyring) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if keyring == nil {
+		return set
+	}
+
+	for id, key := range keyring.keys {
+		if key.algorithm != AlgorithmEdDSA {
+			continue
+		}
+
+		set.Keys = append(set.Keys, JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.private.Public().(ed25519.PublicKey)),
+			KeyID:     id,
+			Algorithm: AlgorithmEdDSA,
+			Use:       "sig",
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+
+	return set
+}
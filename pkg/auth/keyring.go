@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zeebo/errs"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrKeyring is an error class for keyring errors.
+var ErrKeyring = errs.Class("keyring error")
+
+// MinKeyLength is the minimal allowed length of token signing secret.
+const MinKeyLength = 16
+
+const (
+	// AlgorithmHS256 signs tokens with HMAC SHA-256 of shared secret.
+	AlgorithmHS256 = "HS256"
+	// AlgorithmEdDSA signs tokens with Ed25519 private key, public key is published in JWKS.
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is a token signing secret with id which is put into tokens signed by it.
+// Secret of EdDSA key is base64url encoded Ed25519 seed, empty algorithm means HS256.
+type SigningKey struct {
+	ID        string `yaml:"id"`
+	Algorithm string `yaml:"algorithm,omitempty"`
+	Secret    string `yaml:"secret"`
+}
+
+// KeyringConfig lists signing keys, new tokens are signed with the active one
+// and the rest are kept to verify tokens signed before rotation.
+type KeyringConfig struct {
+	Active string       `yaml:"active"`
+	Keys   []SigningKey `yaml:"keys"`
+}
+
+// Keyring holds keys which sign and verify tokens.
+type Keyring struct {
+	active string
+	keys   map[string]keyringKey
+}
+
+// keyringKey is a parsed signing key.
+type keyringKey struct {
+	algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+}
+
+// NewKeyring validates config and returns keyring.
+func NewKeyring(config KeyringConfig) (*Keyring, error) {
+	if len(config.Keys) == 0 {
+		return nil, ErrKeyring.New("no keys")
+	}
+
+	keyring := &Keyring{active: config.Active, keys: make(map[string]keyringKey, len(config.Keys))}
+	for _, key := range config.Keys {
+		if key.ID == "" || strings.ContainsAny(key.ID, ".\"") {
+			return nil, ErrKeyring.New("invalid key id %q", key.ID)
+		}
+		if _, ok := keyring.keys[key.ID]; ok {
+			return nil, ErrKeyring.New("duplicate key id %q", key.ID)
+		}
+
+		parsed, err := parseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[key.ID] = parsed
+	}
+
+	if _, ok := keyring.keys[config.Active]; !ok {
+		return nil, ErrKeyring.New("active key %q is not in keyring", config.Active)
+	}
+
+	return keyring, nil
+}
+
+// parseKey validates secret of key according to its algorithm.
+func parseKey(key SigningKey) (keyringKey, error) {
+	switch key.Algorithm {
+	case "", AlgorithmHS256:
+		if len(key.Secret) < MinKeyLength {
+			return keyringKey{}, ErrKeyring.New("secret of key %q must be at least %d bytes long", key.ID, MinKeyLength)
+		}
+
+		return keyringKey{algorithm: AlgorithmHS256, secret: []byte(key.Secret)}, nil
+	case AlgorithmEdDSA:
+		seed, err := base64.RawURLEncoding.DecodeString(key.Secret)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return keyringKey{}, ErrKeyring.New("secret of key %q must be base64url encoded %d bytes seed", key.ID, ed25519.SeedSize)
+		}
+
+		return keyringKey{algorithm: AlgorithmEdDSA, private: ed25519.NewKeyFromSeed(seed)}, nil
+	default:
+		return keyringKey{}, ErrKeyring.New("unsupported algorithm %q of key %q", key.Algorithm, key.ID)
+	}
+}
+
+// sign returns signature of data.
+func (key keyringKey) sign(data []byte) []byte {
+	if key.algorithm == AlgorithmEdDSA {
+		return ed25519.Sign(key.private, data)
+	}
+
+	mac := hmac.New(sha256.New, key.secret)
+	_, _ = mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// verify checks signature of data.
+func (key keyringKey) verify(data, signature []byte) bool {
+	if key.algorithm == AlgorithmEdDSA {
+		return ed25519.Verify(key.private.Public().(ed25519.PublicKey), data, signature)
+	}
+
+	return hmac.Equal(key.sign(data), signature)
+}
+
+// GenerateKey returns key of algorithm with random secret, key id starts with creation date to ease rotation audits.
+func GenerateKey(algorithm string) (SigningKey, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return SigningKey{}, ErrKeyring.Wrap(err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return SigningKey{}, ErrKeyring.Wrap(err)
+	}
+
+	key := SigningKey{
+		ID:        time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(id),
+		Algorithm: algorithm,
+		Secret:    base64.RawURLEncoding.EncodeToString(secret),
+	}
+	if _, err := parseKey(key); err != nil {
+		return SigningKey{}, err
+	}
+
+	return key, nil
+}
+
+// Rotate adds new key and makes it active, previous keys keep verifying tokens until retired.
+func (config KeyringConfig) Rotate(algorithm string) (KeyringConfig, SigningKey, error) {
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		return config, SigningKey{}, err
+	}
+
+	config.Keys = append(append([]SigningKey{}, config.Keys...), key)
+	config.Active = key.ID
+
+	return config, key, nil
+}
+
+// Retire removes key, tokens signed by it are not accepted anymore. Active key can not be retired.
+func (config KeyringConfig) Retire(id string) (KeyringConfig, error) {
+	if id == config.Active {
+		return config, ErrKeyring.New("key %q is active, rotate keys first", id)
+	}
+
+	keys := make([]SigningKey, 0, len(config.Keys))
+	for _, key := range config.Keys {
+		if key.ID != id {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == len(config.Keys) {
+		return config, ErrKeyring.New("key %q is not in keyring", id)
+	}
+	config.Keys = keys
+
+	return config, nil
+}
+
+// LoadKeyFile reads keyring config from yaml file.
+func LoadKeyFile(path string) (KeyringConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return KeyringConfig{}, ErrKeyring.Wrap(err)
+	}
+
+	var config KeyringConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&config); err != nil {
+		return KeyringConfig{}, ErrKeyring.New("%s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// SaveKeyFile writes keyring config to yaml file readable only by its owner,
+// file is replaced atomically so running servers never read partial file.
+func SaveKeyFile(path string, config KeyringConfig) (err error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return ErrKeyring.Wrap(err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return ErrKeyring.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	if err = file.Chmod(0600); err != nil {
+		return ErrKeyring.Wrap(errs.Combine(err, file.Close()))
+	}
+	if _, err = file.Write(data); err != nil {
+		return ErrKeyring.Wrap(errs.Combine(err, file.Close()))
+	}
+	if err = file.Close(); err != nil {
+		return ErrKeyring.Wrap(err)
+	}
+
+	return ErrKeyring.Wrap(os.Rename(file.Name(), path))
+}
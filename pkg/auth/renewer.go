@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Tokens is a pair of short-lived access token and refresh token which renews it.
+type Tokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	// RememberMe keeps refresh cookie after browser is closed.
+	RememberMe bool
+	// SecondFactorToken is set instead of other tokens when password is correct, but user
+	// has to enter second factor code, it is exchanged for other tokens together with the code.
+	SecondFactorToken     string
+	SecondFactorExpiresAt time.Time
+}
+
+// TokenRefresher exchanges refresh token for new tokens.
+type TokenRefresher interface {
+	TokenAuthorizer
+	Refresh(ctx context.Context, refreshToken string) (Tokens, error)
+}
+
+// Renewer renews expired credentials of request, new credentials are written to response.
+type Renewer interface {
+	// Renew returns claims of renewed credentials.
+	// ErrNoCredentials is returned when request has nothing to renew credentials with.
+	Renew(w http.ResponseWriter, r *http.Request) (Claims, error)
+}
+
+// CookieRenewer renews access token cookie with refresh token cookie.
+type CookieRenewer struct {
+	Cookie *CookieAuth
+	Tokens TokenRefresher
+}
+
+// Renew implements Renewer.
+func (renewer CookieRenewer) Renew(w http.ResponseWriter, r *http.Request) (Claims, error) {
+	refreshToken, err := renewer.Cookie.GetRefreshToken(r)
+	if err != nil || refreshToken == "" {
+		return Claims{}, ErrNoCredentials.New("no refresh cookie")
+	}
+
+	ctx := r.Context()
+	tokens, err := renewer.Tokens.Refresh(ctx, refreshToken)
+	if err != nil {
+		renewer.Cookie.RemoveTokenCookie(w)
+		return Claims{}, ErrUnauthenticated.Wrap(err)
+	}
+	renewer.Cookie.SetTokens(w, tokens)
+
+	return authorize(ctx, renewer.Tokens, tokens.AccessToken)
+}
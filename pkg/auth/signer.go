@@ -2,46 +2,66 @@ package auth
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"encoding/json"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/zeebo/errs"
 )
 
 // TokenSignerError is an error class for TokenSigner errors.
 var TokenSignerError = errs.Class("auth token signer error")
 
-// TokenSigner creates signature for provided auth Token. Its hmac256 based TokenSigner.
+// TokenSigner creates and verifies signatures of JWT auth tokens with HS256 or EdDSA keys.
+// Tokens are signed with the active key of keyring and verified with the key named in their header.
 type TokenSigner struct {
-	Secret []byte
+	Keyring *Keyring
 }
 
-// SignToken signs token with some secret key.
+// SignToken signs token with the active key.
 func (a *TokenSigner) SignToken(token *Token) error {
-	mac := hmac.New(sha256.New, a.Secret)
+	key := a.Keyring.keys[a.Keyring.active]
+	token.Algorithm, token.KeyID, token.header = key.algorithm, a.Keyring.active, ""
+	token.Signature = key.sign([]byte(token.signed()))
 
-	encoded := base64.URLEncoding.EncodeToString(token.Payload)
+	return nil
+}
 
-	_, err := mac.Write([]byte(encoded))
-	if err != nil {
-		return TokenSignerError.Wrap(err)
+// VerifyToken checks token signature with the key it was signed with.
+// Algorithm of token must match the key, so HS256 tokens can not be forged with public EdDSA keys.
+func (a *TokenSigner) VerifyToken(token Token) error {
+	key, ok := a.Keyring.keys[token.KeyID]
+	if !ok {
+		return TokenSignerError.New("unknown signing key %q", token.KeyID)
 	}
 
-	token.Signature = mac.Sum(nil)
+	if token.Algorithm != key.algorithm {
+		return TokenSignerError.New("algorithm %q does not match key %q", token.Algorithm, token.KeyID)
+	}
+
+	if !key.verify([]byte(token.signed()), token.Signature) {
+		return TokenSignerError.New("incorrect signature")
+	}
 
 	return nil
 }
 
-// CreateToken creates string representation.
+// CreateToken creates string representation of JWT with claims.
+// ID and IssuedAt of claims are set when empty.
 func (a *TokenSigner) CreateToken(ctx context.Context, claims *Claims) (string, error) {
-	json, err := claims.JSON()
+	if claims.ID == uuid.Nil {
+		claims.ID = uuid.New()
+	}
+	if claims.IssuedAt.IsZero() {
+		claims.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	payload, err := json.Marshal(claims.JWT())
 	if err != nil {
-		// TODO: wrap
 		return "", TokenSignerError.Wrap(err)
 	}
 
-	token := Token{Payload: json}
+	token := Token{Payload: payload}
 	err = a.SignToken(&token)
 	if err != nil {
 		return "", TokenSignerError.Wrap(err)
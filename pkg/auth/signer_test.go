@@ -0,0 +1,168 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/pkg/auth"
+)
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	initial, err := auth.GenerateKey(auth.AlgorithmHS256)
+	require.NoError(t, err)
+	config := auth.KeyringConfig{Active: initial.ID, Keys: []auth.SigningKey{initial}}
+
+	signer := func(config auth.KeyringConfig) *auth.TokenSigner {
+		keyring, err := auth.NewKeyring(config)
+		require.NoError(t, err)
+		return &auth.TokenSigner{Keyring: keyring}
+	}
+	verify := func(signer *auth.TokenSigner, token string) error {
+		parsed, err := auth.FromBase64URLString(token)
+		require.NoError(t, err)
+		return signer.VerifyToken(parsed)
+	}
+
+	oldToken, err := signer(config).CreateToken(ctx, &auth.Claims{UserID: uuid.New()})
+	require.NoError(t, err)
+
+	rotated, key, err := config.Rotate(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, rotated.Active)
+	assert.Len(t, config.Keys, 1, "rotation must not change original config")
+
+	// tokens signed before rotation stay valid, new ones are signed with the new key.
+	newToken, err := signer(rotated).CreateToken(ctx, &auth.Claims{UserID: uuid.New()})
+	require.NoError(t, err)
+	parsed, err := auth.FromBase64URLString(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, parsed.KeyID)
+
+	assert.NoError(t, verify(signer(rotated), oldToken))
+	assert.NoError(t, verify(signer(rotated), newToken))
+
+	// token can not be moved to other key by changing its header.
+	parsed.KeyID = initial.ID
+	assert.Error(t, signer(rotated).VerifyToken(parsed))
+
+	_, err = rotated.Retire(rotated.Active)
+	assert.True(t, auth.ErrKeyring.Has(err), err)
+
+	retired, err := rotated.Retire(initial.ID)
+	require.NoError(t, err)
+	assert.Error(t, verify(signer(retired), oldToken))
+	assert.NoError(t, verify(signer(retired), newToken))
+
+	// key file keeps keys between restarts and is private.
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, auth.SaveKeyFile(path, rotated))
+	loaded, err := auth.LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, rotated, loaded)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	secret := "0123456789abcdef"
+
+	for name, config := range map[string]auth.KeyringConfig{
+		"no keys":        {},
+		"missing active": {Active: "b", Keys: []auth.SigningKey{{ID: "a", Secret: secret}}},
+		"short secret":   {Active: "a", Keys: []auth.SigningKey{{ID: "a", Secret: "short"}}},
+		"duplicate id":   {Active: "a", Keys: []auth.SigningKey{{ID: "a", Secret: secret}, {ID: "a", Secret: secret}}},
+		"invalid id":     {Active: "a.b", Keys: []auth.SigningKey{{ID: "a.b", Secret: secret}}},
+		"invalid seed":   {Active: "a", Keys: []auth.SigningKey{{ID: "a", Algorithm: auth.AlgorithmEdDSA, Secret: secret}}},
+		"unknown alg":    {Active: "a", Keys: []auth.SigningKey{{ID: "a", Algorithm: "none", Secret: secret}}},
+	} {
+		_, err := auth.NewKeyring(config)
+		assert.True(t, auth.ErrKeyring.Has(err), name)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	hs256, err := auth.GenerateKey(auth.AlgorithmHS256)
+	require.NoError(t, err)
+	eddsa, err := auth.GenerateKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+
+	keyring, err := auth.NewKeyring(auth.KeyringConfig{Active: eddsa.ID, Keys: []auth.SigningKey{hs256, eddsa}})
+	require.NoError(t, err)
+	signer := auth.TokenSigner{Keyring: keyring}
+
+	claims := auth.Claims{
+		UserID:    uuid.New(),
+		SessionID: uuid.New(),
+		Email:     "user@gmail.com",
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	token, err := signer.CreateToken(context.Background(), &claims)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	decode := func(part string, value interface{}) {
+		data, err := base64.RawURLEncoding.DecodeString(part)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, value))
+	}
+
+	var header map[string]string
+	decode(parts[0], &header)
+	assert.Equal(t, map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": eddsa.ID}, header)
+
+	var payload map[string]interface{}
+	decode(parts[1], &payload)
+	assert.Equal(t, claims.UserID.String(), payload["sub"])
+	assert.Equal(t, claims.ID.String(), payload["jti"])
+	assert.EqualValues(t, claims.ExpiresAt.Unix(), payload["exp"])
+	assert.EqualValues(t, claims.IssuedAt.Unix(), payload["iat"])
+
+	parsed, err := auth.FromBase64URLString(token)
+	require.NoError(t, err)
+	require.NoError(t, signer.VerifyToken(parsed))
+	restored, err := auth.FromJWT(parsed.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, claims.UserID, restored.UserID)
+	assert.Equal(t, claims.SessionID, restored.SessionID)
+	assert.True(t, claims.ExpiresAt.Equal(restored.ExpiresAt))
+
+	t.Run("jwks verifies token without secret", func(t *testing.T) {
+		jwks := keyring.JWKS()
+		require.Len(t, jwks.Keys, 1, "hs256 secret must not be published")
+		assert.Equal(t, eddsa.ID, jwks.Keys[0].KeyID)
+
+		public, err := base64.RawURLEncoding.DecodeString(jwks.Keys[0].X)
+		require.NoError(t, err)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(public, []byte(parts[0]+"."+parts[1]), signature))
+	})
+
+	t.Run("algorithm must match key", func(t *testing.T) {
+		forged := parsed
+		forged.Algorithm = auth.AlgorithmHS256
+		assert.Error(t, signer.VerifyToken(forged))
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		tampered, err := auth.FromBase64URLString(parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x"}`)) + "." + parts[2])
+		require.NoError(t, err)
+		assert.Error(t, signer.VerifyToken(tampered))
+	})
+}
@@ -1,9 +1,8 @@
 package auth
 
 import (
-	"bytes"
 	"encoding/base64"
-	"io/ioutil"
+	"encoding/json"
 	"strings"
 
 	"github.com/zeebo/errs"
@@ -12,42 +11,74 @@ import (
 // TokenError is an error class for auth Token errors.
 var TokenError = errs.Class("admin auth token error")
 
-// Token represents authentication data structure.
+// Token represents authentication data structure, it is encoded as RFC 7519 JWT.
 type Token struct {
+	// Algorithm is a JWS algorithm of signature.
+	Algorithm string
+	// KeyID is an id of key which signed token.
+	KeyID     string
 	Payload   []byte
 	Signature []byte
+
+	// header is an encoded header of parsed token, signature covers it as it was received.
+	header string
 }
 
-// String returns base64URLEncoded data joined with.
-func (t *Token) String() string {
-	payload := base64.URLEncoding.EncodeToString(t.Payload)
-	signature := base64.URLEncoding.EncodeToString(t.Signature)
+// tokenHeader is a JOSE header of token.
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// signed returns base64URLEncoded header and payload joined with dot, it is the part covered by signature.
+func (t *Token) signed() string {
+	header := t.header
+	if header == "" {
+		data, _ := json.Marshal(tokenHeader{Algorithm: t.Algorithm, Type: "JWT", KeyID: t.KeyID})
+		header = base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	return header + "." + base64.RawURLEncoding.EncodeToString(t.Payload)
+}
 
-	return strings.Join([]string{payload, signature}, ".")
+// String returns base64URLEncoded header, payload and signature joined with dots.
+func (t *Token) String() string {
+	return t.signed() + "." + base64.RawURLEncoding.EncodeToString(t.Signature)
 }
 
 // FromBase64URLString creates Token instance from base64URLEncoded string representation.
 func FromBase64URLString(token string) (Token, error) {
-	i := strings.Index(token, ".")
-	if i < 0 {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
 		return Token{}, TokenError.New("invalid token format")
 	}
 
-	payload := token[:i]
-	signature := token[i+1:]
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Token{}, TokenError.New("decoding token's header failed: %s", err)
+	}
 
-	payloadDecoder := base64.NewDecoder(base64.URLEncoding, bytes.NewReader([]byte(payload)))
-	signatureDecoder := base64.NewDecoder(base64.URLEncoding, bytes.NewReader([]byte(signature)))
+	var header tokenHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return Token{}, TokenError.New("invalid token header: %s", err)
+	}
 
-	payloadBytes, err := ioutil.ReadAll(payloadDecoder)
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return Token{}, TokenError.New("decoding token's signature failed: %s", err)
+		return Token{}, TokenError.New("decoding token's body failed: %s", err)
 	}
 
-	signatureBytes, err := ioutil.ReadAll(signatureDecoder)
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(parts[2])
 	if err != nil {
-		return Token{}, TokenError.New("decoding token's body failed: %s", err)
+		return Token{}, TokenError.New("decoding token's signature failed: %s", err)
 	}
 
-	return Token{Payload: payloadBytes, Signature: signatureBytes}, nil
+	return Token{
+		Algorithm: header.Algorithm,
+		KeyID:     header.KeyID,
+		Payload:   payloadBytes,
+		Signature: signatureBytes,
+		header:    parts[0],
+	}, nil
 }
@@ -0,0 +1,97 @@
+// Package mail sends plain text emails through smtp or writes them to a file or stdout for local use.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// Error is an error class for mail errors.
+var Error = errs.Class("mail error")
+
+const (
+	// BackendSMTP sends messages through smtp server.
+	BackendSMTP = "smtp"
+	// BackendFile appends messages to a file.
+	BackendFile = "file"
+	// BackendStdout prints messages to stdout.
+	BackendStdout = "stdout"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Config contains configuration of mailer.
+type Config struct {
+	// Backend is smtp, file or stdout.
+	Backend string `yaml:"backend"`
+	// From is an address messages are sent from.
+	From string `yaml:"from"`
+
+	SMTP SMTPConfig `yaml:"smtp"`
+
+	// File is a path messages are appended to by file backend.
+	File string `yaml:"file"`
+}
+
+// New returns mailer of configured backend.
+func New(config Config) (Mailer, error) {
+	if config.From == "" || !strings.Contains(config.From, "@") {
+		return nil, Error.New("invalid from address %q", config.From)
+	}
+
+	switch config.Backend {
+	case BackendSMTP:
+		if config.SMTP.Address == "" {
+			return nil, Error.New("smtp address is required")
+		}
+		return &SMTP{from: config.From, config: config.SMTP}, nil
+	case BackendFile:
+		if config.File == "" {
+			return nil, Error.New("file is required")
+		}
+		return &File{from: config.From, path: config.File}, nil
+	case BackendStdout:
+		return NewWriter(config.From, nil), nil
+	default:
+		return nil, Error.New("unsupported backend %q", config.Backend)
+	}
+}
+
+// format returns message with headers, lines are separated with CRLF as smtp requires.
+// Headers with line breaks are rejected so that message can not smuggle extra headers.
+func format(from string, message Message, now time.Time) ([]byte, error) {
+	for _, header := range []string{from, message.To, message.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, Error.New("header contains line break")
+		}
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("\r\n")
+	buffer.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	buffer.WriteString("\r\n")
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,61 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/pkg/mail"
+)
+
+func TestWriter(t *testing.T) {
+	ctx := context.Background()
+	var buffer bytes.Buffer
+	mailer := mail.NewWriter("todo@localhost", &buffer)
+
+	require.NoError(t, mailer.Send(ctx, mail.Message{To: "user@gmail.com", Subject: "Привет", Body: "first\nsecond"}))
+	message := buffer.String()
+	assert.True(t, strings.HasPrefix(message, "From: todo@localhost\r\nTo: user@gmail.com\r\nSubject: =?utf-8?q?"), message)
+	assert.Contains(t, message, "\r\n\r\nfirst\r\nsecond\r\n")
+
+	// line break in header would let message add its own headers.
+	buffer.Reset()
+	err := mailer.Send(ctx, mail.Message{To: "user@gmail.com\r\nBcc: victim@gmail.com", Subject: "subject"})
+	assert.True(t, mail.Error.Has(err), err)
+	err = mailer.Send(ctx, mail.Message{To: "user@gmail.com", Subject: "subject\nBcc: victim@gmail.com"})
+	assert.True(t, mail.Error.Has(err), err)
+	assert.Zero(t, buffer.Len())
+}
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mail.txt")
+
+	mailer, err := mail.New(mail.Config{Backend: mail.BackendFile, From: "todo@localhost", File: path})
+	require.NoError(t, err)
+	require.NoError(t, mailer.Send(ctx, mail.Message{To: "first@gmail.com", Subject: "first"}))
+	require.NoError(t, mailer.Send(ctx, mail.Message{To: "second@gmail.com", Subject: "second"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "To: first@gmail.com\r\n")
+	assert.Contains(t, string(data), "To: second@gmail.com\r\n")
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	for _, config := range []mail.Config{
+		{Backend: mail.BackendStdout},
+		{Backend: "pigeon", From: "todo@localhost"},
+		{Backend: mail.BackendSMTP, From: "todo@localhost"},
+		{Backend: mail.BackendFile, From: "todo@localhost"},
+	} {
+		_, err := mail.New(config)
+		assert.True(t, mail.Error.Has(err), "%+v: %v", config, err)
+	}
+}
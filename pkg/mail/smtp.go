@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// SMTPConfig contains address and credentials of smtp server.
+type SMTPConfig struct {
+	// Address is host:port of smtp server.
+	Address  string `yaml:"address"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// SMTP sends messages through smtp server, connection is upgraded with STARTTLS when server supports it.
+type SMTP struct {
+	from   string
+	config SMTPConfig
+}
+
+// Send implements Mailer.
+func (mailer *SMTP) Send(ctx context.Context, message Message) (err error) {
+	data, err := format(mailer.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(mailer.config.Address)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", mailer.config.Address)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return Error.Wrap(errs.Combine(err, conn.Close()))
+		}
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return Error.Wrap(errs.Combine(err, conn.Close()))
+	}
+	// successful Quit closes connection itself.
+	defer func() {
+		if err != nil {
+			_ = client.Close()
+		}
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return Error.Wrap(err)
+		}
+	}
+	if mailer.config.Username != "" {
+		// smtp.PlainAuth refuses to send credentials over unencrypted connection to remote host.
+		if err = client.Auth(smtp.PlainAuth("", mailer.config.Username, mailer.config.Password, host)); err != nil {
+			return Error.Wrap(err)
+		}
+	}
+
+	if err = client.Mail(mailer.from); err != nil {
+		return Error.Wrap(err)
+	}
+	if err = client.Rcpt(message.To); err != nil {
+		return Error.Wrap(err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if _, err = writer.Write(data); err != nil {
+		return Error.Wrap(errs.Combine(err, writer.Close()))
+	}
+	if err = writer.Close(); err != nil {
+		return Error.Wrap(err)
+	}
+
+	return Error.Wrap(client.Quit())
+}
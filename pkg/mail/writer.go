@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// Writer writes messages to io.Writer, messages are separated with blank line.
+type Writer struct {
+	from string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns mailer which writes messages to w, nil w means stdout.
+func NewWriter(from string, w io.Writer) *Writer {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &Writer{from: from, w: w}
+}
+
+// Send implements Mailer.
+func (mailer *Writer) Send(ctx context.Context, message Message) error {
+	data, err := format(mailer.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	_, err = mailer.w.Write(append(data, '\r', '\n'))
+	return Error.Wrap(err)
+}
+
+// File appends messages to file, file is created when it does not exist.
+type File struct {
+	from string
+	path string
+
+	mu sync.Mutex
+}
+
+// Send implements Mailer.
+func (mailer *File) Send(ctx context.Context, message Message) (err error) {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	file, err := os.OpenFile(mailer.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, Error.Wrap(file.Close()))
+	}()
+
+	return NewWriter(mailer.from, file).Send(ctx, message)
+}
@@ -0,0 +1,301 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// AlgorithmRS256 is RSASSA-PKCS1-v1_5 with SHA-256, every provider supports it.
+	AlgorithmRS256 = "RS256"
+	// AlgorithmES256 is ECDSA P-256 with SHA-256.
+	AlgorithmES256 = "ES256"
+	// AlgorithmEdDSA is Ed25519.
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// IDToken contains verified claims of ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	ExpiresAt     time.Time
+}
+
+// idTokenClaims is a payload of ID token.
+type idTokenClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	ExpiresAt       int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   jsonBool `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+// audience is aud claim, it is either a string or an array of strings.
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = audience{single}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(aud))
+}
+
+// jsonBool is a boolean which some providers send as string.
+type jsonBool bool
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *jsonBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	case "false", `"false"`, "null":
+		*b = false
+	default:
+		return ErrInvalidToken.New("invalid boolean %s", data)
+	}
+
+	return nil
+}
+
+// tokenHeader is a JOSE header of ID token.
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verify checks signature of raw ID token with keys of provider, its issuer, audience, expiration and nonce.
+func (client *Client) Verify(ctx context.Context, rawIDToken, nonce string, now time.Time) (IDToken, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return IDToken{}, ErrInvalidToken.New("invalid token format")
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return IDToken{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return IDToken{}, ErrInvalidToken.New("invalid signature encoding")
+	}
+
+	key, err := client.key(ctx, header.KeyID, header.Algorithm, now)
+	if err != nil {
+		return IDToken{}, err
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return IDToken{}, ErrInvalidToken.New("incorrect signature")
+	}
+
+	var claims idTokenClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return IDToken{}, err
+	}
+
+	switch {
+	case claims.Issuer != client.metadata.Issuer:
+		return IDToken{}, ErrInvalidToken.New("unexpected issuer %q", claims.Issuer)
+	case !claims.Audience.contains(client.config.ClientID):
+		return IDToken{}, ErrInvalidToken.New("token is not issued to client")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != client.config.ClientID:
+		return IDToken{}, ErrInvalidToken.New("token is authorized to other party %q", claims.AuthorizedParty)
+	case claims.Subject == "":
+		return IDToken{}, ErrInvalidToken.New("subject is missing")
+	case !now.Before(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)):
+		return IDToken{}, ErrInvalidToken.New("token is expired")
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return IDToken{}, ErrInvalidToken.New("token is issued in the future")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return IDToken{}, ErrInvalidToken.New("nonce does not match")
+	}
+
+	return IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		ExpiresAt:     time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// contains reports whether audience contains client id.
+func (aud audience) contains(clientID string) bool {
+	for _, value := range aud {
+		if value == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSegment decodes base64url encoded json segment of token.
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidToken.New("invalid segment encoding")
+	}
+	if err = json.Unmarshal(data, value); err != nil {
+		return ErrInvalidToken.New("invalid segment: %v", err)
+	}
+	return nil
+}
+
+// JSONWebKey is RFC 7517 public key of provider.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	// N and E are modulus and exponent of RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve, X and Y are coordinates of EC and OKP keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is RFC 7517 set of public keys.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// publicKey is a parsed signing key of provider.
+type publicKey struct {
+	algorithm string
+	rsa       *rsa.PublicKey
+	ecdsa     *ecdsa.PublicKey
+	ed25519   ed25519.PublicKey
+}
+
+// key returns key with id which verifies tokens of algorithm.
+// Keys are fetched again when id is unknown, so keys rotated by provider are picked up.
+func (client *Client) key(ctx context.Context, id, algorithm string, now time.Time) (publicKey, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	key, ok := client.findKey(id, algorithm)
+	if !ok && now.Sub(client.keysFetchedAt) > keysRefreshInterval {
+		var set JSONWebKeySet
+		if err := client.getJSON(ctx, client.metadata.JWKSURI, &set); err != nil {
+			return publicKey{}, err
+		}
+
+		client.keys, client.keysFetchedAt = parseKeys(set), now
+		key, ok = client.findKey(id, algorithm)
+	}
+	if !ok {
+		return publicKey{}, ErrInvalidToken.New("no %s key %q", algorithm, id)
+	}
+
+	return key, nil
+}
+
+// findKey returns key with id and algorithm, the only key of algorithm is used when token names no key.
+func (client *Client) findKey(id, algorithm string) (publicKey, bool) {
+	if id != "" {
+		key, ok := client.keys[id]
+		return key, ok && key.algorithm == algorithm
+	}
+
+	var found []publicKey
+	for _, key := range client.keys {
+		if key.algorithm == algorithm {
+			found = append(found, key)
+		}
+	}
+	if len(found) != 1 {
+		return publicKey{}, false
+	}
+	return found[0], true
+}
+
+// parseKeys returns signing keys of set by id, unsupported keys are skipped.
+func parseKeys(set JSONWebKeySet) map[string]publicKey {
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, ok := parseKey(jwk); ok {
+			keys[jwk.KeyID] = key
+		}
+	}
+
+	return keys
+}
+
+// parseKey parses RSA, P-256 or Ed25519 public key.
+func parseKey(jwk JSONWebKey) (publicKey, bool) {
+	decode := func(value string) []byte {
+		data, _ := base64.RawURLEncoding.DecodeString(value)
+		return data
+	}
+
+	switch {
+	case jwk.KeyType == "RSA" && (jwk.Algorithm == "" || jwk.Algorithm == AlgorithmRS256):
+		n, e := new(big.Int).SetBytes(decode(jwk.N)), new(big.Int).SetBytes(decode(jwk.E))
+		if n.BitLen() < 2048 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return publicKey{}, false
+		}
+		return publicKey{algorithm: AlgorithmRS256, rsa: &rsa.PublicKey{N: n, E: int(e.Int64())}}, true
+	case jwk.KeyType == "EC" && jwk.Curve == "P-256":
+		x, y := new(big.Int).SetBytes(decode(jwk.X)), new(big.Int).SetBytes(decode(jwk.Y))
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return publicKey{}, false
+		}
+		return publicKey{algorithm: AlgorithmES256, ecdsa: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, true
+	case jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+		x := decode(jwk.X)
+		if len(x) != ed25519.PublicKeySize {
+			return publicKey{}, false
+		}
+		return publicKey{algorithm: AlgorithmEdDSA, ed25519: ed25519.PublicKey(x)}, true
+	default:
+		return publicKey{}, false
+	}
+}
+
+// verify checks signature of data.
+func (key publicKey) verify(data, signature []byte) bool {
+	hash := sha256.Sum256(data)
+
+	switch key.algorithm {
+	case AlgorithmRS256:
+		return rsa.VerifyPKCS1v15(key.rsa, crypto.SHA256, hash[:], signature) == nil
+	case AlgorithmES256:
+		if len(signature) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key.ecdsa, hash[:], r, s)
+	case AlgorithmEdDSA:
+		return ed25519.Verify(key.ed25519, data, signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,204 @@
+// Package oidc is a minimal OpenID Connect relying party: provider discovery, authorization code flow
+// with PKCE and validation of ID tokens signed with RS256, ES256 or EdDSA keys of the provider.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+var (
+	// Error is an error class for errors of provider communication.
+	Error = errs.Class("oidc error")
+
+	// ErrInvalidToken indicates that ID token is malformed, not signed by provider or not meant for client.
+	ErrInvalidToken = errs.Class("invalid id token")
+)
+
+const (
+	// DiscoveryPath is a path of provider metadata relative to issuer.
+	DiscoveryPath = "/.well-known/openid-configuration"
+
+	// keysRefreshInterval is how often keys are fetched again when token is signed by unknown key.
+	keysRefreshInterval = time.Minute
+
+	// clockSkew is how much clocks of provider and app may differ.
+	clockSkew = time.Minute
+
+	// maxResponseSize limits responses of provider.
+	maxResponseSize = 1 << 20
+)
+
+// Config contains client registration at provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Metadata is a part of provider metadata which is used by client.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Client is a relying party of one provider.
+type Client struct {
+	config   Config
+	http     *http.Client
+	metadata Metadata
+
+	mu            sync.Mutex
+	keys          map[string]publicKey
+	keysFetchedAt time.Time
+}
+
+// Discover fetches metadata of provider and returns client of it.
+func Discover(ctx context.Context, httpClient *http.Client, config Config) (*Client, error) {
+	client := &Client{config: config, http: httpClient}
+
+	err := client.getJSON(ctx, strings.TrimSuffix(config.Issuer, "/")+DiscoveryPath, &client.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// issuer must match exactly, otherwise tokens of one provider could be accepted as tokens of other.
+	if client.metadata.Issuer != config.Issuer {
+		return nil, Error.New("issuer %q of metadata does not match %q", client.metadata.Issuer, config.Issuer)
+	}
+	if client.metadata.AuthorizationEndpoint == "" || client.metadata.TokenEndpoint == "" || client.metadata.JWKSURI == "" {
+		return nil, Error.New("metadata of %q misses endpoints", config.Issuer)
+	}
+
+	return client, nil
+}
+
+// Metadata returns metadata of provider.
+func (client *Client) Metadata() Metadata {
+	return client.metadata
+}
+
+// AuthCodeURL returns url of provider where user is sent to log in.
+// State and nonce are checked on callback, code challenge is derived from verifier, which is passed to Exchange.
+func (client *Client) AuthCodeURL(state, nonce, verifier string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", client.config.ClientID)
+	query.Set("redirect_uri", client.config.RedirectURL)
+	query.Set("scope", strings.Join(client.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(client.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return client.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// tokenResponse is a response of token endpoint.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange exchanges authorization code for ID token and returns the token verified with nonce.
+func (client *Client) Exchange(ctx context.Context, code, verifier, nonce string) (IDToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", client.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", client.config.ClientID)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, client.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return IDToken{}, Error.Wrap(err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	// client credentials are form encoded before basic auth, see RFC 6749 section 2.3.1.
+	request.SetBasicAuth(url.QueryEscape(client.config.ClientID), url.QueryEscape(client.config.ClientSecret))
+
+	var response tokenResponse
+	status, err := client.do(request, &response)
+	if err != nil {
+		return IDToken{}, err
+	}
+	if status != http.StatusOK || response.IDToken == "" {
+		return IDToken{}, Error.New("token endpoint responded %d %s %s", status, response.Error, response.ErrorDescription)
+	}
+
+	return client.Verify(ctx, response.IDToken, nonce, time.Now())
+}
+
+// NewSecret returns random url safe string which is used as state, nonce and PKCE verifier.
+func NewSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", Error.Wrap(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// CodeChallenge returns S256 PKCE challenge of verifier.
+func CodeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// getJSON decodes json response of GET request to url.
+func (client *Client) getJSON(ctx context.Context, url string, value interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	status, err := client.do(request, value)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return Error.New("%s responded %d", url, status)
+	}
+
+	return nil
+}
+
+// do sends request and decodes json response into value, status of response is returned.
+func (client *Client) do(request *http.Request, value interface{}) (_ int, err error) {
+	response, err := client.http.Do(request)
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+	defer func() {
+		err = errs.Combine(err, Error.Wrap(response.Body.Close()))
+	}()
+
+	err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(value)
+	if err != nil && response.StatusCode == http.StatusOK {
+		return response.StatusCode, Error.New("invalid response of %s: %v", request.URL, err)
+	}
+
+	return response.StatusCode, nil
+}
@@ -0,0 +1,129 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/pkg/oidc"
+	"todo/pkg/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost/callback"
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	provider := oidctest.NewProvider(t)
+	provider.SetUser(oidctest.User{Subject: "42", Email: "user@example.com", EmailVerified: true, Name: "User"})
+
+	client, err := oidc.Discover(ctx, http.DefaultClient, provider.Config(redirectURL))
+	require.NoError(t, err)
+	assert.Equal(t, provider.Issuer, client.Metadata().Issuer)
+
+	verifier, err := oidc.NewSecret()
+	require.NoError(t, err)
+	code, state := authorize(t, client.AuthCodeURL("state", "nonce", verifier))
+	assert.Equal(t, "state", state)
+
+	_, err = client.Exchange(ctx, code, "wrong verifier", "nonce")
+	assert.True(t, oidc.Error.Has(err), err)
+
+	code, _ = authorize(t, client.AuthCodeURL("state", "nonce", verifier))
+	token, err := client.Exchange(ctx, code, verifier, "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, "42", token.Subject)
+	assert.Equal(t, "user@example.com", token.Email)
+	assert.True(t, token.EmailVerified)
+	assert.Equal(t, "User", token.Name)
+
+	// codes are single use.
+	_, err = client.Exchange(ctx, code, verifier, "nonce")
+	assert.True(t, oidc.Error.Has(err), err)
+
+	code, _ = authorize(t, client.AuthCodeURL("state", "nonce", verifier))
+	_, err = client.Exchange(ctx, code, verifier, "other nonce")
+	assert.True(t, oidc.ErrInvalidToken.Has(err), err)
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	provider := oidctest.NewProvider(t)
+
+	config := provider.Config(redirectURL)
+	config.Issuer += "/"
+	_, err := oidc.Discover(context.Background(), http.DefaultClient, config)
+	assert.True(t, oidc.Error.Has(err), err)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	provider := oidctest.NewProvider(t)
+	user := oidctest.User{Subject: "42", Email: "user@example.com", EmailVerified: true}
+
+	client, err := oidc.Discover(ctx, http.DefaultClient, provider.Config(redirectURL))
+	require.NoError(t, err)
+
+	token, err := client.Verify(ctx, provider.IDToken(t, provider.Claims(user, "nonce")), "nonce", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "42", token.Subject)
+
+	for name, tamper := range map[string]func(claims map[string]interface{}){
+		"issuer":           func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"audience":         func(claims map[string]interface{}) { claims["aud"] = "other" },
+		"authorized party": func(claims map[string]interface{}) { claims["aud"] = []string{provider.ClientID, "other"} },
+		"expired":          func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"issued in future": func(claims map[string]interface{}) { claims["iat"] = time.Now().Add(time.Hour).Unix() },
+		"nonce":            func(claims map[string]interface{}) { claims["nonce"] = "other" },
+		"subject":          func(claims map[string]interface{}) { delete(claims, "sub") },
+	} {
+		claims := provider.Claims(user, "nonce")
+		tamper(claims)
+
+		_, err = client.Verify(ctx, provider.IDToken(t, claims), "nonce", time.Now())
+		assert.True(t, oidc.ErrInvalidToken.Has(err), name, err)
+	}
+
+	claims := provider.Claims(user, "nonce")
+	claims["aud"], claims["azp"] = []string{"other", provider.ClientID}, provider.ClientID
+	_, err = client.Verify(ctx, provider.IDToken(t, claims), "nonce", time.Now())
+	assert.NoError(t, err)
+
+	claims = provider.Claims(user, "nonce")
+	claims["email_verified"] = "true"
+	token, err = client.Verify(ctx, provider.IDToken(t, claims), "nonce", time.Now())
+	require.NoError(t, err)
+	assert.True(t, token.EmailVerified)
+
+	raw := provider.IDToken(t, provider.Claims(user, "nonce"))
+	parts := strings.Split(raw, ".")
+	other := strings.Split(provider.IDToken(t, provider.Claims(oidctest.User{Subject: "43"}, "nonce")), ".")
+	_, err = client.Verify(ctx, parts[0]+"."+other[1]+"."+parts[2], "nonce", time.Now())
+	assert.True(t, oidc.ErrInvalidToken.Has(err), err)
+
+	// unsigned tokens are rejected.
+	_, err = client.Verify(ctx, "eyJhbGciOiJub25lIn0."+parts[1]+".", "nonce", time.Now())
+	assert.True(t, oidc.ErrInvalidToken.Has(err), err)
+}
+
+// authorize follows authorization url and returns code and state which provider redirected with.
+func authorize(t *testing.T, authURL string) (code, state string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	response, err := client.Get(authURL)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), redirectURL), location)
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
@@ -0,0 +1,246 @@
+// Package oidctest contains local OpenID Connect provider which logs in configured user without asking,
+// it is used to test relying parties end to end.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"todo/pkg/oidc"
+)
+
+// keyID is id of the signing key of provider.
+const keyID = "test-key"
+
+// User is an account of provider which is logged in on authorization request.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is a local OpenID Connect provider.
+type Provider struct {
+	// Issuer is url of provider.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  User
+	codes map[string]authorization
+}
+
+// authorization is an issued authorization code.
+type authorization struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// NewProvider starts provider which is closed with t.Cleanup.
+func NewProvider(t testing.TB) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	provider := &Provider{
+		ClientID:     "todo",
+		ClientSecret: "secret",
+		key:          key,
+		user:         User{Subject: "subject", Email: "oidc@example.com", EmailVerified: true, Name: "OIDC User"},
+		codes:        map[string]authorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidc.DiscoveryPath, provider.discovery)
+	mux.HandleFunc("/authorize", provider.authorize)
+	mux.HandleFunc("/token", provider.token)
+	mux.HandleFunc("/jwks", provider.jwks)
+
+	provider.server = httptest.NewServer(mux)
+	provider.Issuer = provider.server.URL
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+// SetUser sets user who is logged in by next authorization requests.
+func (provider *Provider) SetUser(user User) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	provider.user = user
+}
+
+// Config returns client config registered at provider.
+func (provider *Provider) Config(redirectURL string) oidc.Config {
+	return oidc.Config{
+		Issuer:       provider.Issuer,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email"},
+	}
+}
+
+// IDToken signs ID token with claims, it is used to test validation of tampered tokens.
+func (provider *Provider) IDToken(t testing.TB, claims map[string]interface{}) string {
+	token, err := provider.sign(claims)
+	require.NoError(t, err)
+	return token
+}
+
+// Claims returns valid ID token claims of user.
+func (provider *Provider) Claims(user User, nonce string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            provider.Issuer,
+		"sub":            user.Subject,
+		"aud":            provider.ClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"name":           user.Name,
+	}
+}
+
+// discovery serves metadata of provider.
+func (provider *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{
+		Issuer:                provider.Issuer,
+		AuthorizationEndpoint: provider.Issuer + "/authorize",
+		TokenEndpoint:         provider.Issuer + "/token",
+		JWKSURI:               provider.Issuer + "/jwks",
+	})
+}
+
+// authorize logs in current user and redirects back to client with code.
+func (provider *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != provider.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		http.Error(w, "invalid redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oidc.NewSecret()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	provider.mu.Lock()
+	provider.codes[code] = authorization{
+		user:          provider.user,
+		redirectURI:   redirect.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	provider.mu.Unlock()
+
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token exchanges single use code for ID token.
+func (provider *Provider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != url.QueryEscape(provider.ClientID) || secret != url.QueryEscape(provider.ClientSecret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	provider.mu.Lock()
+	code, ok := provider.codes[r.PostForm.Get("code")]
+	delete(provider.codes, r.PostForm.Get("code"))
+	provider.mu.Unlock()
+
+	if !ok || code.redirectURI != r.PostForm.Get("redirect_uri") ||
+		code.codeChallenge != oidc.CodeChallenge(r.PostForm.Get("code_verifier")) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token, err := provider.sign(provider.Claims(code.user, code.nonce))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": token, "token_type": "Bearer"})
+}
+
+// jwks serves public key of provider.
+func (provider *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	writeJSON(w, http.StatusOK, oidc.JSONWebKeySet{Keys: []oidc.JSONWebKey{{
+		KeyType:   "RSA",
+		KeyID:     keyID,
+		Algorithm: oidc.AlgorithmRS256,
+		Use:       "sig",
+		N:         encode(provider.key.N.Bytes()),
+		E:         encode(big.NewInt(int64(provider.key.E)).Bytes()),
+	}}})
+}
+
+// sign returns RS256 token with claims.
+func (provider *Provider) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": oidc.AlgorithmRS256, "kid": keyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, provider.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// writeJSON writes value as json response.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
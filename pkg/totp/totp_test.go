@@ -0,0 +1,68 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/pkg/totp"
+)
+
+// rfcSecret is SHA1 secret of RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// the last 6 digits of RFC 6238 appendix B codes.
+	for unix, expected := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		code, err := totp.Code(rfcSecret, totp.Counter(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, unix)
+	}
+
+	_, err := totp.Code("not base32!", 1)
+	assert.True(t, totp.Error.Has(err), err)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := totp.Code(secret, totp.Counter(now.Add(-totp.Period)))
+	require.NoError(t, err)
+
+	counter, ok, err := totp.Validate(secret, code, now, 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Counter(now)-1, counter)
+
+	_, ok, err = totp.Validate(secret, code, now.Add(totp.Period), 1)
+	require.NoError(t, err)
+	assert.False(t, ok, "code older than skew must be rejected")
+
+	_, ok, err = totp.Validate(secret, "12345", now, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(totp.URI("todo", "user@gmail.com", "SECRET"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/todo:user@gmail.com", uri.Path)
+	assert.Equal(t, "SECRET", uri.Query().Get("secret"))
+	assert.Equal(t, "todo", uri.Query().Get("issuer"))
+}
@@ -0,0 +1,69 @@
+// Package sessions contains server-side login sessions, every auth token belongs to a session
+// and stops being accepted once its session is revoked.
+package sessions
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+)
+
+// ErrNoSession indicates that session does not exist.
+var ErrNoSession = errs.Class("session does not exist")
+
+// DB is exposing access to sessions db.
+type DB interface {
+	// Create creates session in the database.
+	Create(ctx context.Context, session Session) error
+	// Get returns session by id from the database.
+	Get(ctx context.Context, id uuid.UUID) (Session, error)
+	// List returns all sessions of user from the database, the most recent first.
+	List(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	// Rotate replaces refresh token hash of active session and extends its expiration time.
+	// ErrNoSession is returned when session is revoked or its refresh token hash is not oldHash.
+	Rotate(ctx context.Context, id uuid.UUID, oldHash, newHash []byte, expiresAt time.Time) error
+	// UpdateLastSeen sets time of the latest request made within session.
+	UpdateLastSeen(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error
+	// Revoke marks session as revoked.
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// RevokeAll marks all sessions of user as revoked.
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+}
+
+// Session describes login session of user.
+// Session is a family of rotating refresh tokens, only hash of the latest one is kept.
+type Session struct {
+	ID          uuid.UUID `json:"id" bson:"id"`
+	UserID      uuid.UUID `json:"userId" bson:"user_id"`
+	UserAgent   string    `json:"userAgent" bson:"user_agent"`
+	IP          string    `json:"ip" bson:"ip"`
+	RefreshHash []byte    `json:"-" bson:"refresh_hash"`
+	RememberMe  bool      `json:"rememberMe" bson:"remember_me"`
+	CreatedAt   time.Time `json:"createdAt" bson:"created_at"`
+	LastSeenAt  time.Time `json:"lastSeenAt" bson:"last_seen_at"`
+	ExpiresAt   time.Time `json:"expiresAt" bson:"expires_at"`
+	Revoked     bool      `json:"revoked" bson:"revoked"`
+}
+
+// Metadata describes client which opens session.
+type Metadata struct {
+	UserAgent string
+	IP        string
+}
+
+// RequestMetadata returns metadata of client which sent request.
+func RequestMetadata(r *http.Request) Metadata {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return Metadata{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
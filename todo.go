@@ -9,9 +9,15 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
+	"todo/accesstokens"
 	"todo/console"
+	"todo/identities"
 	"todo/items"
 	"todo/pkg/auth"
+	"todo/pkg/mail"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
 	"todo/users"
 	"todo/users/userauth"
 )
@@ -24,8 +30,23 @@ type DB interface {
 	// Items provides access to items db.
 	Items() items.DB
 
-	// CreateSchema creates db schema.
-	CreateSchema(ctx context.Context) error
+	// Sessions provides access to sessions db.
+	Sessions() sessions.DB
+
+	// Tokens provides access to single-use tokens db.
+	Tokens() tokens.DB
+	// TwoFactor provides access to second factors db.
+	TwoFactor() twofactor.DB
+	// Identities provides access to external identities db.
+	Identities() identities.DB
+	// AccessTokens provides access to personal access tokens db.
+	AccessTokens() accesstokens.DB
+
+	// MigrateToLatest migrates db schema to the latest version.
+	MigrateToLatest(ctx context.Context) error
+
+	// CheckVersion returns error if db schema is not on the latest version.
+	CheckVersion(ctx context.Context) error
 
 	// Close closes connection with db.
 	Close() error
@@ -53,24 +74,57 @@ type Todo struct {
 }
 
 // New is constructor for Todo.
-func New(database DB) (*Todo, error) {
-	todo := &Todo{Database: database}
-	todo.Logger, _ = zap.NewProduction()
-	err := database.CreateSchema(context.Background())
+func New(logger *zap.Logger, config Config, database DB) (*Todo, error) {
+	todo := &Todo{
+		Logger:   logger,
+		Database: database,
+	}
+
+	var err error
+	if config.Database.MigrateOnStart {
+		err = database.MigrateToLatest(context.Background())
+	} else {
+		err = database.CheckVersion(context.Background())
+	}
 	if err != nil {
 		return todo, err
 	}
 
 	{
+		passwords, err := users.NewPasswordHasher(config.Passwords)
+		if err != nil {
+			return todo, err
+		}
+
 		todo.Users.Service = users.New(
 			todo.Database.Users(),
+			passwords,
 		)
 
+		keyring, err := config.Auth.Keyring()
+		if err != nil {
+			return todo, err
+		}
+
+		mailer, err := mail.New(config.Mail)
+		if err != nil {
+			return todo, err
+		}
+
 		todo.Users.Auth = userauth.NewService(
+			todo.Logger,
 			todo.Database.Users(),
+			todo.Database.Sessions(),
+			todo.Database.Tokens(),
+			todo.Database.TwoFactor(),
+			todo.Database.Identities(),
+			todo.Database.AccessTokens(),
 			auth.TokenSigner{
-				Secret: []byte("secret-token"),
+				Keyring: keyring,
 			},
+			passwords,
+			mailer,
+			config.Auth,
 		)
 	}
 
@@ -81,18 +135,22 @@ func New(database DB) (*Todo, error) {
 	}
 
 	{ // console setup
-		todo.Console.Listener, err = net.Listen("tcp", ":8087")
+		todo.Console.Listener, err = net.Listen("tcp", config.Console.Address)
 		if err != nil {
 			return nil, err
 		}
 
 		todo.Console.Endpoint, err = console.NewServer(
+			config.Console,
 			todo.Console.Listener,
 			todo.Users.Auth,
 			todo.Logger,
 			todo.Items.Service,
 			todo.Users.Service,
 		)
+		if err != nil {
+			return nil, errs.Combine(err, todo.Console.Listener.Close())
+		}
 	}
 
 	return todo, nil
@@ -109,11 +167,13 @@ func (todo *Todo) Run(ctx context.Context) error {
 	return group.Wait()
 }
 
-// Close closes all the resources.
+// Close closes all the resources, servers are closed before the database they depend on.
 func (todo *Todo) Close() error {
 	var errlist errs.Group
 
 	errlist.Add(todo.Console.Endpoint.Close())
+	todo.Users.Auth.Wait()
+	errlist.Add(todo.Database.Close())
 
 	return errlist.Err()
 }
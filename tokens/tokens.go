@@ -0,0 +1,82 @@
+// Package tokens contains single-use tokens which are sent to users by mail, e.g. in password reset links.
+// Only hashes of tokens are stored, so leaked database does not let anyone use them.
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+)
+
+// ErrNoToken indicates that token does not exist, is expired or was already used.
+var ErrNoToken = errs.Class("token does not exist")
+
+// Purpose tells what token can be used for, token of one purpose is never accepted for other.
+type Purpose string
+
+const (
+	// PurposePasswordReset is a purpose of password reset tokens.
+	PurposePasswordReset Purpose = "password_reset"
+	// PurposeEmailVerification is a purpose of token which confirms ownership of email.
+	PurposeEmailVerification Purpose = "email_verification"
+	// PurposeSecondFactor is a purpose of token which proves that password of user was checked and
+	// second factor is awaited.
+	PurposeSecondFactor Purpose = "second_factor"
+	// PurposeReauthentication is a purpose of code mailed to user who confirms account change without password.
+	PurposeReauthentication Purpose = "reauthentication"
+)
+
+// secretLength is a length of random token secret.
+const secretLength = 32
+
+// DB is exposing access to tokens db.
+type DB interface {
+	// Create creates token in the database.
+	Create(ctx context.Context, token Token) error
+	// Consume deletes token with hash and purpose which expires after now and returns it.
+	// ErrNoToken is returned when there is no such token, so every token is accepted only once.
+	Consume(ctx context.Context, purpose Purpose, hash []byte, now time.Time) (Token, error)
+	// Get returns token with hash and purpose which expires after now without using it up.
+	Get(ctx context.Context, purpose Purpose, hash []byte, now time.Time) (Token, error)
+	// DeleteByUser deletes all tokens of user with purpose.
+	DeleteByUser(ctx context.Context, userID uuid.UUID, purpose Purpose) error
+}
+
+// Token describes single-use token of user.
+type Token struct {
+	Hash      []byte    `bson:"hash"`
+	UserID    uuid.UUID `bson:"user_id"`
+	Purpose   Purpose   `bson:"purpose"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// New returns random secret, which is sent to user, and token which keeps only its hash.
+func New(userID uuid.UUID, purpose Purpose, ttl time.Duration) (secret string, token Token, err error) {
+	random := make([]byte, secretLength)
+	if _, err = rand.Read(random); err != nil {
+		return "", Token{}, errs.Wrap(err)
+	}
+	secret = base64.RawURLEncoding.EncodeToString(random)
+
+	now := time.Now().UTC()
+	return secret, Token{
+		Hash:      Hash(secret),
+		UserID:    userID,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// Hash returns hash of random secret which is stored instead of it, e.g. of token, refresh token,
+// recovery code or access token. Secrets are random, so plain sha256 is enough.
+func Hash(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
+	return hash[:]
+}
@@ -0,0 +1,85 @@
+// Package twofactor keeps TOTP second factors of users.
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/tokens"
+)
+
+// ErrNoFactor indicates that factor, its recovery code or unused code counter does not exist.
+var ErrNoFactor = errs.Class("second factor does not exist")
+
+const (
+	// RecoveryCodesCount is how many recovery codes user gets.
+	RecoveryCodesCount = 10
+
+	// recoveryCodeLength is a length of random recovery code in bytes.
+	recoveryCodeLength = 10
+)
+
+// DB is exposing access to second factors db.
+type DB interface {
+	// Save creates or replaces factor of user.
+	Save(ctx context.Context, factor Factor) error
+	// Get returns factor of user.
+	Get(ctx context.Context, userID uuid.UUID) (Factor, error)
+	// UseCounter records that code of counter is used, ErrNoFactor is returned
+	// when code of the same or later counter was used already, so every code works once.
+	UseCounter(ctx context.Context, userID uuid.UUID, counter int64) error
+	// UseRecoveryCode deletes recovery code with hash, ErrNoFactor is returned when user has no such code.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) error
+	// Delete deletes factor of user.
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+// Factor is TOTP second factor of user.
+type Factor struct {
+	UserID uuid.UUID `bson:"user_id"`
+	// Secret is base32 encoded TOTP secret shared with authenticator app.
+	Secret string `bson:"secret"`
+	// RecoveryCodes are hashes of single-use codes which replace TOTP code when phone is lost.
+	RecoveryCodes [][]byte `bson:"recovery_codes"`
+	// LastCounter is a counter of the last accepted code.
+	LastCounter int64     `bson:"last_counter"`
+	CreatedAt   time.Time `bson:"created_at"`
+	// ConfirmedAt is when user proved that authenticator app works, factor is not required before that.
+	ConfirmedAt *time.Time `bson:"confirmed_at"`
+}
+
+// Confirmed reports whether factor is confirmed.
+func (factor Factor) Confirmed() bool {
+	return factor.ConfirmedAt != nil
+}
+
+// encoding of recovery codes, lower case letters and digits are easy to read and type.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes returns random recovery codes, which are shown to user, and their hashes.
+func NewRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	for i := 0; i < RecoveryCodesCount; i++ {
+		random := make([]byte, recoveryCodeLength)
+		if _, err = rand.Read(random); err != nil {
+			return nil, nil, errs.Wrap(err)
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(random))
+		code = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns hash of recovery code, case and dashes are ignored.
+func HashRecoveryCode(code string) []byte {
+	return tokens.Hash(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", "")))
+}
@@ -0,0 +1,239 @@
+package users
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zeebo/errs"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrPassword indicates that password does not match hash.
+	ErrPassword = errs.Class("password mismatch")
+
+	// ErrPasswordHash indicates that stored hash is malformed or uses unsupported algorithm.
+	ErrPasswordHash = errs.Class("password hash error")
+)
+
+const (
+	// AlgorithmArgon2id hashes passwords with argon2id, it is the default.
+	AlgorithmArgon2id = "argon2id"
+	// AlgorithmBcrypt hashes passwords with bcrypt.
+	AlgorithmBcrypt = "bcrypt"
+
+	// argon2SaltLength and argon2KeyLength are lengths of salt and hash in bytes.
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+
+	// maxArgon2Memory in KiB, maxArgon2Iterations, maxArgon2Parallelism and maxArgon2KeyLength bound
+	// both config and stored hashes, so that tampered hash can not make verification exhaust memory or cpu.
+	maxArgon2Memory      = 1024 * 1024
+	maxArgon2Iterations  = 64
+	maxArgon2Parallelism = 255
+	maxArgon2KeyLength   = 128
+)
+
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+type PasswordHasher interface {
+	// Hash returns encoded hash of password.
+	Hash(password []byte) ([]byte, error)
+	// Verify returns ErrPassword when password does not match hash.
+	Verify(hash, password []byte) error
+	// NeedsRehash reports whether hash was made with other algorithm or parameters than Hash uses.
+	NeedsRehash(hash []byte) bool
+}
+
+// PasswordConfig contains algorithm and cost parameters of new password hashes.
+type PasswordConfig struct {
+	// Algorithm of new hashes, argon2id or bcrypt. Hashes of both are verified regardless.
+	Algorithm string `yaml:"algorithm"`
+
+	Argon2 struct {
+		// Memory is memory cost in KiB.
+		Memory      uint32 `yaml:"memory"`
+		Iterations  uint32 `yaml:"iterations"`
+		Parallelism uint32 `yaml:"parallelism"`
+	} `yaml:"argon2"`
+
+	BcryptCost int `yaml:"bcryptCost"`
+}
+
+// DefaultPasswordConfig returns argon2id parameters recommended by OWASP.
+func DefaultPasswordConfig() PasswordConfig {
+	var config PasswordConfig
+	config.Algorithm = AlgorithmArgon2id
+	config.Argon2.Memory = 19 * 1024
+	config.Argon2.Iterations = 2
+	config.Argon2.Parallelism = 1
+	config.BcryptCost = bcrypt.DefaultCost
+
+	return config
+}
+
+// Validate checks that algorithm is supported and costs are in range.
+func (config PasswordConfig) Validate() error {
+	switch config.Algorithm {
+	case AlgorithmArgon2id:
+		return validateArgon2(config.Argon2.Memory, config.Argon2.Iterations, config.Argon2.Parallelism)
+	case AlgorithmBcrypt:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return ErrPasswordHash.New("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return ErrPasswordHash.New("unsupported algorithm %q", config.Algorithm)
+	}
+
+	return nil
+}
+
+// validateArgon2 checks that argon2 memory, iterations and parallelism are in range.
+func validateArgon2(memory, iterations, parallelism uint32) error {
+	if parallelism < 1 || parallelism > maxArgon2Parallelism || memory < 8*parallelism || memory > maxArgon2Memory ||
+		iterations < 1 || iterations > maxArgon2Iterations {
+		return ErrPasswordHash.New("invalid argon2 parameters m=%d t=%d p=%d", memory, iterations, parallelism)
+	}
+
+	return nil
+}
+
+// passwordHasher hashes new passwords with configured algorithm
+// and verifies argon2id hashes in PHC string format and bcrypt hashes.
+type passwordHasher struct {
+	config PasswordConfig
+}
+
+// NewPasswordHasher returns PasswordHasher of config.
+func NewPasswordHasher(config PasswordConfig) (PasswordHasher, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &passwordHasher{config: config}, nil
+}
+
+// Hash implements PasswordHasher.
+func (hasher *passwordHasher) Hash(password []byte) ([]byte, error) {
+	if hasher.config.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword(password, hasher.config.BcryptCost)
+		return hash, ErrPasswordHash.Wrap(err)
+	}
+
+	params := argon2Params{
+		memory:      hasher.config.Argon2.Memory,
+		iterations:  hasher.config.Argon2.Iterations,
+		parallelism: uint8(hasher.config.Argon2.Parallelism),
+		salt:        make([]byte, argon2SaltLength),
+	}
+	if _, err := rand.Read(params.salt); err != nil {
+		return nil, ErrPasswordHash.Wrap(err)
+	}
+	params.key = params.derive(password, argon2KeyLength)
+
+	return params.encode(), nil
+}
+
+// Verify implements PasswordHasher.
+func (hasher *passwordHasher) Verify(hash, password []byte) error {
+	if isBcrypt(hash) {
+		err := bcrypt.CompareHashAndPassword(hash, password)
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPassword.New("")
+		}
+		return ErrPasswordHash.Wrap(err)
+	}
+
+	params, err := parseArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	key := params.derive(password, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrPassword.New("")
+	}
+
+	return nil
+}
+
+// NeedsRehash implements PasswordHasher.
+func (hasher *passwordHasher) NeedsRehash(hash []byte) bool {
+	if isBcrypt(hash) {
+		cost, err := bcrypt.Cost(hash)
+		return hasher.config.Algorithm != AlgorithmBcrypt || err != nil || cost != hasher.config.BcryptCost
+	}
+
+	params, err := parseArgon2id(hash)
+	if err != nil || hasher.config.Algorithm != AlgorithmArgon2id {
+		return true
+	}
+
+	return params.memory != hasher.config.Argon2.Memory ||
+		params.iterations != hasher.config.Argon2.Iterations ||
+		uint32(params.parallelism) != hasher.config.Argon2.Parallelism
+}
+
+// isBcrypt reports whether hash is made by bcrypt.
+func isBcrypt(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) || bytes.HasPrefix(hash, []byte("$2b$")) || bytes.HasPrefix(hash, []byte("$2y$"))
+}
+
+// argon2Params are parameters, salt and key of argon2id hash.
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// derive returns argon2id key of password.
+func (params argon2Params) derive(password []byte, length uint32) []byte {
+	return argon2.IDKey(password, params.salt, params.iterations, params.memory, params.parallelism, length)
+}
+
+// encode returns hash in PHC string format: $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+func (params argon2Params) encode() []byte {
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		params.memory, params.iterations, params.parallelism,
+		base64.RawStdEncoding.EncodeToString(params.salt), base64.RawStdEncoding.EncodeToString(params.key)))
+}
+
+// parseArgon2id parses hash in PHC string format.
+func parseArgon2id(hash []byte) (argon2Params, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != AlgorithmArgon2id {
+		return argon2Params{}, ErrPasswordHash.New("unsupported password hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, ErrPasswordHash.New("unsupported argon2 version %q", parts[2])
+	}
+
+	var params argon2Params
+	var parallelism uint32
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &parallelism)
+	if err != nil {
+		return argon2Params{}, ErrPasswordHash.New("invalid argon2 parameters: %v", err)
+	}
+	if err = validateArgon2(params.memory, params.iterations, parallelism); err != nil {
+		return argon2Params{}, err
+	}
+	params.parallelism = uint8(parallelism)
+
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Params{}, ErrPasswordHash.New("invalid salt: %v", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 || len(params.key) > maxArgon2KeyLength {
+		return argon2Params{}, ErrPasswordHash.New("invalid key")
+	}
+
+	return params, nil
+}
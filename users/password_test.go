@@ -0,0 +1,110 @@
+package users_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"todo/users"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	config := users.DefaultPasswordConfig()
+	hasher, err := users.NewPasswordHasher(config)
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash([]byte("password"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=19456,t=2,p=1$"), string(hash))
+	assert.NoError(t, hasher.Verify(hash, []byte("password")))
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	err = hasher.Verify(hash, []byte("wrong"))
+	assert.True(t, users.ErrPassword.Has(err), err)
+
+	other, err := hasher.Hash([]byte("password"))
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "hashes must be salted")
+
+	t.Run("bcrypt hashes are verified and upgraded", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		assert.NoError(t, hasher.Verify(legacy, []byte("password")))
+		err = hasher.Verify(legacy, []byte("wrong"))
+		assert.True(t, users.ErrPassword.Has(err), err)
+		assert.True(t, hasher.NeedsRehash(legacy))
+	})
+
+	t.Run("changed costs require rehash", func(t *testing.T) {
+		stronger := config
+		stronger.Argon2.Iterations++
+		strongerHasher, err := users.NewPasswordHasher(stronger)
+		require.NoError(t, err)
+
+		assert.NoError(t, strongerHasher.Verify(hash, []byte("password")))
+		assert.True(t, strongerHasher.NeedsRehash(hash))
+	})
+
+	t.Run("bcrypt algorithm", func(t *testing.T) {
+		config := config
+		config.Algorithm, config.BcryptCost = users.AlgorithmBcrypt, bcrypt.MinCost
+		bcryptHasher, err := users.NewPasswordHasher(config)
+		require.NoError(t, err)
+
+		legacy, err := bcryptHasher.Hash([]byte("password"))
+		require.NoError(t, err)
+		assert.NoError(t, bcryptHasher.Verify(legacy, []byte("password")))
+		assert.False(t, bcryptHasher.NeedsRehash(legacy))
+		assert.True(t, bcryptHasher.NeedsRehash(hash))
+	})
+
+	t.Run("malformed hash", func(t *testing.T) {
+		for _, malformed := range []string{"", "plain", "$argon2id$v=18$m=1,t=1,p=1$c2FsdA$a2V5", "$argon2i$v=19$m=1,t=1,p=1$c2FsdA$a2V5"} {
+			err := hasher.Verify([]byte(malformed), []byte("password"))
+			assert.True(t, users.ErrPasswordHash.Has(err), malformed)
+			assert.True(t, hasher.NeedsRehash([]byte(malformed)), malformed)
+		}
+	})
+
+	t.Run("hash parameters out of range", func(t *testing.T) {
+		for _, test := range []struct {
+			name string
+			hash string
+		}{
+			{name: "zero parallelism", hash: "$argon2id$v=19$m=19456,t=2,p=0$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+			{name: "parallelism over 255", hash: "$argon2id$v=19$m=19456,t=2,p=256$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+			{name: "zero iterations", hash: "$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+			{name: "too many iterations", hash: "$argon2id$v=19$m=19456,t=4294967295,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+			{name: "memory below 8 per lane", hash: "$argon2id$v=19$m=15,t=2,p=2$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+			{name: "oversized memory", hash: "$argon2id$v=19$m=4294967295,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5"},
+			{name: "oversized key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$" + strings.Repeat("a2V5", 100)},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				err := hasher.Verify([]byte(test.hash), []byte("password"))
+				assert.True(t, users.ErrPasswordHash.Has(err), err)
+				assert.True(t, hasher.NeedsRehash([]byte(test.hash)))
+			})
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		invalid := config
+		invalid.Algorithm = "md5"
+		_, err := users.NewPasswordHasher(invalid)
+		assert.True(t, users.ErrPasswordHash.Has(err), err)
+
+		invalid = config
+		invalid.Argon2.Parallelism = 0
+		_, err = users.NewPasswordHasher(invalid)
+		assert.True(t, users.ErrPasswordHash.Has(err), err)
+
+		invalid = config
+		invalid.Argon2.Memory = 4 * 1024 * 1024
+		_, err = users.NewPasswordHasher(invalid)
+		assert.True(t, users.ErrPasswordHash.Has(err), err)
+	})
+}
@@ -0,0 +1,47 @@
+package users
+
+// Role defines what user is allowed to do.
+type Role string
+
+const (
+	// RoleUser is a role of every registered user, it manages only own items and account.
+	RoleUser Role = "user"
+	// RoleAdmin is a role of users who manage other users.
+	RoleAdmin Role = "admin"
+)
+
+// Roles are all known roles.
+var Roles = []Role{RoleUser, RoleAdmin}
+
+// Permission is an action which only some roles are allowed to do.
+type Permission string
+
+const (
+	// PermissionManageUsers lets list users, disable and enable them and revoke their sessions.
+	PermissionManageUsers Permission = "users:manage"
+)
+
+// rolePermissions are permissions granted to roles, RoleUser has none of them.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {PermissionManageUsers},
+}
+
+// Valid reports whether role is known.
+func (role Role) Valid() bool {
+	for _, known := range Roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether role is granted permission.
+func (role Role) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,13 @@ package users
 
 import (
 	"context"
+	"net/mail"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/zeebo/errs"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Error indicates that there was an error in users service.
@@ -14,18 +16,32 @@ var Error = errs.Class("users service error")
 
 // Service is handling users related logic.
 type Service struct {
-	users DB
+	users     DB
+	passwords PasswordHasher
+
+	// admins serializes changes which may take away the last admin, so that two admins
+	// can not demote, disable or delete each other at once.
+	admins sync.Mutex
 }
 
 // New is constructor for Service.
-func New(users DB) *Service {
+func New(users DB, passwords PasswordHasher) *Service {
 	return &Service{
-		users: users,
+		users:     users,
+		passwords: passwords,
 	}
 }
 
-// Create creates user.
+// Create creates user with unverified email.
 func (service *Service) Create(ctx context.Context, email, password string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if err = ValidatePassword(password); err != nil {
+		return err
+	}
+
 	user := User{
 		ID:        uuid.New(),
 		Email:     email,
@@ -33,7 +49,7 @@ func (service *Service) Create(ctx context.Context, email, password string) erro
 		CreatedAt: time.Now(),
 	}
 
-	err := user.EncodePass()
+	err = user.EncodePass(service.passwords)
 	if err != nil {
 		return Error.Wrap(err)
 	}
@@ -41,21 +57,175 @@ func (service *Service) Create(ctx context.Context, email, password string) erro
 	return Error.Wrap(service.users.Create(ctx, user))
 }
 
-// GetByEmail returns user by email.
+// maxEmailLength is the longest email which can be delivered, see RFC 5321 errata 1690.
+const maxEmailLength = 254
+
+// NormalizeEmail returns ErrValidation if email is not a bare address, otherwise returns it
+// trimmed and lower cased, emails which differ only in case belong to the same user.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || len(email) > maxEmailLength {
+		return "", ErrValidation.New("invalid email %q", email)
+	}
+
+	return strings.ToLower(email), nil
+}
+
+// ValidatePassword returns ErrValidation if password can not be set.
+func ValidatePassword(password string) error {
+	if password == "" {
+		return ErrValidation.New("password is required")
+	}
+
+	return nil
+}
+
+// GetByEmail returns user by email in any case.
 func (service *Service) GetByEmail(ctx context.Context, email string) (User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return User{}, ErrNoUser.Wrap(err)
+	}
+
 	user, err := service.users.GetByEmail(ctx, email)
 
 	return user, Error.Wrap(err)
 }
 
-// Delete deletes user.
+// GetByID returns user by id.
+func (service *Service) GetByID(ctx context.Context, id uuid.UUID) (User, error) {
+	user, err := service.users.GetByID(ctx, id)
+
+	return user, Error.Wrap(err)
+}
+
+// SetPassword replaces password of user. It does not ask for the current password, callers reauthenticate
+// user before or act as operators.
+func (service *Service) SetPassword(ctx context.Context, id uuid.UUID, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+
+	user := User{Password: []byte(password)}
+	if err := user.EncodePass(service.passwords); err != nil {
+		return Error.Wrap(err)
+	}
+
+	return Error.Wrap(service.users.UpdatePassword(ctx, id, user.Password))
+}
+
+// ChangeEmail replaces email of user and returns updated user, new email is unverified until user verifies it.
+// Callers reauthenticate user before.
+func (service *Service) ChangeEmail(ctx context.Context, id uuid.UUID, email string) (User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := service.users.GetByID(ctx, id)
+	if err != nil {
+		return User{}, Error.Wrap(err)
+	}
+	if user.Email == email {
+		return User{}, ErrValidation.New("new email is the same as current one")
+	}
+
+	if err = service.users.UpdateEmail(ctx, id, email); err != nil {
+		return User{}, Error.Wrap(err)
+	}
+	user.Email, user.VerifiedAt = email, nil
+
+	return user, nil
+}
+
+// Delete deletes user, ErrLastAdmin is returned for the last enabled admin.
 func (service *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	service.admins.Lock()
+	defer service.admins.Unlock()
+
+	if err := service.keepAdmin(ctx, id); err != nil {
+		return err
+	}
+
 	return Error.Wrap(service.users.Delete(ctx, id))
 }
 
+// List returns users whose email contains search ordered by email, all of them if search is empty.
+func (service *Service) List(ctx context.Context, search string) ([]User, error) {
+	list, err := service.users.List(ctx, strings.ToLower(strings.TrimSpace(search)))
+
+	return list, Error.Wrap(err)
+}
+
+// SetRole replaces role of user, ErrValidation is returned for unknown role
+// and ErrLastAdmin when the last enabled admin would lose admin role.
+func (service *Service) SetRole(ctx context.Context, id uuid.UUID, role Role) error {
+	if !role.Valid() {
+		return ErrValidation.New("unknown role %q", role)
+	}
+
+	service.admins.Lock()
+	defer service.admins.Unlock()
+
+	if role != RoleAdmin {
+		if err := service.keepAdmin(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return Error.Wrap(service.users.UpdateRole(ctx, id, role))
+}
+
+// Disable disables user, disabled user can not log in and its tokens are not accepted.
+// ErrLastAdmin is returned for the last enabled admin.
+func (service *Service) Disable(ctx context.Context, id uuid.UUID) error {
+	service.admins.Lock()
+	defer service.admins.Unlock()
+
+	if err := service.keepAdmin(ctx, id); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	return Error.Wrap(service.users.UpdateDisabled(ctx, id, &now))
+}
+
+// keepAdmin returns ErrLastAdmin if user is the only enabled admin, disabled admins can not manage users.
+func (service *Service) keepAdmin(ctx context.Context, id uuid.UUID) error {
+	list, err := service.users.List(ctx, "")
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	last := false
+	for _, user := range list {
+		if user.Role != RoleAdmin || user.Disabled() {
+			continue
+		}
+		if user.ID != id {
+			return nil
+		}
+		last = true
+	}
+
+	if last {
+		return ErrLastAdmin.New("user %s is the last enabled admin, make other user admin first", id)
+	}
+
+	return nil
+}
+
+// Enable enables disabled user.
+func (service *Service) Enable(ctx context.Context, id uuid.UUID) error {
+	return Error.Wrap(service.users.UpdateDisabled(ctx, id, nil))
+}
+
 // EncodePass encode the password and generate "hash" to store from users password.
-func (user *User) EncodePass() error {
-	hash, err := bcrypt.GenerateFromPassword(user.Password, bcrypt.DefaultCost)
+func (user *User) EncodePass(hasher PasswordHasher) error {
+	hash, err := hasher.Hash(user.Password)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,210 @@
+package users_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"todo/database/memdb"
+	"todo/users"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	for email, expected := range map[string]string{
+		"user@gmail.com":         "user@gmail.com",
+		"  User.Name@GMail.COM ": "user.name@gmail.com",
+		"user+tag@example.org":   "user+tag@example.org",
+	} {
+		normalized, err := users.NormalizeEmail(email)
+		require.NoError(t, err, email)
+		assert.Equal(t, expected, normalized)
+	}
+
+	for _, email := range []string{
+		"",
+		"user",
+		"@gmail.com",
+		"user@",
+		"User <user@gmail.com>",
+		"user@gmail.com, other@gmail.com",
+		strings.Repeat("a", 250) + "@gmail.com",
+	} {
+		_, err := users.NormalizeEmail(email)
+		assert.True(t, users.ErrValidation.Has(err), "%q: %v", email, err)
+	}
+}
+
+func TestCreateNormalizesEmail(t *testing.T) {
+	ctx := context.Background()
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	service := users.New(memdb.New().Users(), passwords)
+
+	require.NoError(t, service.Create(ctx, "User@Gmail.com", "password"))
+
+	user, err := service.GetByEmail(ctx, "USER@gmail.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "user@gmail.com", user.Email)
+	assert.False(t, user.Verified())
+
+	err = service.Create(ctx, "user@GMAIL.com", "password")
+	assert.True(t, users.ErrEmailTaken.Has(err), err)
+}
+
+func TestAccountSelfService(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	service := users.New(db.Users(), passwords)
+
+	require.NoError(t, service.Create(ctx, "user@gmail.com", "password"))
+	require.NoError(t, service.Create(ctx, "other@gmail.com", "password"))
+	user, err := service.GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Users().Verify(ctx, user.ID, time.Now().UTC()))
+
+	t.Run("set password", func(t *testing.T) {
+		err = service.SetPassword(ctx, user.ID, "")
+		assert.True(t, users.ErrValidation.Has(err), err)
+
+		require.NoError(t, service.SetPassword(ctx, user.ID, "new password"))
+		stored, err := service.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.NoError(t, passwords.Verify(stored.Password, []byte("new password")))
+	})
+
+	t.Run("change email", func(t *testing.T) {
+		_, err := service.ChangeEmail(ctx, user.ID, "OTHER@gmail.com")
+		assert.True(t, users.ErrEmailTaken.Has(err), err)
+		_, err = service.ChangeEmail(ctx, user.ID, "user@gmail.com")
+		assert.True(t, users.ErrValidation.Has(err), err)
+		_, err = service.ChangeEmail(ctx, user.ID, "invalid")
+		assert.True(t, users.ErrValidation.Has(err), err)
+
+		changed, err := service.ChangeEmail(ctx, user.ID, " Changed@gmail.com")
+		require.NoError(t, err)
+		assert.Equal(t, "changed@gmail.com", changed.Email)
+		assert.False(t, changed.Verified(), "new email must be verified again")
+
+		stored, err := service.GetByEmail(ctx, "changed@gmail.com")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, stored.ID)
+		assert.False(t, stored.Verified())
+		require.NoError(t, passwords.Verify(stored.Password, []byte("new password")), "password must stay")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, service.Delete(ctx, user.ID))
+		_, err = service.GetByID(ctx, user.ID)
+		assert.True(t, users.ErrNoUser.Has(err), err)
+	})
+}
+
+func TestUserAdministration(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	service := users.New(db.Users(), passwords)
+
+	require.NoError(t, service.Create(ctx, "user@gmail.com", "password"))
+	require.NoError(t, service.Create(ctx, "admin@example.com", "password"))
+	user, err := service.GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	assert.Equal(t, users.RoleUser, user.Role, "new users must get user role")
+	assert.False(t, user.Role.Can(users.PermissionManageUsers))
+
+	t.Run("roles", func(t *testing.T) {
+		err := service.SetRole(ctx, user.ID, "root")
+		assert.True(t, users.ErrValidation.Has(err), err)
+
+		require.NoError(t, service.SetRole(ctx, user.ID, users.RoleAdmin))
+		stored, err := service.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, users.RoleAdmin, stored.Role)
+		assert.True(t, stored.Role.Can(users.PermissionManageUsers))
+	})
+
+	t.Run("search", func(t *testing.T) {
+		list, err := service.List(ctx, " GMAIL")
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, user.ID, list[0].ID)
+
+		list, err = service.List(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, "admin@example.com", list[0].Email)
+	})
+
+	t.Run("set password", func(t *testing.T) {
+		err := service.SetPassword(ctx, user.ID, "")
+		assert.True(t, users.ErrValidation.Has(err), err)
+
+		require.NoError(t, service.SetPassword(ctx, user.ID, "new password"))
+		stored, err := service.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.NoError(t, passwords.Verify(stored.Password, []byte("new password")))
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		other, err := service.GetByEmail(ctx, "admin@example.com")
+		require.NoError(t, err)
+
+		require.NoError(t, service.Disable(ctx, other.ID))
+		stored, err := service.GetByID(ctx, other.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Disabled())
+
+		require.NoError(t, service.Enable(ctx, other.ID))
+		stored, err = service.GetByID(ctx, other.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Disabled())
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		other, err := service.GetByEmail(ctx, "admin@example.com")
+		require.NoError(t, err)
+
+		err = service.SetRole(ctx, user.ID, users.RoleUser)
+		assert.True(t, users.ErrLastAdmin.Has(err), err)
+		err = service.Disable(ctx, user.ID)
+		assert.True(t, users.ErrLastAdmin.Has(err), err)
+
+		require.NoError(t, service.SetRole(ctx, other.ID, users.RoleAdmin))
+		require.NoError(t, service.Disable(ctx, user.ID))
+		err = service.SetRole(ctx, other.ID, users.RoleUser)
+		assert.True(t, users.ErrLastAdmin.Has(err), "disabled admin must not count: %v", err)
+		require.NoError(t, service.Enable(ctx, user.ID))
+
+		// two admins demoting each other at once must leave one of them.
+		errs := make(chan error, 2)
+		for _, id := range []uuid.UUID{user.ID, other.ID} {
+			go func(id uuid.UUID) { errs <- service.SetRole(ctx, id, users.RoleUser) }(id)
+		}
+		first, second := <-errs, <-errs
+		assert.True(t, (first == nil) != (second == nil), "%v, %v", first, second)
+
+		list, err := service.List(ctx, "")
+		require.NoError(t, err)
+		var admins []users.User
+		for _, user := range list {
+			if user.Role == users.RoleAdmin {
+				admins = append(admins, user)
+			}
+		}
+		require.Len(t, admins, 1)
+
+		admin := admins[0]
+		err = service.Delete(ctx, admin.ID)
+		assert.True(t, users.ErrLastAdmin.Has(err), err)
+		_, err = service.GetByID(ctx, admin.ID)
+		require.NoError(t, err, "the last admin must be kept")
+	})
+}
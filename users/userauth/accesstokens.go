@@ -0,0 +1,103 @@
+package userauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/accesstokens"
+	"todo/pkg/auth"
+	"todo/tokens"
+)
+
+// MaxAccessTokenTTL is the longest lifetime of personal access token.
+const MaxAccessTokenTTL = 365 * 24 * time.Hour
+
+// ErrInsufficientScope indicates that personal access token is not allowed to do what is requested.
+var ErrInsufficientScope = errs.Class("insufficient scope")
+
+// CreateAccessToken creates personal access token of user and returns its secret, which is shown only once.
+func (service *Service) CreateAccessToken(ctx context.Context, userID uuid.UUID, name string, scopes []accesstokens.Scope, ttl time.Duration) (string, accesstokens.Token, error) {
+	if ttl > MaxAccessTokenTTL {
+		return "", accesstokens.Token{}, accesstokens.ErrValidation.New("token must expire within %d days", MaxAccessTokenTTL/(24*time.Hour))
+	}
+
+	secret, token, err := accesstokens.New(userID, name, scopes, ttl)
+	if err != nil {
+		if accesstokens.ErrValidation.Has(err) {
+			return "", accesstokens.Token{}, err
+		}
+		return "", accesstokens.Token{}, Error.Wrap(err)
+	}
+
+	if err = service.access.Create(ctx, token); err != nil {
+		return "", accesstokens.Token{}, Error.Wrap(err)
+	}
+
+	return secret, token, nil
+}
+
+// AccessTokens returns personal access tokens of user, the most recent first.
+func (service *Service) AccessTokens(ctx context.Context, userID uuid.UUID) ([]accesstokens.Token, error) {
+	list, err := service.access.List(ctx, userID)
+
+	return list, Error.Wrap(err)
+}
+
+// RevokeAccessToken deletes personal access token of user, it is not accepted anymore.
+func (service *Service) RevokeAccessToken(ctx context.Context, userID, id uuid.UUID) error {
+	err := service.access.Delete(ctx, userID, id)
+	if accesstokens.ErrNoToken.Has(err) {
+		return err
+	}
+
+	return Error.Wrap(err)
+}
+
+// authorizeAccessToken returns claims of personal access token and records when it was used.
+func (service *Service) authorizeAccessToken(ctx context.Context, secret string) (auth.Claims, error) {
+	token, err := service.access.GetByHash(ctx, tokens.Hash(secret))
+	if err != nil {
+		if accesstokens.ErrNoToken.Has(err) {
+			return auth.Claims{}, ErrUnauthenticated.New("access token is revoked or does not exist")
+		}
+		return auth.Claims{}, Error.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	if token.Expired(now) {
+		return auth.Claims{}, ErrUnauthenticated.New("access token %q is expired", token.Name)
+	}
+
+	user, err := service.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return auth.Claims{}, Error.Wrap(err)
+	}
+
+	claims := auth.Claims{
+		ID:        token.ID,
+		UserID:    user.ID,
+		Email:     user.Email,
+		IssuedAt:  token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+		Scopes:    make([]string, 0, len(token.Scopes)),
+	}
+	for _, scope := range token.Scopes {
+		claims.Scopes = append(claims.Scopes, string(scope))
+	}
+
+	if err = service.authorize(ctx, &claims); err != nil {
+		return auth.Claims{}, ErrUnauthenticated.Wrap(err)
+	}
+
+	// like last seen time of sessions, last use is recorded at most once per interval.
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= LastSeenInterval {
+		if err = service.access.UpdateLastUsed(ctx, token.ID, now); err != nil {
+			return auth.Claims{}, Error.Wrap(err)
+		}
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,201 @@
+package userauth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockoutConfig configures backoff of failed logins, counters are kept per email and per ip.
+// Zero threshold disables the counter.
+type LockoutConfig struct {
+	// AccountThreshold is how many failed logins with one email are allowed before lockout.
+	AccountThreshold int `yaml:"accountThreshold"`
+	// IPThreshold is how many failed logins from one ip are allowed before lockout.
+	IPThreshold int `yaml:"ipThreshold"`
+	// BaseDelay is the first lockout, every next failure doubles it up to MaxDelay.
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	MaxDelay  time.Duration `yaml:"maxDelay"`
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration `yaml:"window"`
+}
+
+// DefaultLockoutConfig returns lockout config used when none is configured.
+func DefaultLockoutConfig() LockoutConfig {
+	return LockoutConfig{
+		AccountThreshold: 5,
+		IPThreshold:      50,
+		BaseDelay:        time.Second,
+		MaxDelay:         15 * time.Minute,
+		Window:           time.Hour,
+	}
+}
+
+// lockout counts failed logins by key and locks keys with exponential backoff.
+// Counters live in memory, so every instance of the server counts on its own.
+type lockout struct {
+	config LockoutConfig
+
+	mu        sync.Mutex
+	failures  map[string]*failures
+	lastSweep time.Time
+}
+
+// failures of key in a row.
+type failures struct {
+	count int
+	// pending is how many reserved attempts are not yet known to fail or succeed.
+	pending     int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// limit is a counter key with threshold of failures it allows, zero threshold disables the counter.
+type limit struct {
+	key       string
+	threshold int
+}
+
+// newLockout is a constructor for lockout.
+func newLockout(config LockoutConfig) *lockout {
+	return &lockout{
+		config:   config,
+		failures: map[string]*failures{},
+	}
+}
+
+// emailKey returns counter key of email.
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// ipKey returns counter key of ip.
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// reserve checks limits and counts attempt as pending in all of them under one lock, so that parallel
+// attempts can not get past threshold before the first of them fails. Attempt is not reserved when
+// any of limits is locked or already has as many pending attempts as failures it allows,
+// reserve returns how long to wait then. Reserved attempt is finished by fail or release.
+func (lockout *lockout) reserve(now time.Time, limits ...limit) (time.Duration, bool) {
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	lockout.sweep(now)
+
+	var longest time.Duration
+	reserved := true
+	for _, limit := range limits {
+		if limit.threshold <= 0 {
+			continue
+		}
+		entry := lockout.entry(now, limit.key)
+		if locked := entry.lockedUntil.Sub(now); locked > 0 {
+			reserved = false
+			if locked > longest {
+				longest = locked
+			}
+		}
+		// after lockout expires only one attempt at a time is allowed until it fails again.
+		allowed := limit.threshold - entry.count
+		if allowed < 1 {
+			allowed = 1
+		}
+		if entry.pending >= allowed {
+			reserved = false
+			if longest < lockout.config.BaseDelay {
+				longest = lockout.config.BaseDelay
+			}
+		}
+	}
+	if !reserved {
+		return longest, false
+	}
+
+	for _, limit := range limits {
+		if limit.threshold > 0 {
+			lockout.entry(now, limit.key).pending++
+		}
+	}
+
+	return 0, true
+}
+
+// fail records failure of attempt reserved in limit and returns failures in a row and lockout it caused,
+// lockout is zero while failures are below threshold.
+func (lockout *lockout) fail(now time.Time, limit limit) (int, time.Duration) {
+	if limit.threshold <= 0 {
+		return 0, 0
+	}
+
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	entry := lockout.entry(now, limit.key)
+	if entry.pending > 0 {
+		entry.pending--
+	}
+	entry.count++
+	entry.last = now
+
+	if entry.count < limit.threshold {
+		return entry.count, 0
+	}
+
+	delay := lockout.config.MaxDelay
+	if shift := entry.count - limit.threshold; shift < 32 && lockout.config.BaseDelay<<shift < delay {
+		delay = lockout.config.BaseDelay << shift
+	}
+	entry.lockedUntil = now.Add(delay)
+
+	return entry.count, delay
+}
+
+// release finishes attempt reserved in limits without counting it as failure.
+func (lockout *lockout) release(limits ...limit) {
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	for _, limit := range limits {
+		if entry, ok := lockout.failures[limit.key]; ok && entry.pending > 0 {
+			entry.pending--
+		}
+	}
+}
+
+// entry returns counter of key, counter whose failures are older than window starts over.
+func (lockout *lockout) entry(now time.Time, key string) *failures {
+	entry, ok := lockout.failures[key]
+	if !ok {
+		entry = &failures{last: now}
+		lockout.failures[key] = entry
+	}
+	if entry.pending == 0 && now.Sub(entry.last) > lockout.config.Window {
+		entry.count = 0
+	}
+
+	return entry
+}
+
+// reset forgets failures of key.
+func (lockout *lockout) reset(key string) {
+	lockout.mu.Lock()
+	defer lockout.mu.Unlock()
+
+	delete(lockout.failures, key)
+}
+
+// sweep forgets expired counters once per window, so that memory does not grow with every guessed email.
+func (lockout *lockout) sweep(now time.Time) {
+	if now.Sub(lockout.lastSweep) < lockout.config.Window {
+		return
+	}
+	lockout.lastSweep = now
+
+	for key, entry := range lockout.failures {
+		if entry.pending == 0 && now.Sub(entry.last) > lockout.config.Window && now.After(entry.lockedUntil) {
+			delete(lockout.failures, key)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package userauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"todo/identities"
+	"todo/pkg/auth"
+	"todo/pkg/oidc"
+	"todo/sessions"
+	"todo/users"
+)
+
+const (
+	// OIDCCallbackPath is a path of console where provider sends user back, it is registered at provider.
+	OIDCCallbackPath = "/login/oidc/callback"
+
+	// OIDCRequestExpirationTime is how long user has to log in at provider.
+	OIDCRequestExpirationTime = 10 * time.Minute
+
+	// oidcTimeout limits requests to provider.
+	oidcTimeout = 10 * time.Second
+)
+
+// ErrOIDCDisabled indicates that OpenID Connect provider is not configured.
+var ErrOIDCDisabled = errs.Class("single sign-on is not configured")
+
+// OIDCConfig configures OpenID Connect provider users can log in with.
+type OIDCConfig struct {
+	// Issuer is url of provider, login with provider is disabled when it is empty.
+	Issuer string `yaml:"issuer"`
+	// Name of provider is shown on "Sign in with" button.
+	Name         string   `yaml:"name"`
+	ClientID     string   `yaml:"clientID"`
+	ClientSecret string   `yaml:"clientSecret"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// DefaultOIDCConfig returns default OpenID Connect config, provider is not configured.
+func DefaultOIDCConfig() OIDCConfig {
+	return OIDCConfig{
+		Name:   "SSO",
+		Scopes: []string{"openid", "email", "profile"},
+	}
+}
+
+// OIDCRequest is a pending login at provider, it is kept by browser until provider sends user back.
+type OIDCRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// OIDCEnabled reports whether users can log in with OpenID Connect provider.
+func (service *Service) OIDCEnabled() bool {
+	return service.config.OIDC.Issuer != ""
+}
+
+// OIDCName returns name of OpenID Connect provider.
+func (service *Service) OIDCName() string {
+	return service.config.OIDC.Name
+}
+
+// BeginOIDC returns url of provider where user logs in and request which is passed to CompleteOIDC.
+func (service *Service) BeginOIDC(ctx context.Context) (string, OIDCRequest, error) {
+	client, err := service.oidcClient(ctx)
+	if err != nil {
+		return "", OIDCRequest{}, err
+	}
+
+	var request OIDCRequest
+	for _, value := range []*string{&request.State, &request.Nonce, &request.Verifier} {
+		if *value, err = oidc.NewSecret(); err != nil {
+			return "", OIDCRequest{}, Error.Wrap(err)
+		}
+	}
+
+	return client.AuthCodeURL(request.State, request.Nonce, request.Verifier), request, nil
+}
+
+// CompleteOIDC exchanges code which provider sent user back with for ID token and logs in user of it.
+// Identity of provider is linked to user with the same verified email, new user is created
+// if there is none. Users with second factor get only SecondFactorToken like after password.
+func (service *Service) CompleteOIDC(ctx context.Context, request OIDCRequest, state, code string, metadata sessions.Metadata) (auth.Tokens, error) {
+	client, err := service.oidcClient(ctx)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	if request.State == "" || subtle.ConstantTimeCompare([]byte(request.State), []byte(state)) != 1 {
+		return auth.Tokens{}, ErrUnauthenticated.New("login request does not match, start again")
+	}
+
+	token, err := client.Exchange(ctx, code, request.Verifier, request.Nonce)
+	if err != nil {
+		service.log.Warn("could not complete single sign-on", zap.String("ip", metadata.IP), zap.Error(err))
+		return auth.Tokens{}, ErrUnauthenticated.New("provider did not confirm login, start again")
+	}
+
+	user, err := service.oidcUser(ctx, token)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	return service.login(ctx, user, false, metadata)
+}
+
+// oidcUser returns user linked to identity of token, identity is linked first if needed.
+func (service *Service) oidcUser(ctx context.Context, token oidc.IDToken) (users.User, error) {
+	identity, err := service.identities.Get(ctx, token.Issuer, token.Subject)
+	if err != nil {
+		if !identities.ErrNoIdentity.Has(err) {
+			return users.User{}, Error.Wrap(err)
+		}
+		if identity, err = service.linkIdentity(ctx, token); err != nil {
+			return users.User{}, err
+		}
+	}
+
+	user, err := service.users.GetByID(ctx, identity.UserID)
+	return user, Error.Wrap(err)
+}
+
+// linkIdentity links identity of token to user with its email, user is created if there is none.
+func (service *Service) linkIdentity(ctx context.Context, token oidc.IDToken) (identities.Identity, error) {
+	// emails are trusted only when provider checked them, otherwise anyone could take over account by email.
+	email, err := users.NormalizeEmail(token.Email)
+	if err != nil || !token.EmailVerified {
+		return identities.Identity{}, ErrUnauthenticated.New("provider did not confirm your email")
+	}
+
+	now := time.Now().UTC()
+	user, err := service.users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !user.Verified() {
+			if err = service.claimUnverified(ctx, user, now); err != nil {
+				return identities.Identity{}, err
+			}
+		}
+	case users.ErrNoUser.Has(err):
+		user = users.User{ID: uuid.New(), Email: email, CreatedAt: now, VerifiedAt: &now}
+		if user.Password, err = service.unusablePassword(); err != nil {
+			return identities.Identity{}, err
+		}
+		if err = service.users.Create(ctx, user); err != nil {
+			if users.ErrEmailTaken.Has(err) {
+				return identities.Identity{}, ErrUnauthenticated.New("account is being created by other login, try again")
+			}
+			return identities.Identity{}, Error.Wrap(err)
+		}
+		service.log.Info("user is created by single sign-on", zap.Stringer("user", user.ID))
+	default:
+		return identities.Identity{}, Error.Wrap(err)
+	}
+
+	identity := identities.Identity{
+		Provider:  token.Issuer,
+		Subject:   token.Subject,
+		UserID:    user.ID,
+		Email:     email,
+		CreatedAt: now,
+	}
+	if err = service.identities.Create(ctx, identity); err != nil {
+		// the same identity was linked by concurrent login.
+		if identities.ErrIdentityTaken.Has(err) {
+			identity, err = service.identities.Get(ctx, token.Issuer, token.Subject)
+			return identity, Error.Wrap(err)
+		}
+		return identities.Identity{}, Error.Wrap(err)
+	}
+
+	service.log.Info("identity is linked", zap.Stringer("user", user.ID), zap.String("provider", token.Issuer))
+	return identity, nil
+}
+
+// claimUnverified verifies email of user whose owner is proven by provider.
+// Whoever registered the email without verifying it could be someone else, so their password and sessions are dropped.
+func (service *Service) claimUnverified(ctx context.Context, user users.User, now time.Time) error {
+	hash, err := service.unusablePassword()
+	if err != nil {
+		return err
+	}
+	if err = service.users.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return Error.Wrap(err)
+	}
+	if err = service.sessions.RevokeAll(ctx, user.ID); err != nil {
+		return Error.Wrap(err)
+	}
+
+	return Error.Wrap(service.users.Verify(ctx, user.ID, now))
+}
+
+// unusablePassword returns hash of random password nobody knows, it can be replaced by password reset.
+func (service *Service) unusablePassword() ([]byte, error) {
+	password, _, err := newRefreshSecret()
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	hash, err := service.passwords.Hash(password)
+	return hash, Error.Wrap(err)
+}
+
+// oidcClient returns client of provider, provider metadata is discovered once on first use.
+func (service *Service) oidcClient(ctx context.Context) (*oidc.Client, error) {
+	if !service.OIDCEnabled() {
+		return nil, ErrOIDCDisabled.New("auth.oidc.issuer is not set")
+	}
+
+	service.oidcMu.Lock()
+	defer service.oidcMu.Unlock()
+
+	if service.oidc != nil {
+		return service.oidc, nil
+	}
+
+	client, err := oidc.Discover(ctx, &http.Client{Timeout: oidcTimeout}, oidc.Config{
+		Issuer:       service.config.OIDC.Issuer,
+		ClientID:     service.config.OIDC.ClientID,
+		ClientSecret: service.config.OIDC.ClientSecret,
+		RedirectURL:  strings.TrimSuffix(service.config.PublicURL, "/") + OIDCCallbackPath,
+		Scopes:       service.config.OIDC.Scopes,
+	})
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	service.oidc = client
+	return client, nil
+}
@@ -0,0 +1,105 @@
+package userauth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo/pkg/mail"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/users"
+)
+
+// ReauthenticationExpirationTime after passing this time mailed confirmation code expires.
+const ReauthenticationExpirationTime = 15 * time.Minute
+
+// Reauthenticate confirms that logged in user is present before account changes which need it, with password
+// or with confirmation code mailed by SendConfirmationCode, which lets users who signed in only with OpenID
+// Connect and have no password confirm changes. Attempts are counted by lockout like logins,
+// users.ErrPassword is returned when neither matches and ErrLocked after too many of them.
+func (service *Service) Reauthenticate(ctx context.Context, userID uuid.UUID, secret string, metadata sessions.Metadata) error {
+	user, err := service.users.GetByID(ctx, userID)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	limits := service.loginLimits(user.Email, metadata.IP)
+	if locked, ok := service.lockout.reserve(now, limits...); !ok {
+		return ErrLocked.New("too many failed attempts, try again in %s", locked.Round(time.Second))
+	}
+
+	confirmed, err := service.confirm(ctx, user, secret, now)
+	if err != nil {
+		service.lockout.release(limits...)
+		return err
+	}
+	if !confirmed {
+		_ = service.failLogin(now, user.Email, metadata.IP, limits)
+		return users.ErrPassword.New("password or confirmation code is wrong")
+	}
+
+	service.lockout.release(limits...)
+	service.lockout.reset(emailKey(user.Email))
+
+	return nil
+}
+
+// confirm reports whether secret is unused confirmation code mailed to user or password of user.
+func (service *Service) confirm(ctx context.Context, user users.User, secret string, now time.Time) (bool, error) {
+	hash := tokens.Hash(secret)
+	token, err := service.tokens.Get(ctx, tokens.PurposeReauthentication, hash, now)
+	switch {
+	case err == nil && token.UserID == user.ID:
+		_, err = service.tokens.Consume(ctx, tokens.PurposeReauthentication, hash, now)
+		if tokens.ErrNoToken.Has(err) {
+			return false, nil
+		}
+		return err == nil, Error.Wrap(err)
+	case err != nil && !tokens.ErrNoToken.Has(err):
+		return false, Error.Wrap(err)
+	}
+
+	err = service.passwords.Verify(user.Password, []byte(secret))
+	if users.ErrPassword.Has(err) {
+		return false, nil
+	}
+
+	return err == nil, Error.Wrap(err)
+}
+
+// SendConfirmationCode mails user code which confirms account change instead of password, previous codes
+// stop working. Requests are throttled by email and by ip with ErrLocked.
+func (service *Service) SendConfirmationCode(ctx context.Context, userID uuid.UUID, metadata sessions.Metadata) error {
+	user, err := service.users.GetByID(ctx, userID)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err = service.throttle(time.Now().UTC(), "reauthentication", user.Email, metadata.IP); err != nil {
+		return err
+	}
+
+	if err = service.tokens.DeleteByUser(ctx, user.ID, tokens.PurposeReauthentication); err != nil {
+		return Error.Wrap(err)
+	}
+	secret, token, err := tokens.New(user.ID, tokens.PurposeReauthentication, ReauthenticationExpirationTime)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err = service.tokens.Create(ctx, token); err != nil {
+		return Error.Wrap(err)
+	}
+
+	err = service.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Confirm account change",
+		Body: fmt.Sprintf("Someone asked to change or delete your todo account.\n\n"+
+			"Enter this code instead of password to confirm it, it expires in %s and works once:\n%s\n\n"+
+			"If it was not you, ignore this email, your account stays the same.\n", ReauthenticationExpirationTime, secret),
+	})
+
+	return Error.Wrap(err)
+}
@@ -0,0 +1,118 @@
+package userauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+
+	"todo/pkg/auth"
+	"todo/tokens"
+)
+
+// refreshSecretLength is a length of random part of refresh token.
+const refreshSecretLength = 32
+
+// newRefreshSecret returns random secret of refresh token and its hash which is stored in session.
+func newRefreshSecret() (secret, hash []byte, err error) {
+	secret = make([]byte, refreshSecretLength)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, nil, err
+	}
+
+	return secret, tokens.Hash(string(secret)), nil
+}
+
+// parseRefreshToken splits refresh token "<session id>.<secret>" into its parts.
+func parseRefreshToken(token string) (sessionID uuid.UUID, secret []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return uuid.UUID{}, nil, errs.New("invalid refresh token format")
+	}
+
+	sessionID, err = uuid.Parse(parts[0])
+	if err != nil {
+		return uuid.UUID{}, nil, errs.New("invalid refresh token session: %v", err)
+	}
+
+	secret, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(secret) != refreshSecretLength {
+		return uuid.UUID{}, nil, errs.New("invalid refresh token secret")
+	}
+
+	return sessionID, secret, nil
+}
+
+// rotations remembers refreshes of recent refresh tokens, so that parallel refreshes with the same token,
+// e.g. from several browser tabs, get the same tokens instead of being taken for reuse of stolen token.
+// Rotations live in memory, so only refreshes which reach the same instance of the server are recognized.
+type rotations struct {
+	mu        sync.Mutex
+	byHash    map[string]*rotation
+	lastSweep time.Time
+}
+
+// rotation is a refresh of token, done is closed once its tokens or error are known.
+type rotation struct {
+	done       chan struct{}
+	tokens     auth.Tokens
+	err        error
+	finishedAt time.Time
+}
+
+// newRotations is a constructor for rotations.
+func newRotations() *rotations {
+	return &rotations{byHash: map[string]*rotation{}}
+}
+
+// start returns rotation of token with hash, which is rotated by caller when start is true,
+// otherwise token is already rotated or is being rotated and caller waits for rotation to be done.
+// Rotations finished more than grace ago are forgotten, so that token reused later revokes its session.
+func (rotations *rotations) start(now time.Time, hash []byte, grace time.Duration) (_ *rotation, start bool) {
+	rotations.mu.Lock()
+	defer rotations.mu.Unlock()
+
+	if now.Sub(rotations.lastSweep) > grace {
+		rotations.lastSweep = now
+		for key, rotation := range rotations.byHash {
+			if rotation.expired(now, grace) {
+				delete(rotations.byHash, key)
+			}
+		}
+	}
+
+	if rotation, ok := rotations.byHash[string(hash)]; ok && !rotation.expired(now, grace) {
+		return rotation, false
+	}
+
+	rotation := &rotation{done: make(chan struct{})}
+	rotations.byHash[string(hash)] = rotation
+	return rotation, true
+}
+
+// finish records outcome of rotation of token with hash and wakes up refreshes waiting for it.
+// Failed rotation is forgotten at once, so that it is not repeated to next refreshes.
+func (rotations *rotations) finish(hash []byte, rotation *rotation, tokens auth.Tokens, err error) {
+	rotations.mu.Lock()
+	defer rotations.mu.Unlock()
+
+	rotation.tokens, rotation.err, rotation.finishedAt = tokens, err, time.Now().UTC()
+	close(rotation.done)
+	if err != nil && rotations.byHash[string(hash)] == rotation {
+		delete(rotations.byHash, string(hash))
+	}
+}
+
+// expired returns whether rotation finished more than grace ago.
+func (rotation *rotation) expired(now time.Time, grace time.Duration) bool {
+	select {
+	case <-rotation.done:
+		return now.Sub(rotation.finishedAt) > grace
+	default:
+		return false
+	}
+}
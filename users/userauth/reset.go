@@ -0,0 +1,113 @@
+package userauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"todo/pkg/mail"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/users"
+)
+
+// PasswordResetExpirationTime after passing this time password reset link expires.
+const PasswordResetExpirationTime = time.Hour
+
+// RequestPasswordReset mails link to reset password to user with email in background, previous links
+// of user stop working. Requests are throttled by email and by ip with ErrLocked, so that the form
+// can not be used to flood mailboxes.
+func (service *Service) RequestPasswordReset(ctx context.Context, email string, metadata sessions.Metadata) error {
+	email = lookupEmail(email)
+	if err := service.throttle(time.Now().UTC(), "reset", email, metadata.IP); err != nil {
+		return err
+	}
+
+	service.mailRegistered(email, "password reset", service.sendPasswordReset)
+	return nil
+}
+
+// sendPasswordReset mails link to reset password to user, previous links stop working.
+func (service *Service) sendPasswordReset(ctx context.Context, user users.User) error {
+	if err := service.tokens.DeleteByUser(ctx, user.ID, tokens.PurposePasswordReset); err != nil {
+		return Error.Wrap(err)
+	}
+
+	secret, token, err := tokens.New(user.ID, tokens.PurposePasswordReset, service.config.ResetTTL)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err = service.tokens.Create(ctx, token); err != nil {
+		return Error.Wrap(err)
+	}
+
+	link := strings.TrimSuffix(service.config.PublicURL, "/") + "/reset-password?token=" + url.QueryEscape(secret)
+	err = service.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Open the link to choose a new password, it expires in %s and works once:\n%s\n\n"+
+			"If it was not you, ignore this email, your password stays the same.\n", service.config.ResetTTL, link),
+	})
+
+	return Error.Wrap(err)
+}
+
+// throttle counts request of action by email and by ip in lockout and returns ErrLocked
+// once there are more of them than lockout thresholds allow.
+func (service *Service) throttle(now time.Time, action, email, ip string) error {
+	limits := []limit{
+		{key: action + ":" + emailKey(email), threshold: service.config.Lockout.AccountThreshold},
+		{key: action + ":" + ipKey(ip), threshold: service.config.Lockout.IPThreshold},
+	}
+	if locked, ok := service.lockout.reserve(now, limits...); !ok {
+		return ErrLocked.New("too many requests, try again in %s", locked.Round(time.Second))
+	}
+	for _, limit := range limits {
+		service.lockout.fail(now, limit)
+	}
+
+	return nil
+}
+
+// ResetPassword sets new password of user who received reset token and revokes all sessions of user.
+// ErrUnauthenticated is returned when token is unknown, expired or already used.
+func (service *Service) ResetPassword(ctx context.Context, secret, password string) error {
+	if err := users.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	token, err := service.tokens.Consume(ctx, tokens.PurposePasswordReset, tokens.Hash(secret), time.Now().UTC())
+	if err != nil {
+		if tokens.ErrNoToken.Has(err) {
+			return ErrUnauthenticated.New("reset link is invalid or expired")
+		}
+		return Error.Wrap(err)
+	}
+
+	hash, err := service.passwords.Hash([]byte(password))
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	user, err := service.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err = service.users.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return Error.Wrap(err)
+	}
+
+	// whoever knew the old password must not stay logged in.
+	if err = service.sessions.RevokeAll(ctx, user.ID); err != nil {
+		return Error.Wrap(err)
+	}
+	service.lockout.reset(emailKey(user.Email))
+
+	service.log.Info("password is reset", zap.Stringer("user", user.ID))
+	return nil
+}
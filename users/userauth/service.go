@@ -6,75 +6,485 @@ package userauth
 import (
 	"context"
 	"crypto/subtle"
+	"encoding/base64"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/zeebo/errs"
-	"golang.org/x/crypto/bcrypt"
+	"go.uber.org/zap"
 
+	"todo/accesstokens"
+	"todo/identities"
 	"todo/pkg/auth"
+	"todo/pkg/mail"
+	"todo/pkg/oidc"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
 	"todo/users"
 )
 
 const (
-	// TokenExpirationTime after passing this time token expires.
-	TokenExpirationTime = 24 * time.Hour
+	// TokenExpirationTime after passing this time access token expires.
+	TokenExpirationTime = 15 * time.Minute
+
+	// RefreshExpirationTime after passing this time without refresh session expires.
+	RefreshExpirationTime = 24 * time.Hour
+
+	// RememberMeExpirationTime is RefreshExpirationTime of sessions opened with "remember me".
+	RememberMeExpirationTime = 30 * 24 * time.Hour
+
+	// MinSecretLength is the minimal allowed length of token signing secret.
+	MinSecretLength = auth.MinKeyLength
+
+	// defaultKeyID is an id of key made of TokenSecret.
+	defaultKeyID = "default"
+
+	// LastSeenInterval is how often last seen time of session is updated.
+	LastSeenInterval = time.Minute
+
+	// mailTimeout limits time of sending mail in background.
+	mailTimeout = time.Minute
+
+	// RefreshGracePeriod is RefreshGrace used when none is configured.
+	RefreshGracePeriod = 30 * time.Second
 )
 
 var (
 	// ErrUnauthenticated should be returned when user performs unauthenticated action.
 	ErrUnauthenticated = errs.Class("user unauthenticated error")
 
+	// ErrUnverified indicates that user has to verify email before logging in.
+	ErrUnverified = errs.Class("email is not verified")
+
+	// ErrLocked indicates that login or form is temporarily locked after too many attempts.
+	ErrLocked = errs.Class("login locked")
+
+	// ErrDisabled indicates that account is disabled by admin and can not be used.
+	ErrDisabled = errs.Class("account is disabled")
+
+	// ErrNoKeys indicates that no token signing keys are configured.
+	ErrNoKeys = errs.Class("no signing keys")
+
 	// Error is a error class for internal auth errors.
 	Error = errs.Class("user auth internal error")
 )
 
+// Config contains configuration for user auth service.
+type Config struct {
+	// TokenSecret is a single signing key, it is used when neither Keys nor KeyFile are set.
+	TokenSecret string `yaml:"tokenSecret"`
+	// Keys are HS256 or EdDSA signing keys, ActiveKey signs new tokens and the rest only verify old ones.
+	Keys      []auth.SigningKey `yaml:"keys"`
+	ActiveKey string            `yaml:"activeKey"`
+	// KeyFile is a path to yaml keyring maintained by "keys" command, it takes precedence over Keys.
+	KeyFile string `yaml:"keyFile"`
+	// TokenTTL is lifetime of access token.
+	TokenTTL time.Duration `yaml:"tokenTTL"`
+	// RefreshTTL is how long session lives without refresh, every refresh extends it.
+	RefreshTTL time.Duration `yaml:"refreshTTL"`
+	// RememberMeTTL replaces RefreshTTL for sessions opened with "remember me".
+	RememberMeTTL time.Duration `yaml:"rememberMeTTL"`
+	// RefreshGrace is how long refresh token keeps returning tokens it was exchanged for,
+	// so that parallel refreshes are not taken for reuse of stolen token.
+	RefreshGrace time.Duration `yaml:"refreshGrace"`
+	// Lockout configures backoff of failed logins.
+	Lockout LockoutConfig `yaml:"lockout"`
+	// ResetTTL is lifetime of password reset link.
+	ResetTTL time.Duration `yaml:"resetTTL"`
+	// PublicURL is a base url of console which is put into links sent by mail.
+	PublicURL string `yaml:"publicURL"`
+	// Verification configures email verification of new users.
+	Verification VerificationConfig `yaml:"verification"`
+	// TwoFactor configures TOTP second factor.
+	TwoFactor TwoFactorConfig `yaml:"twoFactor"`
+	// OIDC configures OpenID Connect provider users can log in with.
+	OIDC OIDCConfig `yaml:"oidc"`
+}
+
+// KeyringConfig returns signing keys from KeyFile, Keys or TokenSecret, whichever is set first.
+func (config Config) KeyringConfig() (auth.KeyringConfig, error) {
+	switch {
+	case config.KeyFile != "":
+		return auth.LoadKeyFile(config.KeyFile)
+	case len(config.Keys) > 0:
+		return auth.KeyringConfig{Active: config.ActiveKey, Keys: config.Keys}, nil
+	case config.TokenSecret != "":
+		return auth.KeyringConfig{
+			Active: defaultKeyID,
+			Keys:   []auth.SigningKey{{ID: defaultKeyID, Algorithm: auth.AlgorithmHS256, Secret: config.TokenSecret}},
+		}, nil
+	default:
+		return auth.KeyringConfig{}, ErrNoKeys.New("one of auth.tokenSecret, auth.keys or auth.keyFile is required")
+	}
+}
+
+// Keyring returns keyring of configured signing keys.
+func (config Config) Keyring() (*auth.Keyring, error) {
+	keyringConfig, err := config.KeyringConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewKeyring(keyringConfig)
+}
+
 // Service is handling all user authentication logic.
 //
 // architecture: Service
 type Service struct {
-	users  users.DB
-	signer auth.TokenSigner
+	log        *zap.Logger
+	users      users.DB
+	sessions   sessions.DB
+	tokens     tokens.DB
+	twoFactor  twofactor.DB
+	identities identities.DB
+	access     accesstokens.DB
+	signer     auth.TokenSigner
+	passwords  users.PasswordHasher
+	mailer     mail.Mailer
+	config     Config
+
+	lockout   *lockout
+	rotations *rotations
+	// dummyHash is verified for unknown emails, so that they take as long as wrong passwords.
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+
+	// background tracks mails sent in background.
+	background sync.WaitGroup
+
+	// oidc is client of OpenID Connect provider, it is created on first use.
+	oidc   *oidc.Client
+	oidcMu sync.Mutex
 }
 
 // NewService is a constructor for user auth service.
-func NewService(users users.DB, signer auth.TokenSigner) *Service {
+func NewService(log *zap.Logger, users users.DB, sessions sessions.DB, tokens tokens.DB, twoFactor twofactor.DB,
+	identities identities.DB, access accesstokens.DB, signer auth.TokenSigner, passwords users.PasswordHasher, mailer mail.Mailer,
+	config Config) *Service {
 	return &Service{
-		users:  users,
-		signer: signer,
+		log:        log,
+		users:      users,
+		sessions:   sessions,
+		tokens:     tokens,
+		twoFactor:  twoFactor,
+		identities: identities,
+		access:     access,
+		signer:     signer,
+		passwords:  passwords,
+		mailer:     mailer,
+		config:     config,
+		lockout:    newLockout(config.Lockout),
+		rotations:  newRotations(),
+	}
+}
+
+// Token authenticates user by credentials, opens new session and returns its tokens.
+// Refresh token of session opened with rememberMe lives for RememberMeTTL instead of RefreshTTL.
+// Unknown email and wrong password are both reported as invalid credentials, too many of them
+// lock login by email and by ip with ErrLocked.
+// Users with second factor get only SecondFactorToken, which is exchanged for session by SecondFactor.
+func (service *Service) Token(ctx context.Context, email string, password string, rememberMe bool, metadata sessions.Metadata) (_ auth.Tokens, err error) {
+	user, err := service.checkCredentials(ctx, time.Now().UTC(), lookupEmail(email), password, metadata.IP)
+	if err != nil {
+		return auth.Tokens{}, err
+	}
+
+	if err = service.checkVerified(user); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	// password is known only now, so hashes made with outdated algorithm or costs are upgraded on login.
+	if service.passwords.NeedsRehash(user.Password) {
+		hash, err := service.passwords.Hash([]byte(password))
+		if err != nil {
+			return auth.Tokens{}, Error.Wrap(err)
+		}
+		if err = service.users.UpdatePassword(ctx, user.ID, hash); err != nil {
+			return auth.Tokens{}, Error.Wrap(err)
+		}
+	}
+
+	return service.login(ctx, user, rememberMe, metadata)
+}
+
+// login opens session of user whose identity is checked, users with second factor are challenged first.
+func (service *Service) login(ctx context.Context, user users.User, rememberMe bool, metadata sessions.Metadata) (auth.Tokens, error) {
+	if err := checkEnabled(user); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	factor, err := service.twoFactor.Get(ctx, user.ID)
+	switch {
+	case err == nil && factor.Confirmed():
+		return service.challenge(ctx, user.ID)
+	case err != nil && !twofactor.ErrNoFactor.Has(err):
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	return service.openSession(ctx, user, rememberMe, metadata)
+}
+
+// openSession opens new session of user and returns its tokens.
+func (service *Service) openSession(ctx context.Context, user users.User, rememberMe bool, metadata sessions.Metadata) (auth.Tokens, error) {
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	session := sessions.Session{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		UserAgent:   metadata.UserAgent,
+		IP:          metadata.IP,
+		RefreshHash: hash,
+		RememberMe:  rememberMe,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(service.refreshTTL(rememberMe)),
 	}
+	if err = service.sessions.Create(ctx, session); err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	return service.issueTokens(ctx, user, session, secret)
 }
 
-// Token authenticates user by credentials and returns auth token.
-func (service *Service) Token(ctx context.Context, email string, password string) (token string, err error) {
+// checkCredentials returns user with email when password matches. Attempt is reserved in lockout of email
+// and ip before password is verified, so that parallel guesses can not get past thresholds.
+// Unknown email and wrong password are both reported as invalid credentials.
+func (service *Service) checkCredentials(ctx context.Context, now time.Time, email, password, ip string) (users.User, error) {
+	limits := service.loginLimits(email, ip)
+	if locked, ok := service.lockout.reserve(now, limits...); !ok {
+		return users.User{}, ErrLocked.New("too many failed attempts, try again in %s", locked.Round(time.Second))
+	}
+
 	user, err := service.users.GetByEmail(ctx, email)
 	if err != nil {
-		return "", Error.Wrap(err)
+		if !users.ErrNoUser.Has(err) {
+			service.lockout.release(limits...)
+			return users.User{}, Error.Wrap(err)
+		}
+
+		_ = service.passwords.Verify(service.getDummyHash(), []byte(password))
+		return users.User{}, service.failLogin(now, email, ip, limits)
+	}
+
+	if err = service.passwords.Verify(user.Password, []byte(password)); err != nil {
+		if users.ErrPassword.Has(err) {
+			return users.User{}, service.failLogin(now, email, ip, limits)
+		}
+		service.lockout.release(limits...)
+		return users.User{}, Error.Wrap(err)
+	}
+
+	service.lockout.release(limits...)
+	// ip counter is not reset, otherwise attacker could reset it by logging into own account.
+	service.lockout.reset(emailKey(email))
+
+	return user, nil
+}
+
+// loginLimits returns lockout limits of login with email from ip.
+func (service *Service) loginLimits(email, ip string) []limit {
+	return []limit{
+		{key: emailKey(email), threshold: service.config.Lockout.AccountThreshold},
+		{key: ipKey(ip), threshold: service.config.Lockout.IPThreshold},
 	}
+}
+
+// failLogin counts failed login reserved in limits, logs lockouts and returns invalid credentials error.
+func (service *Service) failLogin(now time.Time, email, ip string, limits []limit) error {
+	for _, limit := range limits {
+		failures, locked := service.lockout.fail(now, limit)
+		if locked > 0 {
+			service.log.Warn("login locked", zap.String("key", limit.key), zap.String("email", email), zap.String("ip", ip),
+				zap.Int("failures", failures), zap.Duration("lockout", locked))
+		}
+	}
+
+	return ErrUnauthenticated.New("invalid credentials")
+}
+
+// mailRegistered looks up user with email and mails it with send in background. Forms which mail users respond
+// the same and as fast whether email is registered or not, so that they do not disclose registered users.
+func (service *Service) mailRegistered(email, purpose string, send func(ctx context.Context, user users.User) error) {
+	service.background.Add(1)
+	go func() {
+		defer service.background.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
 
-	err = bcrypt.CompareHashAndPassword(user.Password, []byte(password))
+		user, err := service.users.GetByEmail(ctx, email)
+		if err != nil {
+			if users.ErrNoUser.Has(err) {
+				service.log.Debug(purpose+" requested for unknown email", zap.String("email", email))
+				return
+			}
+			service.log.Error("could not get user to mail "+purpose, zap.Error(Error.Wrap(err)))
+			return
+		}
+
+		if err = send(ctx, user); err != nil {
+			service.log.Error("could not mail "+purpose, zap.Stringer("user", user.ID), zap.Error(err))
+		}
+	}()
+}
+
+// Wait waits for mails which are sent in background, it is called on shutdown before database is closed.
+func (service *Service) Wait() {
+	service.background.Wait()
+}
+
+// getDummyHash returns hash of random password made with current password hasher.
+func (service *Service) getDummyHash() []byte {
+	service.dummyHashOnce.Do(func() {
+		password, _, err := newRefreshSecret()
+		if err == nil {
+			service.dummyHash, err = service.passwords.Hash(password)
+		}
+		if err != nil {
+			service.log.Error("could not create dummy password hash", zap.Error(err))
+		}
+	})
+
+	return service.dummyHash
+}
+
+// Refresh exchanges refresh token for new access and refresh tokens and extends session.
+// Refresh token can be used only once, reusing it revokes the whole session. Refreshes with the same token
+// within RefreshGrace of the first one get the same tokens, so that parallel refreshes do not revoke session.
+func (service *Service) Refresh(ctx context.Context, refreshToken string) (_ auth.Tokens, err error) {
+	sessionID, secret, err := parseRefreshToken(refreshToken)
 	if err != nil {
-		return "", ErrUnauthenticated.Wrap(err)
+		return auth.Tokens{}, ErrUnauthenticated.Wrap(err)
+	}
+
+	oldHash := tokens.Hash(string(secret))
+	rotation, start := service.rotations.start(time.Now().UTC(), oldHash, service.config.RefreshGrace)
+	if !start {
+		select {
+		case <-rotation.done:
+			return rotation.tokens, rotation.err
+		case <-ctx.Done():
+			return auth.Tokens{}, Error.Wrap(ctx.Err())
+		}
+	}
+
+	tokens, err := service.rotate(ctx, sessionID, oldHash)
+	service.rotations.finish(oldHash, rotation, tokens, err)
+
+	return tokens, err
+}
+
+// rotate replaces refresh token with hash oldHash of session with new one and issues tokens with it.
+func (service *Service) rotate(ctx context.Context, sessionID uuid.UUID, oldHash []byte) (auth.Tokens, error) {
+	session, err := service.sessions.Get(ctx, sessionID)
+	if err != nil {
+		if sessions.ErrNoSession.Has(err) {
+			return auth.Tokens{}, ErrUnauthenticated.Wrap(err)
+		}
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	if session.Revoked || now.After(session.ExpiresAt) {
+		return auth.Tokens{}, ErrUnauthenticated.New("session %s is revoked or expired", session.ID)
+	}
+
+	if subtle.ConstantTimeCompare(oldHash, session.RefreshHash) != 1 {
+		return auth.Tokens{}, service.revokeReused(ctx, session.ID)
+	}
+
+	newSecret, newHash, err := newRefreshSecret()
+	if err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	session.RefreshHash, session.ExpiresAt = newHash, now.Add(service.refreshTTL(session.RememberMe))
+	err = service.sessions.Rotate(ctx, session.ID, oldHash, session.RefreshHash, session.ExpiresAt)
+	if err != nil {
+		// token was rotated by concurrent request with the same token.
+		if sessions.ErrNoSession.Has(err) {
+			return auth.Tokens{}, service.revokeReused(ctx, session.ID)
+		}
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	user, err := service.users.GetByID(ctx, session.UserID)
+	if err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+	if err = service.checkVerified(user); err != nil {
+		return auth.Tokens{}, err
+	}
+
+	return service.issueTokens(ctx, user, session, newSecret)
+}
+
+// revokeReused revokes session whose refresh token was presented after rotation,
+// either legitimate owner or attacker holds a stolen token, so neither can continue.
+func (service *Service) revokeReused(ctx context.Context, sessionID uuid.UUID) error {
+	if err := service.sessions.Revoke(ctx, sessionID); err != nil {
+		return Error.Wrap(err)
+	}
+
+	return ErrUnauthenticated.New("refresh token reuse detected, session %s is revoked", sessionID)
+}
+
+// issueTokens creates access token of session and refresh token with secret.
+// Tokens are never issued to disabled user, whichever way session is opened or refreshed.
+func (service *Service) issueTokens(ctx context.Context, user users.User, session sessions.Session, secret []byte) (auth.Tokens, error) {
+	if err := checkEnabled(user); err != nil {
+		return auth.Tokens{}, err
 	}
 
 	claims := auth.Claims{
-		UserID:    user.ID,
-		Email:     user.Email,
-		ExpiresAt: time.Now().UTC().Add(TokenExpirationTime),
+		UserID:        user.ID,
+		SessionID:     session.ID,
+		Email:         user.Email,
+		EmailVerified: user.Verified(),
+		Role:          string(user.Role),
+		ExpiresAt:     time.Now().UTC().Add(service.config.TokenTTL).Truncate(time.Second),
 	}
 
-	token, err = service.signer.CreateToken(ctx, &claims)
+	accessToken, err := service.signer.CreateToken(ctx, &claims)
 	if err != nil {
-		return "", Error.Wrap(err)
+		return auth.Tokens{}, Error.Wrap(err)
 	}
 
-	return token, nil
+	return auth.Tokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  claims.ExpiresAt,
+		RefreshToken:     session.ID.String() + "." + base64.RawURLEncoding.EncodeToString(secret),
+		RefreshExpiresAt: session.ExpiresAt,
+		RememberMe:       session.RememberMe,
+	}, nil
+}
+
+// refreshTTL returns lifetime of refresh token.
+func (service *Service) refreshTTL(rememberMe bool) time.Duration {
+	if rememberMe {
+		return service.config.RememberMeTTL
+	}
+	return service.config.RefreshTTL
 }
 
 // Authorize validates token from context and returns authorized Authorization.
+// Personal access tokens are accepted as well, their claims carry scopes of token.
 func (service *Service) Authorize(ctx context.Context, tokenS string) (_ auth.Claims, err error) {
+	if accesstokens.IsAccessToken(tokenS) {
+		return service.authorizeAccessToken(ctx, tokenS)
+	}
+
+	// malformed, forged and expired tokens are ErrUnauthenticated, storage failures stay Error.
 	token, err := auth.FromBase64URLString(tokenS)
 	if err != nil {
-		return auth.Claims{}, Error.Wrap(err)
+		return auth.Claims{}, ErrUnauthenticated.Wrap(err)
 	}
 
 	claims, err := service.authenticate(token)
@@ -84,26 +494,89 @@ func (service *Service) Authorize(ctx context.Context, tokenS string) (_ auth.Cl
 
 	err = service.authorize(ctx, claims)
 	if err != nil {
-		return auth.Claims{}, ErrUnauthenticated.Wrap(err)
+		return auth.Claims{}, err
+	}
+
+	err = service.checkSession(ctx, claims)
+	if err != nil {
+		return auth.Claims{}, err
 	}
 
 	return *claims, nil
 }
 
-// authenticate validates token signature and returns authenticated *satelliteauth.Authorization.
-func (service *Service) authenticate(token auth.Token) (_ *auth.Claims, err error) {
-	signature := token.Signature
+// JWKS returns public keys which verify auth tokens.
+func (service *Service) JWKS() auth.JSONWebKeySet {
+	return service.signer.Keyring.JWKS()
+}
+
+// Logout revokes session, its tokens are not accepted anymore.
+func (service *Service) Logout(ctx context.Context, sessionID uuid.UUID) error {
+	return Error.Wrap(service.sessions.Revoke(ctx, sessionID))
+}
+
+// LogoutEverywhere revokes all sessions of user.
+func (service *Service) LogoutEverywhere(ctx context.Context, userID uuid.UUID) error {
+	return Error.Wrap(service.sessions.RevokeAll(ctx, userID))
+}
 
-	err = service.signer.SignToken(&token)
+// LogoutOthers revokes all sessions of user except keep, which is the session user acts from.
+func (service *Service) LogoutOthers(ctx context.Context, userID, keep uuid.UUID) error {
+	list, err := service.sessions.List(ctx, userID)
 	if err != nil {
-		return nil, err
+		return Error.Wrap(err)
 	}
 
-	if subtle.ConstantTimeCompare(signature, token.Signature) != 1 {
-		return nil, errs.New("incorrect signature")
+	for _, session := range list {
+		if session.ID == keep || session.Revoked {
+			continue
+		}
+		if err = service.sessions.Revoke(ctx, session.ID); err != nil {
+			return Error.Wrap(err)
+		}
 	}
 
-	claims, err := auth.FromJSON(token.Payload)
+	return nil
+}
+
+// Sessions returns all sessions of user, the most recent first.
+func (service *Service) Sessions(ctx context.Context, userID uuid.UUID) ([]sessions.Session, error) {
+	list, err := service.sessions.List(ctx, userID)
+
+	return list, Error.Wrap(err)
+}
+
+// checkSession returns ErrUnauthenticated if session of claims is revoked or does not exist
+// and updates last seen time of active session.
+func (service *Service) checkSession(ctx context.Context, claims *auth.Claims) error {
+	session, err := service.sessions.Get(ctx, claims.SessionID)
+	if err != nil {
+		if sessions.ErrNoSession.Has(err) {
+			return ErrUnauthenticated.Wrap(err)
+		}
+		return Error.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	if session.Revoked || session.UserID != claims.UserID || now.After(session.ExpiresAt) {
+		return ErrUnauthenticated.New("session %s is revoked or expired", session.ID)
+	}
+
+	if now.Sub(session.LastSeenAt) < LastSeenInterval {
+		return nil
+	}
+
+	return Error.Wrap(service.sessions.UpdateLastSeen(ctx, session.ID, now))
+}
+
+// authenticate validates token signature and returns authenticated *satelliteauth.Authorization.
+func (service *Service) authenticate(token auth.Token) (_ *auth.Claims, err error) {
+	err = service.signer.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := auth.FromJWT(token.Payload)
 	if err != nil {
 		return nil, err
 	}
@@ -117,9 +590,38 @@ func (service *Service) authorize(ctx context.Context, claims *auth.Claims) (err
 		return ErrUnauthenticated.New("token expiration time has expired")
 	}
 
-	_, err = service.users.GetByEmail(ctx, claims.Email)
+	user, err := service.users.GetByID(ctx, claims.UserID)
 	if err != nil {
-		return errs.New("authorization failed. no user with email: %s", claims.Email)
+		if users.ErrNoUser.Has(err) {
+			return ErrUnauthenticated.New("authorization failed. no user with id: %s", claims.UserID)
+		}
+		return Error.Wrap(err)
+	}
+
+	if err = checkEnabled(user); err != nil {
+		return err
+	}
+
+	// email, its verification and role are taken from user, so that changes apply without waiting for token renewal.
+	claims.Email = user.Email
+	claims.EmailVerified = user.Verified()
+	claims.Role = string(user.Role)
+
+	if service.config.TwoFactor.Required {
+		enabled, err := service.TwoFactorEnabled(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		claims.TwoFactorRequired = !enabled
+	}
+
+	return service.checkVerified(user)
+}
+
+// checkEnabled returns ErrDisabled if user is disabled by admin.
+func checkEnabled(user users.User) error {
+	if user.Disabled() {
+		return ErrDisabled.New("account %s is disabled", user.Email)
 	}
 
 	return nil
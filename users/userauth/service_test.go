@@ -0,0 +1,852 @@
+package userauth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"todo"
+	"todo/accesstokens"
+	"todo/database/memdb"
+	"todo/pkg/auth"
+	"todo/pkg/mail"
+	"todo/pkg/oidc/oidctest"
+	"todo/pkg/totp"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/users"
+	"todo/users/userauth"
+)
+
+// newService returns auth service backed by in-memory database and buffer which receives mails,
+// configure changes default test config.
+func newService(t *testing.T, configure func(config *userauth.Config)) (*userauth.Service, todo.DB, *bytes.Buffer) {
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+
+	return newServiceWithPasswords(t, passwords, configure)
+}
+
+// newServiceWithPasswords is newService which hashes passwords with passwords hasher.
+func newServiceWithPasswords(t *testing.T, passwords users.PasswordHasher, configure func(config *userauth.Config)) (*userauth.Service, todo.DB, *bytes.Buffer) {
+	db := memdb.New()
+
+	keyring, err := auth.NewKeyring(auth.KeyringConfig{
+		Active: "test",
+		Keys:   []auth.SigningKey{{ID: "test", Secret: "0123456789abcdef"}},
+	})
+	require.NoError(t, err)
+
+	mailbox := new(bytes.Buffer)
+	mailer := mail.NewWriter("todo@localhost", mailbox)
+
+	config := userauth.Config{
+		TokenTTL:     time.Hour,
+		RefreshTTL:   time.Hour,
+		ResetTTL:     time.Hour,
+		PublicURL:    "http://localhost:8087",
+		Verification: userauth.DefaultVerificationConfig(),
+		TwoFactor:    userauth.DefaultTwoFactorConfig(),
+	}
+	if configure != nil {
+		configure(&config)
+	}
+
+	return userauth.NewService(zap.NewNop(), db.Users(), db.Sessions(), db.Tokens(), db.TwoFactor(), db.Identities(), db.AccessTokens(), auth.TokenSigner{Keyring: keyring}, passwords, mailer, config), db, mailbox
+}
+
+func TestTokenRehashesPassword(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, nil)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", Password: legacy, CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	_, err = service.Token(ctx, user.Email, "wrong", false, sessions.Metadata{})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	stored, err := db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, legacy, stored.Password, "failed login must not rehash")
+
+	_, err = service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	stored, err = db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(stored.Password), "$argon2id$"), string(stored.Password))
+
+	_, err = service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	rehashed, err := db.Users().GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored.Password, rehashed.Password, "current hash must be kept")
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, func(config *userauth.Config) {
+		config.Lockout = userauth.LockoutConfig{
+			AccountThreshold: 3,
+			IPThreshold:      5,
+			BaseDelay:        200 * time.Millisecond,
+			MaxDelay:         time.Minute,
+			Window:           time.Hour,
+		}
+	})
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", Password: hash, CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	login := func(email, password, ip string) error {
+		_, err := service.Token(ctx, email, password, false, sessions.Metadata{IP: ip})
+		return err
+	}
+
+	t.Run("unknown email is indistinguishable from wrong password", func(t *testing.T) {
+		unknown := login("nobody@gmail.com", "password", "10.0.0.1")
+		wrong := login(user.Email, "wrong", "10.0.0.2")
+		assert.True(t, userauth.ErrUnauthenticated.Has(unknown), unknown)
+		assert.Equal(t, wrong.Error(), unknown.Error())
+		require.NoError(t, login(user.Email, "password", "10.0.0.2"))
+	})
+
+	t.Run("account is locked with backoff", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			err := login(user.Email, "wrong", fmt.Sprintf("10.0.1.%d", i))
+			assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+		}
+
+		// correct password from other ip is rejected too while account is locked.
+		err := login(user.Email, "password", "10.0.1.9")
+		assert.True(t, userauth.ErrLocked.Has(err), err)
+		err = login("USER@gmail.com", "password", "10.0.1.9")
+		assert.True(t, userauth.ErrLocked.Has(err), err)
+
+		time.Sleep(250 * time.Millisecond)
+		require.NoError(t, login(user.Email, "password", "10.0.1.9"))
+		require.Error(t, login(user.Email, "wrong", "10.0.1.9"), "success resets account counter")
+		require.NoError(t, login(user.Email, "password", "10.0.1.9"))
+	})
+
+	t.Run("ip is locked across emails", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			err := login(uuid.NewString()+"@gmail.com", "password", "10.0.2.1")
+			assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+		}
+
+		err := login(user.Email, "password", "10.0.2.1")
+		assert.True(t, userauth.ErrLocked.Has(err), err)
+		require.NoError(t, login(user.Email, "password", "10.0.2.2"))
+	})
+}
+
+// countingHasher counts verified passwords.
+type countingHasher struct {
+	users.PasswordHasher
+	verified int32
+}
+
+// Verify implements users.PasswordHasher.
+func (hasher *countingHasher) Verify(hash, password []byte) error {
+	atomic.AddInt32(&hasher.verified, 1)
+	return hasher.PasswordHasher.Verify(hash, password)
+}
+
+func TestLoginLockoutConcurrent(t *testing.T) {
+	ctx := context.Background()
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	hasher := &countingHasher{PasswordHasher: passwords}
+
+	service, db, _ := newServiceWithPasswords(t, hasher, func(config *userauth.Config) {
+		config.Lockout = userauth.LockoutConfig{
+			AccountThreshold: 3,
+			IPThreshold:      100,
+			BaseDelay:        time.Minute,
+			MaxDelay:         time.Hour,
+			Window:           time.Hour,
+		}
+	})
+
+	hash, err := passwords.Hash([]byte("password"))
+	require.NoError(t, err)
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", Password: hash, CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	const attempts = 20
+	errsCh := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := service.Token(ctx, user.Email, "wrong", false, sessions.Metadata{IP: fmt.Sprintf("10.0.3.%d", i)})
+			errsCh <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errsCh)
+
+	var unauthenticated int
+	for err := range errsCh {
+		switch {
+		case userauth.ErrUnauthenticated.Has(err):
+			unauthenticated++
+		default:
+			assert.True(t, userauth.ErrLocked.Has(err), err)
+		}
+	}
+
+	assert.LessOrEqual(t, int(atomic.LoadInt32(&hasher.verified)), 3)
+	assert.Equal(t, int(atomic.LoadInt32(&hasher.verified)), unauthenticated)
+
+	_, err = service.Token(ctx, user.Email, "password", false, sessions.Metadata{IP: "10.0.3.100"})
+	assert.True(t, userauth.ErrLocked.Has(err), err)
+}
+
+// mailedToken returns token of the last link to page in mailbox.
+func mailedToken(t *testing.T, mailbox *bytes.Buffer, page string) string {
+	matches := regexp.MustCompile(page+`\?token=(\S+)`).FindAllStringSubmatch(mailbox.String(), -1)
+	require.NotEmpty(t, matches, mailbox.String())
+
+	token, err := url.QueryUnescape(matches[len(matches)-1][1])
+	require.NoError(t, err)
+	return token
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, func(config *userauth.Config) {
+		config.RefreshGrace = 200 * time.Millisecond
+	})
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", Password: hash, CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	issued, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	refreshed := make([]auth.Tokens, 2)
+	refreshErrs := make([]error, 2)
+	for i := range refreshed {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			refreshed[i], refreshErrs[i] = service.Refresh(ctx, issued.RefreshToken)
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, refreshErrs[0])
+	require.NoError(t, refreshErrs[1])
+	assert.Equal(t, refreshed[0], refreshed[1])
+	assert.NotEqual(t, issued.RefreshToken, refreshed[0].RefreshToken)
+
+	_, err = service.Authorize(ctx, refreshed[0].AccessToken)
+	require.NoError(t, err, "parallel refreshes must not revoke session")
+
+	// reuse after grace is taken for stolen token.
+	time.Sleep(250 * time.Millisecond)
+	_, err = service.Refresh(ctx, issued.RefreshToken)
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	_, err = service.Refresh(ctx, refreshed[0].RefreshToken)
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	service, db, mailbox := newService(t, func(config *userauth.Config) {
+		config.Lockout = userauth.LockoutConfig{
+			AccountThreshold: 3,
+			IPThreshold:      5,
+			BaseDelay:        time.Minute,
+			MaxDelay:         time.Hour,
+			Window:           time.Hour,
+		}
+	})
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := users.User{ID: uuid.New(), Email: "user@gmail.com", Password: hash, CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.Users().Create(ctx, user))
+
+	tokens, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+
+	// unknown email is not reported.
+	require.NoError(t, service.RequestPasswordReset(ctx, "nobody@gmail.com", sessions.Metadata{IP: "10.0.4.1"}))
+	service.Wait()
+	assert.Zero(t, mailbox.Len())
+
+	requestToken := func() string {
+		mailbox.Reset()
+		require.NoError(t, service.RequestPasswordReset(ctx, user.Email, sessions.Metadata{IP: "10.0.4.2"}))
+		service.Wait()
+		assert.Contains(t, mailbox.String(), "To: "+user.Email+"\r\n")
+		return mailedToken(t, mailbox, "/reset-password")
+	}
+
+	replaced := requestToken()
+	token := requestToken()
+
+	err = service.ResetPassword(ctx, replaced, "new password")
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), "new link must invalidate previous one: %v", err)
+	err = service.ResetPassword(ctx, token, "")
+	assert.True(t, users.ErrValidation.Has(err), err)
+
+	require.NoError(t, service.ResetPassword(ctx, token, "new password"))
+
+	err = service.ResetPassword(ctx, token, "other password")
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), "link must work once: %v", err)
+
+	_, err = service.Authorize(ctx, tokens.AccessToken)
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), "sessions must be revoked: %v", err)
+	_, err = service.Refresh(ctx, tokens.RefreshToken)
+	assert.Error(t, err)
+
+	_, err = service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	_, err = service.Token(ctx, user.Email, "new password", false, sessions.Metadata{})
+	require.NoError(t, err)
+
+	// requests are throttled by email and by ip, whether email is registered or not.
+	require.NoError(t, service.RequestPasswordReset(ctx, user.Email, sessions.Metadata{IP: "10.0.4.3"}))
+	err = service.RequestPasswordReset(ctx, "USER@gmail.com", sessions.Metadata{IP: "10.0.4.4"})
+	assert.True(t, userauth.ErrLocked.Has(err), err)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, service.RequestPasswordReset(ctx, fmt.Sprintf("nobody%d@gmail.com", i), sessions.Metadata{IP: "10.0.4.1"}))
+	}
+	err = service.RequestPasswordReset(ctx, "somebody@gmail.com", sessions.Metadata{IP: "10.0.4.1"})
+	assert.True(t, userauth.ErrLocked.Has(err), err)
+	service.Wait()
+}
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	for _, unverified := range []string{userauth.UnverifiedLimited, userauth.UnverifiedBlocked} {
+		unverified := unverified
+		t.Run(unverified, func(t *testing.T) {
+			service, db, mailbox := newService(t, func(config *userauth.Config) {
+				config.Verification.Unverified = unverified
+			})
+			passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+			require.NoError(t, err)
+			require.NoError(t, users.New(db.Users(), passwords).Create(ctx, " User@Gmail.com ", "password"))
+			service.RequestVerification("USER@gmail.com")
+			service.Wait()
+			token := mailedToken(t, mailbox, "/verify-email")
+
+			login := func() (auth.Claims, error) {
+				tokens, err := service.Token(ctx, "user@GMAIL.com", "password", false, sessions.Metadata{})
+				if err != nil {
+					return auth.Claims{}, err
+				}
+				return service.Authorize(ctx, tokens.AccessToken)
+			}
+
+			claims, err := login()
+			if unverified == userauth.UnverifiedBlocked {
+				assert.True(t, userauth.ErrUnverified.Has(err), err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "user@gmail.com", claims.Email)
+				assert.False(t, claims.EmailVerified)
+			}
+
+			user, err := service.VerifyEmail(ctx, token)
+			require.NoError(t, err)
+			assert.True(t, user.Verified())
+			_, err = service.VerifyEmail(ctx, token)
+			assert.True(t, userauth.ErrUnauthenticated.Has(err), "link must work once: %v", err)
+
+			claims, err = login()
+			require.NoError(t, err)
+			assert.True(t, claims.EmailVerified)
+
+			// verified and unknown emails receive nothing.
+			mailbox.Reset()
+			service.RequestVerification(user.Email)
+			service.RequestVerification("nobody@gmail.com")
+			service.Wait()
+			assert.Zero(t, mailbox.Len())
+		})
+	}
+}
+
+func TestTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, func(config *userauth.Config) {
+		config.RememberMeTTL = 24 * time.Hour
+	})
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	require.NoError(t, users.New(db.Users(), passwords).Create(ctx, "user@gmail.com", "password"))
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+
+	// currentCode returns code which is valid now, next code is valid too, so that codes are not replayed.
+	currentCode := func(secret string, next int64) string {
+		code, err := totp.Code(secret, totp.Counter(time.Now())+next)
+		require.NoError(t, err)
+		return code
+	}
+
+	enrollment, err := service.BeginEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/todo:user@gmail.com?"), enrollment.URI)
+	again, err := service.BeginEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, again.Secret, "unconfirmed enrollment must be reused")
+
+	tokens, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken, "unconfirmed factor must not be required")
+
+	_, err = service.ConfirmEnrollment(ctx, user.ID, "abcdef")
+	assert.True(t, userauth.ErrInvalidCode.Has(err), err)
+	codes, err := service.ConfirmEnrollment(ctx, user.ID, currentCode(enrollment.Secret, 0))
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	_, err = service.BeginEnrollment(ctx, user.ID)
+	assert.True(t, userauth.ErrTwoFactorState.Has(err), err)
+
+	challenge := func() string {
+		tokens, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+		require.NoError(t, err)
+		require.NotEmpty(t, tokens.SecondFactorToken)
+		assert.Empty(t, tokens.AccessToken, "access token must wait for second factor")
+		return tokens.SecondFactorToken
+	}
+
+	secondFactorToken := challenge()
+	_, err = service.SecondFactor(ctx, secondFactorToken, "abcdef", false, sessions.Metadata{})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	_, err = service.SecondFactor(ctx, secondFactorToken, currentCode(enrollment.Secret, 0), false, sessions.Metadata{})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), "code used for confirmation must not be accepted again: %v", err)
+
+	tokens, err = service.SecondFactor(ctx, secondFactorToken, currentCode(enrollment.Secret, 1), true, sessions.Metadata{})
+	require.NoError(t, err)
+	assert.True(t, tokens.RememberMe)
+	claims, err := service.Authorize(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.False(t, claims.TwoFactorRequired)
+
+	_, err = service.SecondFactor(ctx, secondFactorToken, codes[0], false, sessions.Metadata{})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), "second factor token must work once: %v", err)
+
+	t.Run("recovery code", func(t *testing.T) {
+		_, err := service.SecondFactor(ctx, challenge(), strings.ToUpper(codes[0]), false, sessions.Metadata{})
+		require.NoError(t, err)
+		_, err = service.SecondFactor(ctx, challenge(), codes[0], false, sessions.Metadata{})
+		assert.True(t, userauth.ErrUnauthenticated.Has(err), "recovery code must work once: %v", err)
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		err := service.DisableTwoFactor(ctx, user.ID, "abcdef")
+		assert.True(t, userauth.ErrInvalidCode.Has(err), err)
+		require.NoError(t, service.DisableTwoFactor(ctx, user.ID, codes[1]))
+
+		enabled, err := service.TwoFactorEnabled(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+		tokens, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+	})
+
+	t.Run("required", func(t *testing.T) {
+		service, db, _ := newService(t, func(config *userauth.Config) {
+			config.TwoFactor.Required = true
+		})
+		require.NoError(t, users.New(db.Users(), passwords).Create(ctx, "user@gmail.com", "password"))
+
+		tokens, err := service.Token(ctx, "user@gmail.com", "password", false, sessions.Metadata{})
+		require.NoError(t, err)
+		claims, err := service.Authorize(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.True(t, claims.TwoFactorRequired)
+
+		enrollment, err := service.BeginEnrollment(ctx, claims.UserID)
+		require.NoError(t, err)
+		codes, err := service.ConfirmEnrollment(ctx, claims.UserID, currentCode(enrollment.Secret, 0))
+		require.NoError(t, err)
+
+		claims, err = service.Authorize(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.False(t, claims.TwoFactorRequired)
+		err = service.DisableTwoFactor(ctx, claims.UserID, codes[0])
+		assert.True(t, userauth.ErrTwoFactorState.Has(err), err)
+	})
+}
+
+func TestOIDC(t *testing.T) {
+	ctx := context.Background()
+	provider := oidctest.NewProvider(t)
+	service, db, _ := newService(t, func(config *userauth.Config) {
+		config.OIDC = userauth.DefaultOIDCConfig()
+		config.OIDC.Issuer, config.OIDC.ClientID, config.OIDC.ClientSecret = provider.Issuer, provider.ClientID, provider.ClientSecret
+	})
+
+	// login logs in at provider and returns tokens of user provider sent back.
+	login := func(user oidctest.User) (auth.Tokens, error) {
+		provider.SetUser(user)
+
+		authURL, request, err := service.BeginOIDC(ctx)
+		require.NoError(t, err)
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		response, err := client.Get(authURL)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		callback, err := url.Parse(response.Header.Get("Location"))
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost:8087/login/oidc/callback", callback.Scheme+"://"+callback.Host+callback.Path)
+
+		return service.CompleteOIDC(ctx, request, callback.Query().Get("state"), callback.Query().Get("code"), sessions.Metadata{})
+	}
+
+	// userOf returns user of access token.
+	userOf := func(tokens auth.Tokens) users.User {
+		claims, err := service.Authorize(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		user, err := db.Users().GetByID(ctx, claims.UserID)
+		require.NoError(t, err)
+		return user
+	}
+
+	tokens, err := login(oidctest.User{Subject: "1", Email: "New@Example.com", EmailVerified: true})
+	require.NoError(t, err)
+	created := userOf(tokens)
+	assert.Equal(t, "new@example.com", created.Email)
+	assert.True(t, created.Verified())
+
+	// identity stays linked when email at provider changes.
+	tokens, err = login(oidctest.User{Subject: "1", Email: "changed@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, userOf(tokens).ID)
+
+	_, err = login(oidctest.User{Subject: "2", Email: "unverified@example.com"})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	_, err = db.Users().GetByEmail(ctx, "unverified@example.com")
+	assert.True(t, users.ErrNoUser.Has(err), err)
+
+	_, request, err := service.BeginOIDC(ctx)
+	require.NoError(t, err)
+	_, err = service.CompleteOIDC(ctx, request, "forged", "code", sessions.Metadata{})
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+
+	t.Run("existing user", func(t *testing.T) {
+		passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+		require.NoError(t, err)
+		require.NoError(t, users.New(db.Users(), passwords).Create(ctx, "existing@example.com", "password"))
+		existing, err := db.Users().GetByEmail(ctx, "existing@example.com")
+		require.NoError(t, err)
+
+		tokens, err := login(oidctest.User{Subject: "3", Email: "existing@example.com", EmailVerified: true})
+		require.NoError(t, err)
+		linked := userOf(tokens)
+		assert.Equal(t, existing.ID, linked.ID)
+		assert.True(t, linked.Verified())
+
+		// password of unverified account could be set by anyone, so it is dropped.
+		_, err = service.Token(ctx, "existing@example.com", "password", false, sessions.Metadata{})
+		assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	})
+
+	t.Run("second factor", func(t *testing.T) {
+		enrollment, err := service.BeginEnrollment(ctx, created.ID)
+		require.NoError(t, err)
+		code, err := totp.Code(enrollment.Secret, totp.Counter(time.Now()))
+		require.NoError(t, err)
+		_, err = service.ConfirmEnrollment(ctx, created.ID, code)
+		require.NoError(t, err)
+
+		tokens, err := login(oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true})
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokens.SecondFactorToken)
+		assert.Empty(t, tokens.AccessToken)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		service, _, _ := newService(t, nil)
+		assert.False(t, service.OIDCEnabled())
+
+		_, _, err := service.BeginOIDC(ctx)
+		assert.True(t, userauth.ErrOIDCDisabled.Has(err), err)
+	})
+}
+
+func TestAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, nil)
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	require.NoError(t, users.New(db.Users(), passwords).Create(ctx, "user@gmail.com", "password"))
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+
+	_, _, err = service.CreateAccessToken(ctx, user.ID, "ci", []accesstokens.Scope{"items:admin"}, time.Hour)
+	assert.True(t, accesstokens.ErrValidation.Has(err), err)
+	_, _, err = service.CreateAccessToken(ctx, user.ID, "ci", []accesstokens.Scope{accesstokens.ScopeItemsRead}, 2*userauth.MaxAccessTokenTTL)
+	assert.True(t, accesstokens.ErrValidation.Has(err), err)
+
+	secret, token, err := service.CreateAccessToken(ctx, user.ID, "ci", []accesstokens.Scope{accesstokens.ScopeItemsRead}, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, accesstokens.Prefix), secret)
+
+	stored, err := db.AccessTokens().GetByHash(ctx, tokens.Hash(secret))
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored.Hash), secret, "secret must not be stored")
+	assert.Nil(t, stored.LastUsedAt)
+
+	claims, err := service.Authorize(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.False(t, claims.Session())
+	assert.True(t, claims.HasScope(string(accesstokens.ScopeItemsRead)))
+	assert.False(t, claims.HasScope(string(accesstokens.ScopeItemsWrite)))
+
+	list, err := service.AccessTokens(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.NotNil(t, list[0].LastUsedAt, "use of token must be recorded")
+
+	_, err = service.Authorize(ctx, secret+"x")
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+
+	expired, expiredToken, err := accesstokens.New(user.ID, "expired", []accesstokens.Scope{accesstokens.ScopeItemsRead}, time.Hour)
+	require.NoError(t, err)
+	expiredToken.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, db.AccessTokens().Create(ctx, expiredToken))
+	_, err = service.Authorize(ctx, expired)
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+
+	require.NoError(t, service.RevokeAccessToken(ctx, user.ID, token.ID))
+	_, err = service.Authorize(ctx, secret)
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+	err = service.RevokeAccessToken(ctx, user.ID, token.ID)
+	assert.True(t, accesstokens.ErrNoToken.Has(err), err)
+}
+
+func TestAuthorizeErrors(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, nil)
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	require.NoError(t, users.New(db.Users(), passwords).Create(ctx, "user@gmail.com", "password"))
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Users().Verify(ctx, user.ID, time.Now().UTC()))
+	tokens, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+
+	parts := strings.Split(tokens.AccessToken, ".")
+	require.Len(t, parts, 3)
+	// withHeader returns access token with header replaced, signature is kept.
+	withHeader := func(header string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + parts[1] + "." + parts[2]
+	}
+
+	keyring, err := auth.NewKeyring(auth.KeyringConfig{
+		Active: "test",
+		Keys:   []auth.SigningKey{{ID: "test", Secret: "0123456789abcdef"}},
+	})
+	require.NoError(t, err)
+	expired, err := (&auth.TokenSigner{Keyring: keyring}).CreateToken(ctx, &auth.Claims{UserID: user.ID, ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"malformed":       "not a token",
+		"bad signature":   parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString([]byte("forged")),
+		"unknown key":     withHeader(`{"alg":"HS256","typ":"JWT","kid":"other"}`),
+		"wrong algorithm": withHeader(`{"alg":"none","typ":"JWT","kid":"test"}`),
+		"expired":         expired,
+	} {
+		_, err := service.Authorize(ctx, token)
+		assert.True(t, userauth.ErrUnauthenticated.Has(err), "%s: %v", name, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = service.Authorize(canceled, tokens.AccessToken)
+	require.Error(t, err)
+	assert.False(t, userauth.ErrUnauthenticated.Has(err), "storage failure is not credentials failure: %v", err)
+	assert.True(t, userauth.Error.Has(err), err)
+}
+
+func TestAccountChanges(t *testing.T) {
+	ctx := context.Background()
+	service, db, mailbox := newService(t, func(config *userauth.Config) {
+		config.Lockout = userauth.LockoutConfig{AccountThreshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	})
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	accounts := users.New(db.Users(), passwords)
+	require.NoError(t, accounts.Create(ctx, "user@gmail.com", "password"))
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Users().Verify(ctx, user.ID, time.Now().UTC()))
+
+	current, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	other, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	claims, err := service.Authorize(ctx, current.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, service.LogoutOthers(ctx, user.ID, claims.SessionID))
+	_, err = service.Authorize(ctx, current.AccessToken)
+	require.NoError(t, err, "current session must stay")
+	_, err = service.Authorize(ctx, other.AccessToken)
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), err)
+
+	require.NoError(t, service.RequestPasswordReset(ctx, user.Email, sessions.Metadata{}))
+	service.Wait()
+	reset := mailedToken(t, mailbox, "/reset-password")
+	mailbox.Reset()
+
+	require.NoError(t, service.Reauthenticate(ctx, user.ID, "password", sessions.Metadata{}))
+	changed, err := accounts.ChangeEmail(ctx, user.ID, "changed@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, service.EmailChanged(ctx, user.Email, changed))
+	assert.Contains(t, mailbox.String(), "To: changed@gmail.com\r\n")
+	assert.Contains(t, mailbox.String(), "To: user@gmail.com\r\nSubject: Your email was changed", "previous email must be noticed")
+
+	claims, err = service.Authorize(ctx, current.AccessToken)
+	require.NoError(t, err, "session must survive email change")
+	assert.Equal(t, "changed@gmail.com", claims.Email)
+	assert.False(t, claims.EmailVerified)
+
+	err = service.ResetPassword(ctx, reset, "new password")
+	assert.True(t, userauth.ErrUnauthenticated.Has(err), "reset link sent to old email must stop working: %v", err)
+
+	verified, err := service.VerifyEmail(ctx, mailedToken(t, mailbox, "/verify-email"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed@gmail.com", verified.Email)
+	assert.True(t, verified.Verified())
+
+	// wrong passwords lock reauthentication and login alike.
+	for i := 0; i < 2; i++ {
+		err = service.Reauthenticate(ctx, user.ID, "wrong", sessions.Metadata{IP: "10.0.5.1"})
+		assert.True(t, users.ErrPassword.Has(err), err)
+	}
+	err = service.Reauthenticate(ctx, user.ID, "password", sessions.Metadata{IP: "10.0.5.1"})
+	assert.True(t, userauth.ErrLocked.Has(err), err)
+	_, err = service.Token(ctx, verified.Email, "password", false, sessions.Metadata{IP: "10.0.5.2"})
+	assert.True(t, userauth.ErrLocked.Has(err), err)
+}
+
+func TestConfirmationCode(t *testing.T) {
+	ctx := context.Background()
+	service, db, mailbox := newService(t, nil)
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	accounts := users.New(db.Users(), passwords)
+	// passwords of these users are unknown, just like of users who signed in only with OpenID Connect.
+	require.NoError(t, accounts.Create(ctx, "user@gmail.com", "unknown password"))
+	require.NoError(t, accounts.Create(ctx, "other@gmail.com", "unknown password"))
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	other, err := db.Users().GetByEmail(ctx, "other@gmail.com")
+	require.NoError(t, err)
+
+	code := func(userID uuid.UUID) string {
+		mailbox.Reset()
+		require.NoError(t, service.SendConfirmationCode(ctx, userID, sessions.Metadata{}))
+		matches := regexp.MustCompile(`works once:\r?\n(\S+)`).FindStringSubmatch(mailbox.String())
+		require.Len(t, matches, 2, mailbox.String())
+		return matches[1]
+	}
+
+	first := code(user.ID)
+	second := code(user.ID)
+	err = service.Reauthenticate(ctx, user.ID, first, sessions.Metadata{})
+	assert.True(t, users.ErrPassword.Has(err), "new code must replace previous one: %v", err)
+
+	require.NoError(t, service.Reauthenticate(ctx, user.ID, second, sessions.Metadata{}))
+	err = service.Reauthenticate(ctx, user.ID, second, sessions.Metadata{})
+	assert.True(t, users.ErrPassword.Has(err), "code must work once: %v", err)
+
+	foreign := code(other.ID)
+	err = service.Reauthenticate(ctx, user.ID, foreign, sessions.Metadata{})
+	assert.True(t, users.ErrPassword.Has(err), "code of other user must not work: %v", err)
+	require.NoError(t, service.Reauthenticate(ctx, other.ID, foreign, sessions.Metadata{}))
+}
+
+func TestDisabledUser(t *testing.T) {
+	ctx := context.Background()
+	service, db, _ := newService(t, nil)
+
+	passwords, err := users.NewPasswordHasher(users.DefaultPasswordConfig())
+	require.NoError(t, err)
+	accounts := users.New(db.Users(), passwords)
+	require.NoError(t, accounts.Create(ctx, "user@gmail.com", "password"))
+	user, err := db.Users().GetByEmail(ctx, "user@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Users().Verify(ctx, user.ID, time.Now().UTC()))
+
+	tokens, err := service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+	secret, _, err := service.CreateAccessToken(ctx, user.ID, "ci", []accesstokens.Scope{accesstokens.ScopeItemsRead}, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := service.Authorize(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, string(users.RoleUser), claims.Role)
+
+	require.NoError(t, accounts.SetRole(ctx, user.ID, users.RoleAdmin))
+	claims, err = service.Authorize(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, string(users.RoleAdmin), claims.Role, "role change must apply without waiting for token renewal")
+
+	// other admin is needed, the last admin can not be disabled.
+	require.NoError(t, accounts.Create(ctx, "admin@gmail.com", "password"))
+	admin, err := db.Users().GetByEmail(ctx, "admin@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, accounts.SetRole(ctx, admin.ID, users.RoleAdmin))
+	require.NoError(t, accounts.Disable(ctx, user.ID))
+
+	_, err = service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	assert.True(t, userauth.ErrDisabled.Has(err), err)
+	_, err = service.Authorize(ctx, tokens.AccessToken)
+	assert.True(t, userauth.ErrDisabled.Has(err), err)
+	_, err = service.Authorize(ctx, secret)
+	assert.True(t, userauth.ErrDisabled.Has(err), err)
+	_, err = service.Refresh(ctx, tokens.RefreshToken)
+	assert.True(t, userauth.ErrDisabled.Has(err), err)
+
+	require.NoError(t, accounts.Enable(ctx, user.ID))
+	_, err = service.Authorize(ctx, secret)
+	require.NoError(t, err)
+	_, err = service.Token(ctx, user.Email, "password", false, sessions.Metadata{})
+	require.NoError(t, err)
+}
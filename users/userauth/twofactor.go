@@ -0,0 +1,268 @@
+package userauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"todo/pkg/auth"
+	"todo/pkg/totp"
+	"todo/sessions"
+	"todo/tokens"
+	"todo/twofactor"
+)
+
+const (
+	// SecondFactorExpirationTime is how long user has to enter second factor code after password.
+	SecondFactorExpirationTime = 5 * time.Minute
+
+	// codeSkew is how many periods code may be late or early, it covers clock drift and typing.
+	codeSkew = 1
+)
+
+var (
+	// ErrTwoFactorRequired indicates that user has to enroll second factor before doing anything else.
+	ErrTwoFactorRequired = errs.Class("two-factor authentication is required")
+
+	// ErrTwoFactorState indicates that second factor is already enabled or is not enabled.
+	ErrTwoFactorState = errs.Class("two-factor authentication state error")
+
+	// ErrInvalidCode indicates that code entered to enroll or disable second factor is wrong.
+	ErrInvalidCode = errs.Class("invalid two-factor code")
+)
+
+// TwoFactorConfig configures TOTP second factor.
+type TwoFactorConfig struct {
+	// Required makes every user enroll second factor before using the app.
+	Required bool `yaml:"required"`
+	// Issuer is a name of the app shown by authenticator apps.
+	Issuer string `yaml:"issuer"`
+	// ChallengeTTL is how long user has to enter code after password.
+	ChallengeTTL time.Duration `yaml:"challengeTTL"`
+}
+
+// DefaultTwoFactorConfig returns default second factor config.
+func DefaultTwoFactorConfig() TwoFactorConfig {
+	return TwoFactorConfig{
+		Issuer:       "todo",
+		ChallengeTTL: SecondFactorExpirationTime,
+	}
+}
+
+// Enrollment is a secret of new second factor, it is added to authenticator app by URI, usually as QR code.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// challenge returns tokens with only second factor token, which is exchanged for session by SecondFactor.
+func (service *Service) challenge(ctx context.Context, userID uuid.UUID) (auth.Tokens, error) {
+	secret, token, err := tokens.New(userID, tokens.PurposeSecondFactor, service.config.TwoFactor.ChallengeTTL)
+	if err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+	if err = service.tokens.Create(ctx, token); err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	return auth.Tokens{SecondFactorToken: secret, SecondFactorExpiresAt: token.ExpiresAt}, nil
+}
+
+// SecondFactor checks TOTP or recovery code of user who passed password check and opens session.
+// Wrong codes are counted like failed logins, second factor token stays valid until it expires.
+func (service *Service) SecondFactor(ctx context.Context, secondFactorToken, code string, rememberMe bool, metadata sessions.Metadata) (_ auth.Tokens, err error) {
+	now := time.Now().UTC()
+	hash := tokens.Hash(secondFactorToken)
+
+	token, err := service.tokens.Get(ctx, tokens.PurposeSecondFactor, hash, now)
+	if err != nil {
+		if tokens.ErrNoToken.Has(err) {
+			return auth.Tokens{}, ErrUnauthenticated.New("second factor token is invalid or expired")
+		}
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	codeLimit := limit{key: twoFactorKey(token.UserID), threshold: service.config.Lockout.AccountThreshold}
+	if locked, ok := service.lockout.reserve(now, codeLimit); !ok {
+		return auth.Tokens{}, ErrLocked.New("too many failed attempts, try again in %s", locked.Round(time.Second))
+	}
+
+	factor, err := service.twoFactor.Get(ctx, token.UserID)
+	if err != nil {
+		service.lockout.release(codeLimit)
+		if twofactor.ErrNoFactor.Has(err) {
+			return auth.Tokens{}, ErrUnauthenticated.New("second factor is disabled, log in again")
+		}
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	if err = service.useCode(ctx, factor, code, now, true); err != nil {
+		if ErrInvalidCode.Has(err) {
+			failures, locked := service.lockout.fail(now, codeLimit)
+			if locked > 0 {
+				service.log.Warn("second factor locked for user", zap.Stringer("user", token.UserID),
+					zap.String("ip", metadata.IP), zap.Int("failures", failures), zap.Duration("lockout", locked))
+			}
+			return auth.Tokens{}, ErrUnauthenticated.New("invalid code")
+		}
+		service.lockout.release(codeLimit)
+		return auth.Tokens{}, err
+	}
+	service.lockout.release(codeLimit)
+	service.lockout.reset(codeLimit.key)
+
+	// token is consumed only now, so that mistyped code does not send user back to password.
+	if _, err = service.tokens.Consume(ctx, tokens.PurposeSecondFactor, hash, now); err != nil {
+		if tokens.ErrNoToken.Has(err) {
+			return auth.Tokens{}, ErrUnauthenticated.New("second factor token is already used")
+		}
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	user, err := service.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return auth.Tokens{}, Error.Wrap(err)
+	}
+
+	return service.openSession(ctx, user, rememberMe, metadata)
+}
+
+// BeginEnrollment creates unconfirmed second factor of user and returns its secret.
+// Unconfirmed factor is reused, so that reloaded page shows QR code which was already scanned.
+func (service *Service) BeginEnrollment(ctx context.Context, userID uuid.UUID) (Enrollment, error) {
+	user, err := service.users.GetByID(ctx, userID)
+	if err != nil {
+		return Enrollment{}, Error.Wrap(err)
+	}
+
+	factor, err := service.twoFactor.Get(ctx, userID)
+	switch {
+	case err == nil && factor.Confirmed():
+		return Enrollment{}, ErrTwoFactorState.New("two-factor authentication is already enabled")
+	case twofactor.ErrNoFactor.Has(err):
+		factor = twofactor.Factor{UserID: userID, CreatedAt: time.Now().UTC()}
+		if factor.Secret, err = totp.NewSecret(); err != nil {
+			return Enrollment{}, Error.Wrap(err)
+		}
+		if err = service.twoFactor.Save(ctx, factor); err != nil {
+			return Enrollment{}, Error.Wrap(err)
+		}
+	case err != nil:
+		return Enrollment{}, Error.Wrap(err)
+	}
+
+	return Enrollment{
+		Secret: factor.Secret,
+		URI:    totp.URI(service.config.TwoFactor.Issuer, user.Email, factor.Secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables second factor of user once code from authenticator app is correct
+// and returns recovery codes, they are shown only once.
+func (service *Service) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	factor, err := service.twoFactor.Get(ctx, userID)
+	if err != nil {
+		if twofactor.ErrNoFactor.Has(err) {
+			return nil, ErrTwoFactorState.New("enrollment is not started")
+		}
+		return nil, Error.Wrap(err)
+	}
+	if factor.Confirmed() {
+		return nil, ErrTwoFactorState.New("two-factor authentication is already enabled")
+	}
+
+	now := time.Now().UTC()
+	if err = service.useCode(ctx, factor, code, now, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := twofactor.NewRecoveryCodes()
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	// factor is reloaded, so that counter of the code just used is kept.
+	factor, err = service.twoFactor.Get(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	factor.RecoveryCodes, factor.ConfirmedAt = hashes, &now
+	if err = service.twoFactor.Save(ctx, factor); err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor deletes second factor of user, current code or recovery code is required.
+func (service *Service) DisableTwoFactor(ctx context.Context, userID uuid.UUID, code string) error {
+	if service.config.TwoFactor.Required {
+		return ErrTwoFactorState.New("two-factor authentication is required for all users")
+	}
+
+	factor, err := service.twoFactor.Get(ctx, userID)
+	if err != nil {
+		if twofactor.ErrNoFactor.Has(err) {
+			return ErrTwoFactorState.New("two-factor authentication is not enabled")
+		}
+		return Error.Wrap(err)
+	}
+	if !factor.Confirmed() {
+		return ErrTwoFactorState.New("two-factor authentication is not enabled")
+	}
+
+	if err = service.useCode(ctx, factor, code, time.Now().UTC(), true); err != nil {
+		return err
+	}
+
+	return Error.Wrap(service.twoFactor.Delete(ctx, userID))
+}
+
+// TwoFactorRequired reports whether every user has to enroll second factor.
+func (service *Service) TwoFactorRequired() bool {
+	return service.config.TwoFactor.Required
+}
+
+// TwoFactorEnabled reports whether user has confirmed second factor.
+func (service *Service) TwoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	factor, err := service.twoFactor.Get(ctx, userID)
+	if err != nil {
+		if twofactor.ErrNoFactor.Has(err) {
+			return false, nil
+		}
+		return false, Error.Wrap(err)
+	}
+
+	return factor.Confirmed(), nil
+}
+
+// useCode accepts TOTP code of factor or, when allowed, recovery code and marks it used.
+// ErrInvalidCode is returned for wrong and already used codes.
+func (service *Service) useCode(ctx context.Context, factor twofactor.Factor, code string, now time.Time, allowRecovery bool) error {
+	counter, ok, err := totp.Validate(factor.Secret, code, now, codeSkew)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	if ok {
+		err = service.twoFactor.UseCounter(ctx, factor.UserID, counter)
+	} else if allowRecovery {
+		err = service.twoFactor.UseRecoveryCode(ctx, factor.UserID, twofactor.HashRecoveryCode(code))
+	} else {
+		return ErrInvalidCode.New("code is wrong")
+	}
+
+	if twofactor.ErrNoFactor.Has(err) {
+		return ErrInvalidCode.New("code is wrong or was already used")
+	}
+
+	return Error.Wrap(err)
+}
+
+// twoFactorKey returns lockout counter key of second factor of user.
+func twoFactorKey(userID uuid.UUID) string {
+	return "2fa:" + userID.String()
+}
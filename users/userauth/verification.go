@@ -0,0 +1,147 @@
+package userauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"todo/pkg/mail"
+	"todo/tokens"
+	"todo/users"
+)
+
+const (
+	// VerificationExpirationTime after passing this time email verification link expires.
+	VerificationExpirationTime = 24 * time.Hour
+
+	// UnverifiedLimited lets users with unverified email log in and read, but not change their items.
+	UnverifiedLimited = "limited"
+	// UnverifiedBlocked rejects login of users with unverified email.
+	UnverifiedBlocked = "blocked"
+)
+
+// VerificationConfig configures email verification of new users.
+type VerificationConfig struct {
+	// TTL is lifetime of verification link.
+	TTL time.Duration `yaml:"ttl"`
+	// Unverified is what users with unverified email may do, UnverifiedLimited or UnverifiedBlocked.
+	Unverified string `yaml:"unverified"`
+}
+
+// DefaultVerificationConfig returns default verification config.
+func DefaultVerificationConfig() VerificationConfig {
+	return VerificationConfig{
+		TTL:        VerificationExpirationTime,
+		Unverified: UnverifiedLimited,
+	}
+}
+
+// RequestVerification mails link to verify email to user with email in background, previous links stop working.
+// Already verified users get no mail.
+func (service *Service) RequestVerification(email string) {
+	service.mailRegistered(lookupEmail(email), "verification", func(ctx context.Context, user users.User) error {
+		if user.Verified() {
+			return nil
+		}
+
+		return service.sendVerification(ctx, user, "Welcome to todo!")
+	})
+}
+
+// EmailChanged mails link to verify new email of user and notice to previous email, so that owner of account
+// learns about the change even when someone else made it. Links sent to previous email, password reset
+// links included, stop working, since they could reach someone else.
+func (service *Service) EmailChanged(ctx context.Context, previous string, user users.User) error {
+	if err := service.tokens.DeleteByUser(ctx, user.ID, tokens.PurposePasswordReset); err != nil {
+		return Error.Wrap(err)
+	}
+
+	service.log.Info("email is changed", zap.Stringer("user", user.ID))
+	err := service.mailer.Send(ctx, mail.Message{
+		To:      previous,
+		Subject: "Your email was changed",
+		Body: fmt.Sprintf("The email of your todo account was changed to %s.\n\n"+
+			"If it was not you, someone else has access to your account, "+
+			"ask the administrator to restore it.\n", user.Email),
+	})
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	return service.sendVerification(ctx, user, "Your todo email was changed.")
+}
+
+// sendVerification mails verification link to user, previous links stop working.
+func (service *Service) sendVerification(ctx context.Context, user users.User, greeting string) error {
+	if err := service.tokens.DeleteByUser(ctx, user.ID, tokens.PurposeEmailVerification); err != nil {
+		return Error.Wrap(err)
+	}
+
+	secret, token, err := tokens.New(user.ID, tokens.PurposeEmailVerification, service.config.Verification.TTL)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if err = service.tokens.Create(ctx, token); err != nil {
+		return Error.Wrap(err)
+	}
+
+	link := strings.TrimSuffix(service.config.PublicURL, "/") + "/verify-email?token=" + url.QueryEscape(secret)
+	err = service.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("%s\n\n"+
+			"Open the link to confirm that this email is yours, it expires in %s:\n%s\n\n"+
+			"If you did not register, ignore this email.\n", greeting, service.config.Verification.TTL, link),
+	})
+
+	return Error.Wrap(err)
+}
+
+// VerifyEmail marks email of user who received verification token as verified and returns the user.
+// ErrUnauthenticated is returned when token is unknown, expired or already used.
+func (service *Service) VerifyEmail(ctx context.Context, secret string) (users.User, error) {
+	now := time.Now().UTC()
+
+	token, err := service.tokens.Consume(ctx, tokens.PurposeEmailVerification, tokens.Hash(secret), now)
+	if err != nil {
+		if tokens.ErrNoToken.Has(err) {
+			return users.User{}, ErrUnauthenticated.New("verification link is invalid or expired")
+		}
+		return users.User{}, Error.Wrap(err)
+	}
+
+	if err = service.users.Verify(ctx, token.UserID, now); err != nil {
+		return users.User{}, Error.Wrap(err)
+	}
+
+	user, err := service.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return users.User{}, Error.Wrap(err)
+	}
+
+	service.log.Info("email is verified", zap.Stringer("user", user.ID))
+	return user, nil
+}
+
+// checkVerified returns ErrUnverified if user with unverified email is not allowed to log in.
+func (service *Service) checkVerified(user users.User) error {
+	if !user.Verified() && service.config.Verification.Unverified == UnverifiedBlocked {
+		return ErrUnverified.New("verify your email to log in")
+	}
+
+	return nil
+}
+
+// lookupEmail returns email in the form users are stored with,
+// invalid email is returned as it is and matches no user.
+func lookupEmail(email string) string {
+	if normalized, err := users.NormalizeEmail(email); err == nil {
+		return normalized
+	}
+
+	return email
+}
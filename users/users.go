@@ -8,23 +8,64 @@ import (
 	"github.com/zeebo/errs"
 )
 
-// ErrNoUser indicates that user does not exists.
-var ErrNoUser = errs.Class("user does not exists")
+var (
+	// ErrNoUser indicates that user does not exists.
+	ErrNoUser = errs.Class("user does not exists")
+
+	// ErrValidation indicates that user data is invalid.
+	ErrValidation = errs.Class("user validation error")
+
+	// ErrEmailTaken indicates that other user is registered with the same email.
+	ErrEmailTaken = errs.Class("email is already registered")
+
+	// ErrLastAdmin indicates that change would leave no enabled admin to manage users.
+	ErrLastAdmin = errs.Class("last admin")
+)
 
 // DB is exposing access to users db.
 type DB interface {
-	// Create creates user in the database.
+	// Create creates user in the database, ErrEmailTaken is returned if email is not unique.
 	Create(ctx context.Context, user User) error
+	// GetByID returns user by id from the database.
+	GetByID(ctx context.Context, id uuid.UUID) (User, error)
 	// GetByEmail returns user by email form the database.
 	GetByEmail(ctx context.Context, email string) (User, error)
+	// UpdatePassword replaces password hash of user in the database.
+	UpdatePassword(ctx context.Context, id uuid.UUID, hash []byte) error
+	// UpdateEmail replaces email of user and marks it as unverified in the database,
+	// ErrEmailTaken is returned if email is not unique.
+	UpdateEmail(ctx context.Context, id uuid.UUID, email string) error
+	// Verify marks email of user as verified at verifiedAt in the database.
+	Verify(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error
+	// List returns users whose email contains search in the database ordered by email, all of them if search is empty.
+	List(ctx context.Context, search string) ([]User, error)
+	// UpdateRole replaces role of user in the database.
+	UpdateRole(ctx context.Context, id uuid.UUID, role Role) error
+	// UpdateDisabled sets time user was disabled at in the database, nil enables user.
+	UpdateDisabled(ctx context.Context, id uuid.UUID, disabledAt *time.Time) error
 	// Delete deletes user from the database.
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 // User describes user entity.
 type User struct {
-	ID        uuid.UUID `bson:"id"`
-	Email     string    `bson:"email"`
-	Password  []byte    `bson:"password"`
-	CreatedAt time.Time `bson:"created_at"`
+	ID        uuid.UUID `json:"id" bson:"id"`
+	Email     string    `json:"email" bson:"email"`
+	Password  []byte    `json:"-" bson:"password"`
+	CreatedAt time.Time `json:"createdAt" bson:"created_at"`
+	// VerifiedAt is when user confirmed ownership of email, nil until then.
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty" bson:"verified_at"`
+	Role       Role       `json:"role" bson:"role"`
+	// DisabledAt is when admin disabled user, disabled users can not log in.
+	DisabledAt *time.Time `json:"disabledAt,omitempty" bson:"disabled_at"`
+}
+
+// Verified reports whether user confirmed ownership of email.
+func (user User) Verified() bool {
+	return user.VerifiedAt != nil
+}
+
+// Disabled reports whether user is disabled by admin.
+func (user User) Disabled() bool {
+	return user.DisabledAt != nil
 }